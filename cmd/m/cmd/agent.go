@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/workflow"
+	"github.com/spf13/cobra"
+)
+
+func newAgentRootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "agent",
+		Short:  "Internal commands used by pipeline agents",
+		Hidden: true,
+	}
+
+	cmd.AddCommand(newAgentExecCmd())
+
+	return cmd
+}
+
+func newAgentExecCmd() *cobra.Command {
+	var stackName string
+	var stageID string
+
+	cmd := &cobra.Command{
+		Use:   "exec --stack <stack> --stage <stage> -- <command> [args...]",
+		Short: "Run a stage agent and record its exit code",
+		Long: "Run a stage agent and record its exit code on the stage once it exits.\n" +
+			"Agents are spawned under this command because the command that spawns them usually exits first.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if stackName == "" || stageID == "" {
+				return errs.New(errs.Usage, "--stack and --stage are required")
+			}
+
+			repoRoot := ""
+			if repo, err := discoverRepoContext(); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "m agent exec: %v; the exit code will not be recorded\n", err)
+			} else {
+				repoRoot = repo.rootPath
+			}
+
+			os.Exit(runAgent(repoRoot, stackName, stageID, args, cmd.ErrOrStderr()))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&stackName, "stack", "", "Stack the agent works on")
+	cmd.Flags().StringVar(&stageID, "stage", "", "Stage the agent works on")
+
+	return cmd
+}
+
+// runAgent runs argv with this process's stdio, forwarding interrupts to it,
+// records its exit code on the stage unless repoRoot is empty, and returns
+// the exit code.
+func runAgent(repoRoot, stackName, stageID string, argv []string, stderr io.Writer) int {
+	child := exec.Command(argv[0], argv[1:]...)
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	exitCode := 0
+	if err := child.Start(); err != nil {
+		fmt.Fprintf(stderr, "m agent exec: %v\n", err)
+		exitCode = 127
+	} else {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			for sig := range signals {
+				if err := child.Process.Signal(sig); err != nil {
+					_ = child.Process.Kill()
+				}
+			}
+		}()
+
+		err := child.Wait()
+		signal.Stop(signals)
+		close(signals)
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		} else if err != nil {
+			exitCode = -1
+		}
+	}
+
+	if repoRoot != "" {
+		// The stage records this process, not the child, as its agent.
+		if err := workflow.RecordAgentExit(repoRoot, stackName, stageID, os.Getpid(), exitCode); err != nil {
+			fmt.Fprintf(stderr, "m agent exec: record exit: %v\n", err)
+		}
+	}
+
+	if exitCode < 0 {
+		return 1
+	}
+	return exitCode
+}
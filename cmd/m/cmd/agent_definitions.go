@@ -29,6 +29,9 @@ Your job:
 - Commit any fixes with message prefix "review: "
 - If no fixes are needed, do NOT create an empty commit
 - When complete, call the report_stage_done MCP tool with phase "ai_review"
+- If the implementation is fundamentally wrong and cannot be fixed in review,
+  report outcome "changes_requested" with findings describing what must change;
+  the stage goes back to the build agent with your findings
 
 Do not implement new features. Do not modify files outside the stage's scope.
 `
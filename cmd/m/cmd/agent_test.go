@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+func TestRunAgentRecordsExitCode(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	repoRoot := t.TempDir()
+	stacks := &state.Stacks{Stacks: []state.Stack{{
+		Name: "checkout",
+		Stages: []state.Stage{
+			{ID: "api", Status: state.StatusImplementing, Agent: &state.AgentProcess{Phase: state.PhaseImplementing, PID: os.Getpid()}},
+		},
+	}}}
+	if err := state.SaveStacks(repoRoot, stacks); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runAgent(repoRoot, "checkout", "api", []string{"sh", "-c", "exit 3"}, io.Discard); code != 3 {
+		t.Fatalf("runAgent() = %d, want 3", code)
+	}
+
+	loaded, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := loaded.Stacks[0].Stages[0]
+	if api.Agent == nil || api.Agent.ExitCode == nil || *api.Agent.ExitCode != 3 {
+		t.Fatalf("agent = %+v, want exit code 3", api.Agent)
+	}
+	if api.Status != state.StatusFailed || !strings.Contains(api.StatusReason, "exited with code 3") {
+		t.Fatalf("api = %s (%q), want failed with exit reason", api.Status, api.StatusReason)
+	}
+}
@@ -3,9 +3,11 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/mlawd/m-cli/internal/config"
+	"github.com/mlawd/m-cli/internal/errs"
 	"github.com/spf13/cobra"
 )
 
@@ -34,6 +36,10 @@ func newConfigShowCmd() *cobra.Command {
 				return err
 			}
 
+			if jsonOutput(cmd) {
+				return writeResult(cmd, cfg)
+			}
+
 			data, err := json.MarshalIndent(cfg, "", "  ")
 			if err != nil {
 				return err
@@ -48,7 +54,7 @@ func newConfigShowCmd() *cobra.Command {
 func newConfigSetCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "set <key> <value>",
-		Short: "Set a config value (e.g. agent_harness opencode, agents.review review)",
+		Short: "Set a config value (e.g. agent_harness opencode, agents.review review, agents.build.model sonnet, max_review_rounds 3)",
 		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			key := strings.TrimSpace(args[0])
@@ -62,25 +68,66 @@ func newConfigSetCmd() *cobra.Command {
 			switch {
 			case key == "agent_harness":
 				if !config.IsValidHarness(value) {
-					return fmt.Errorf("invalid agent_harness %q; valid values: opencode, claude", value)
+					return errs.New(errs.Usage, "invalid agent_harness %q; valid values: opencode, claude, command, script", value)
 				}
 				cfg.AgentHarness = value
 
+			case key == "max_review_rounds":
+				rounds, err := strconv.Atoi(value)
+				if err != nil || rounds < 0 {
+					return errs.New(errs.Usage, "invalid max_review_rounds %q; must be a non-negative integer", value)
+				}
+				cfg.MaxReviewRounds = rounds
+
+			case key == "timeouts.implementing":
+				cfg.Timeouts.Implementing = value
+			case key == "timeouts.ai_review":
+				cfg.Timeouts.AIReview = value
+			case key == "timeouts.idle":
+				cfg.Timeouts.Idle = value
+			case key == "timeouts.on_timeout":
+				cfg.Timeouts.OnTimeout = strings.ToLower(value)
+			case key == "timeouts.retries":
+				retries, err := strconv.Atoi(value)
+				if err != nil || retries < 0 {
+					return errs.New(errs.Usage, "invalid timeouts.retries %q; must be a non-negative integer", value)
+				}
+				cfg.Timeouts.Retries = retries
+
+			case strings.HasPrefix(key, "agents.") && strings.HasSuffix(key, ".model"):
+				agentKey := strings.TrimSuffix(strings.TrimPrefix(key, "agents."), ".model")
+				if strings.TrimSpace(agentKey) == "" {
+					return errs.New(errs.Usage, "agent key must be non-empty")
+				}
+				if cfg.Agents == nil {
+					cfg.Agents = map[string]config.AgentEntry{}
+				}
+				// An empty value clears the model so the harness default applies.
+				entry, ok := cfg.Agents[agentKey]
+				if !ok {
+					entry.Agent = agentKey
+				}
+				entry.Model = value
+				cfg.Agents[agentKey] = entry
+
 			case strings.HasPrefix(key, "agents."):
 				agentKey := strings.TrimPrefix(key, "agents.")
 				if strings.TrimSpace(agentKey) == "" {
-					return fmt.Errorf("agent key must be non-empty")
+					return errs.New(errs.Usage, "agent key must be non-empty")
 				}
 				if strings.TrimSpace(value) == "" {
-					return fmt.Errorf("agent value must be non-empty")
+					return errs.New(errs.Usage, "agent value must be non-empty")
 				}
 				if cfg.Agents == nil {
 					cfg.Agents = map[string]config.AgentEntry{}
 				}
-				cfg.Agents[agentKey] = config.AgentEntry{AgentConfig: config.AgentConfig{Agent: value}}
+				// Keep any model already configured for the agent.
+				entry := cfg.Agents[agentKey]
+				entry.Agent = value
+				cfg.Agents[agentKey] = entry
 
 			default:
-				return fmt.Errorf("unknown config key %q; supported: agent_harness, agents.<name>", key)
+				return errs.New(errs.Usage, "unknown config key %q; supported: agent_harness, max_review_rounds, timeouts.<implementing|ai_review|idle|on_timeout|retries>, agents.<name>, agents.<name>.model", key)
 			}
 
 			if err := config.ValidateConfig(cfg); err != nil {
@@ -93,7 +140,13 @@ func newConfigSetCmd() *cobra.Command {
 
 			outSuccess(cmd.OutOrStdout(), "Set %s = %s", key, value)
 			outInfo(cmd.OutOrStdout(), "Config file: %s", config.ConfigPath())
-			return nil
+			return writeResult(cmd, configSetResult{Key: key, Value: value, ConfigFile: config.ConfigPath()})
 		},
 	}
 }
+
+type configSetResult struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	ConfigFile string `json:"config_file"`
+}
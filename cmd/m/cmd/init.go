@@ -26,7 +26,11 @@ func newInitCmd() *cobra.Command {
 			}
 
 			outSuccess(cmd.OutOrStdout(), "Initialized m state at %s", state.Dir(repo.rootPath))
-			return nil
+			return writeResult(cmd, initResult{StateDir: state.Dir(repo.rootPath)})
 		},
 	}
 }
+
+type initResult struct {
+	StateDir string `json:"state_dir"`
+}
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/mlawd/m-cli/internal/errs"
 	"github.com/mlawd/m-cli/internal/mcp"
+	"github.com/mlawd/m-cli/internal/state"
 	"github.com/spf13/cobra"
 )
 
@@ -20,15 +27,104 @@ func newMCPRootCmd(version string) *cobra.Command {
 }
 
 func newMCPServeCmd(version string) *cobra.Command {
-	return &cobra.Command{
+	var addr string
+	var token string
+
+	cmd := &cobra.Command{
 		Use:   "serve",
-		Short: "Run an MCP stdio server with m context",
-		Args:  cobra.NoArgs,
+		Short: "Run an MCP server with m context over stdio or HTTP",
+		Long: "Run an MCP server with m context. By default it speaks stdio to the client that launched it.\n\n" +
+			"With --http it runs as a long-lived daemon for the repository that several agents share, serving streamable HTTP at " + mcp.StreamableHTTPPath +
+			" and HTTP+SSE at " + mcp.SSEPath + ". While it runs, agents spawned by the pipeline are pointed at it instead of launching their own server.",
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := mcp.ServeStdio(cmd.Context(), os.Stdin, os.Stdout, version); err != nil {
-				return fmt.Errorf("run MCP server: %w", err)
+			if addr == "" {
+				if err := mcp.ServeStdio(cmd.Context(), os.Stdin, os.Stdout, version); err != nil {
+					return fmt.Errorf("run MCP server: %w", err)
+				}
+				return nil
 			}
-			return nil
+
+			return serveMCPHTTP(cmd, addr, token, version)
 		},
 	}
+
+	cmd.Flags().StringVar(&addr, "http", "", "Serve streamable HTTP and SSE on this address (e.g. :7777, which binds 127.0.0.1) instead of stdio")
+	cmd.Flags().StringVar(&token, "token", os.Getenv("M_MCP_TOKEN"), "Bearer token clients must send with --http (default $M_MCP_TOKEN)")
+
+	return cmd
+}
+
+func serveMCPHTTP(cmd *cobra.Command, addr, token, version string) error {
+	repo, err := discoverRepoContext()
+	if err != nil {
+		return err
+	}
+	if err := state.EnsureInitialized(repo.rootPath); err != nil {
+		return err
+	}
+
+	addr, err = mcpListenAddr(addr, token)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", addr, err)
+	}
+
+	url := mcp.HTTPURL(listener.Addr())
+	pid := os.Getpid()
+	err = state.SaveMCPServer(repo.rootPath, &state.MCPServer{
+		URL:       url,
+		Token:     token,
+		PID:       pid,
+		StartedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	defer state.RemoveMCPServer(repo.rootPath, pid)
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	w := cmd.ErrOrStderr()
+	outInfo(w, "Serving MCP at %s (SSE at %s); press ctrl-c to stop.", url, mcp.SSEPath)
+	if token == "" {
+		outWarn(w, "No --token set; any process on this machine can call the server.")
+	}
+
+	if err := mcp.ServeHTTP(ctx, listener, mcp.HTTPOptions{Version: version, Token: token}); err != nil {
+		return fmt.Errorf("run MCP server: %w", err)
+	}
+	return nil
+}
+
+// mcpListenAddr binds an address without a host to loopback, and refuses to
+// expose the server beyond this machine without a token, since its tools push
+// branches, open PRs and spawn agents.
+func mcpListenAddr(addr, token string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", errs.New(errs.Usage, "invalid --http address %q: %v", addr, err)
+	}
+	if host == "" {
+		return net.JoinHostPort("127.0.0.1", port), nil
+	}
+
+	loopback := host == "localhost"
+	if ip := net.ParseIP(host); ip != nil {
+		loopback = ip.IsLoopback()
+	}
+	if !loopback && token == "" {
+		return "", errs.Wrap(errs.Usage, fmt.Errorf("refusing to serve MCP on non-loopback address %q without a token", addr), "Pass --token (or set M_MCP_TOKEN), or bind to 127.0.0.1.")
+	}
+	return addr, nil
 }
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mlawd/m-cli/internal/errs"
+)
+
+func TestMCPListenAddr(t *testing.T) {
+	tests := []struct {
+		addr, token, want string
+	}{
+		{":7777", "", "127.0.0.1:7777"},
+		{"localhost:7777", "", "localhost:7777"},
+		{"[::1]:7777", "", "[::1]:7777"},
+		{"0.0.0.0:7777", "s3cret", "0.0.0.0:7777"},
+	}
+	for _, tt := range tests {
+		got, err := mcpListenAddr(tt.addr, tt.token)
+		if err != nil || got != tt.want {
+			t.Errorf("mcpListenAddr(%q, %q) = %q, %v; want %q", tt.addr, tt.token, got, err, tt.want)
+		}
+	}
+
+	for _, addr := range []string{"0.0.0.0:7777", "192.168.1.5:7777", "example.com:7777"} {
+		if _, err := mcpListenAddr(addr, ""); !errs.Is(err, errs.Usage) {
+			t.Errorf("mcpListenAddr(%q, \"\") error = %v, want usage error", addr, err)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"io"
 	"os"
 	"strings"
+
+	"github.com/mlawd/m-cli/internal/errs"
 )
 
 const (
@@ -67,6 +69,10 @@ func supportsColor(w io.Writer) bool {
 		return false
 	}
 
+	return isTerminal(w)
+}
+
+func isTerminal(w io.Writer) bool {
 	file, ok := w.(*os.File)
 	if !ok {
 		return false
@@ -79,15 +85,25 @@ func supportsColor(w io.Writer) bool {
 	return (info.Mode() & os.ModeCharDevice) != 0
 }
 
+// FormatCLIError renders err, followed by any hints on how to recover.
 func FormatCLIError(w io.Writer, err error) string {
 	if err == nil {
 		return ""
 	}
 
 	line := fmt.Sprintf("❌ %s", strings.TrimSpace(err.Error()))
-	if supportsColor(w) {
+	color := supportsColor(w)
+	if color {
 		line = ansiRed + line + ansiReset
 	}
 
+	for _, hint := range errs.Hints(err) {
+		hintLine := fmt.Sprintf("   💡 %s", hint)
+		if color {
+			hintLine = ansiYellow + hintLine + ansiReset
+		}
+		line += "\n" + hintLine
+	}
+
 	return line
 }
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"io"
+
+	"github.com/mlawd/m-cli/internal/workflow"
+	"github.com/spf13/cobra"
+)
+
+// workflowService runs workflow operations on behalf of cmd, printing their
+// progress to its output as they run.
+func workflowService(cmd *cobra.Command, repo *repoContext, grouped bool) *workflow.Service {
+	return &workflow.Service{
+		RepoRoot: repo.rootPath,
+		Actor:    commandActor(cmd),
+		Reporter: cliReporter{w: cmd.OutOrStdout(), grouped: grouped},
+	}
+}
+
+// cliReporter prints workflow progress in the CLI's output style.
+type cliReporter struct {
+	w io.Writer
+	// grouped prints a heading for each stage and indents its steps under
+	// it, for commands that work through several stages.
+	grouped bool
+}
+
+func (r cliReporter) Report(p workflow.Progress) {
+	linePrefix := ""
+	if r.grouped {
+		linePrefix = "  "
+	}
+
+	switch p.Step {
+	case workflow.StepPushing:
+		if r.grouped {
+			outAction(r.w, "%s", p.Message)
+		}
+	case workflow.StepBranchReused, workflow.StepWorktreeReused:
+		outStyledWithPrefix(r.w, ansiBlue, "♻️", linePrefix, "%s", p.Message)
+	case workflow.StepRebasing:
+		outStyledWithPrefix(r.w, ansiBlue, "🔄", linePrefix, "%s", p.Message)
+	case workflow.StepPushed:
+		outStyledWithPrefix(r.w, ansiBlue, "🚀", linePrefix, "%s", p.Message)
+	case workflow.StepPRFound:
+		outStyledWithPrefix(r.w, ansiCyan, "🔗", linePrefix, "%s", p.Message)
+	case workflow.StepRebaseFallback, workflow.StepSyncConflict, workflow.StepBasePushed, workflow.StepJournalFailed:
+		outStyledWithPrefix(r.w, ansiYellow, "⚠️", linePrefix, "%s", p.Message)
+	default:
+		outStyledWithPrefix(r.w, ansiGreen, "✅", linePrefix, "%s", p.Message)
+	}
+}
@@ -36,11 +36,16 @@ func newPromptDefaultCmd() *cobra.Command {
 			}
 
 			fmt.Fprint(cmd.OutOrStdout(), prompt)
-			return nil
+			return writeResult(cmd, promptResult{Path: filepath.Join(repo.rootPath, "MCP_PROMPT.md"), Prompt: prompt})
 		},
 	}
 }
 
+type promptResult struct {
+	Path   string `json:"path"`
+	Prompt string `json:"prompt"`
+}
+
 func readDefaultPrompt(repoRoot string) (string, error) {
 	promptPath := filepath.Join(repoRoot, "MCP_PROMPT.md")
 	data, err := os.ReadFile(promptPath)
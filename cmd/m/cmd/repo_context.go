@@ -3,8 +3,10 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/mlawd/m-cli/internal/errs"
 	"github.com/mlawd/m-cli/internal/gitx"
 	"github.com/mlawd/m-cli/internal/state"
+	"github.com/spf13/cobra"
 )
 
 type repoContext struct {
@@ -27,7 +29,7 @@ func discoverRepoContext() (*repoContext, error) {
 }
 
 func loadState(ctx *repoContext) (*state.Stacks, error) {
-	if err := state.EnsureInitialized(ctx.rootPath); err != nil {
+	if err := requireInitialized(ctx); err != nil {
 		return nil, err
 	}
 
@@ -38,3 +40,35 @@ func loadState(ctx *repoContext) (*state.Stacks, error) {
 
 	return stacks, nil
 }
+
+// updateState applies fn to the stack index under the cross-process state lock,
+// attributing journal events to the running command.
+func updateState(cmd *cobra.Command, ctx *repoContext, fn func(*state.Stacks) error) error {
+	if err := requireInitialized(ctx); err != nil {
+		return err
+	}
+
+	return state.UpdateAs(ctx.rootPath, commandActor(cmd), fn)
+}
+
+// requireInitialized fails with a not-initialized error until `m init` has
+// run, then fills in any state directories that are missing.
+func requireInitialized(ctx *repoContext) error {
+	initialized, err := state.Initialized(ctx.rootPath)
+	if err != nil {
+		return err
+	}
+	if !initialized {
+		return errs.New(errs.NotInitialized, "m is not initialized in %s", ctx.rootPath)
+	}
+
+	return state.EnsureInitialized(ctx.rootPath)
+}
+
+func commandActor(cmd *cobra.Command) string {
+	if cmd == nil {
+		return "m"
+	}
+
+	return cmd.CommandPath()
+}
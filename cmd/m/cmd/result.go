@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/spf13/cobra"
+)
+
+// Formats accepted by the global --output flag.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+type resultWriterKey struct{}
+
+// setupOutput validates --output. For JSON it keeps the command's stdout
+// for the result document and discards the text lines printed as the
+// command runs.
+func setupOutput(cmd *cobra.Command) error {
+	switch outputFormat(cmd) {
+	case outputText:
+		return nil
+	case outputJSON:
+	default:
+		return errs.New(errs.Usage, "invalid --output %q; use text or json", outputFormat(cmd))
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd.SetContext(context.WithValue(ctx, resultWriterKey{}, cmd.OutOrStdout()))
+	cmd.SetOut(io.Discard)
+	return nil
+}
+
+func outputFormat(cmd *cobra.Command) string {
+	flag := cmd.Flag("output")
+	if flag == nil {
+		return outputText
+	}
+	return strings.ToLower(strings.TrimSpace(flag.Value.String()))
+}
+
+func jsonOutput(cmd *cobra.Command) bool {
+	return outputFormat(cmd) == outputJSON
+}
+
+// resultWriter is where the command's JSON result goes: stdout as it was
+// before setupOutput silenced the text output.
+func resultWriter(cmd *cobra.Command) io.Writer {
+	if ctx := cmd.Context(); ctx != nil {
+		if w, ok := ctx.Value(resultWriterKey{}).(io.Writer); ok {
+			return w
+		}
+	}
+	return cmd.OutOrStdout()
+}
+
+// writeResult prints result as an indented JSON document with --output
+// json. Text output is printed by the command as it runs, so this does
+// nothing otherwise.
+func writeResult(cmd *cobra.Command, result any) error {
+	if !jsonOutput(cmd) {
+		return nil
+	}
+
+	return encodeResult(resultWriter(cmd), result, "  ")
+}
+
+// writeResultLine prints one compact JSON object per line, for commands
+// that stream results as they happen.
+func writeResultLine(cmd *cobra.Command, result any) error {
+	return encodeResult(resultWriter(cmd), result, "")
+}
+
+// encodeResult writes result as JSON followed by a newline. Messages often
+// quote git output, so <, > and & are left unescaped.
+func encodeResult(w io.Writer, result any, indent string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	return encoder.Encode(result)
+}
+
+// requireTextOutput rejects --output json for interactive modes that
+// cannot produce a result document.
+func requireTextOutput(cmd *cobra.Command, what string) error {
+	if !jsonOutput(cmd) {
+		return nil
+	}
+	return errs.New(errs.Usage, "%s is not available with --output json", what)
+}
+
+type errorResult struct {
+	Error errorDetail `json:"error"`
+}
+
+// errorDetail describes a failed command. Code is the error's category and
+// ExitCode the process exit status for it.
+type errorDetail struct {
+	Code     string   `json:"code"`
+	ExitCode int      `json:"exit_code"`
+	Message  string   `json:"message"`
+	Hints    []string `json:"hints,omitempty"`
+}
+
+// PrintCLIError reports an error from running root: a JSON error document
+// on stdout with --output json, otherwise styled lines with any hints on
+// stderr.
+func PrintCLIError(root *cobra.Command, stderr io.Writer, err error) {
+	if err == nil {
+		return
+	}
+
+	if !jsonOutput(root) {
+		fmt.Fprintln(stderr, FormatCLIError(stderr, err))
+		return
+	}
+
+	_ = encodeResult(root.OutOrStdout(), errorResult{Error: errorDetail{
+		Code:     string(errs.KindOf(err)),
+		ExitCode: errs.ExitCode(err),
+		Message:  strings.TrimSpace(err.Error()),
+		Hints:    errs.Hints(err),
+	}}, "  ")
+}
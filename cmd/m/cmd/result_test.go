@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+func TestJSONOutputReplacesTextOutput(t *testing.T) {
+	repoRoot := initGitRepoForCurrentCmdTests(t)
+
+	stacks := &state.Stacks{
+		Version: 1,
+		Stacks: []state.Stack{
+			{
+				Name:         "checkout",
+				Type:         "feat",
+				PlanFile:     "plan.md",
+				CurrentStage: "api",
+				Stages: []state.Stage{
+					{ID: "api", Title: "API", Status: state.StatusHumanReview},
+					{ID: "ui", Title: "UI"},
+				},
+			},
+		},
+	}
+	if err := state.SaveStacks(repoRoot, stacks); err != nil {
+		t.Fatalf("SaveStacks: %v", err)
+	}
+
+	out, err := runRootCmdInDir(repoRoot, "stack", "list", "--output", "json")
+	if err != nil {
+		t.Fatalf("stack list returned error: %v", err)
+	}
+	var list stackListResult
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		t.Fatalf("stack list output is not JSON: %v\n%s", err, out)
+	}
+	if len(list.Stacks) != 1 || list.Stacks[0].Name != "checkout" || !list.Stacks[0].Current || len(list.Stacks[0].Stages) != 2 {
+		t.Fatalf("stack list = %+v", list)
+	}
+
+	out, err = runRootCmdInDir(repoRoot, "stage", "list", "-o", "json")
+	if err != nil {
+		t.Fatalf("stage list returned error: %v", err)
+	}
+	var stages stageListResult
+	if err := json.Unmarshal([]byte(out), &stages); err != nil {
+		t.Fatalf("stage list output is not JSON: %v\n%s", err, out)
+	}
+	if stages.CurrentStage != "api" || stages.Stages[0].Status != state.StatusHumanReview || stages.Stages[1].Status != state.StatusPending {
+		t.Fatalf("stage list = %+v", stages)
+	}
+}
+
+func TestPrintCLIErrorWithJSONOutput(t *testing.T) {
+	repoRoot := initGitRepoForCurrentCmdTests(t)
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(originalDir) })
+	if err := os.Chdir(repoRoot); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		kind errs.Kind
+		exit int
+	}{
+		{name: "argument error", args: []string{"-o", "json", "stack", "current", "extra"}, kind: errs.Usage, exit: 2},
+		{name: "unknown flag", args: []string{"-o", "json", "stack", "list", "--nope"}, kind: errs.Usage, exit: 2},
+		{name: "flag value", args: []string{"-o", "json", "stage", "logs", "--lines", "0"}, kind: errs.Usage, exit: 2},
+		{name: "not initialized", args: []string{"-o", "json", "stack", "list"}, kind: errs.NotInitialized, exit: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			root := NewRootCmd("test")
+			root.SetOut(&stdout)
+			root.SetErr(&stderr)
+			root.SetArgs(tt.args)
+
+			err := root.Execute()
+			if err == nil {
+				t.Fatal("Execute() succeeded, want error")
+			}
+			PrintCLIError(root, &stderr, err)
+
+			var result errorResult
+			if jsonErr := json.Unmarshal(stdout.Bytes(), &result); jsonErr != nil {
+				t.Fatalf("stdout is not a JSON error: %v\n%s", jsonErr, stdout.String())
+			}
+			if result.Error.Code != string(tt.kind) || result.Error.ExitCode != tt.exit || result.Error.Message == "" || len(result.Error.Hints) == 0 {
+				t.Fatalf("error = %+v, want %s (exit %d) with hints", result.Error, tt.kind, tt.exit)
+			}
+		})
+	}
+}
+
+func TestInvalidOutputFormatFallsBackToText(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	root := NewRootCmd("test")
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"version", "--output", "yaml"})
+
+	err := root.Execute()
+	if err == nil {
+		t.Fatal("Execute() succeeded, want error")
+	}
+	if got := errs.KindOf(err); got != errs.Usage {
+		t.Fatalf("KindOf() = %q, want %q", got, errs.Usage)
+	}
+
+	PrintCLIError(root, &stderr, err)
+	if stdout.Len() != 0 || !strings.Contains(stderr.String(), "invalid --output") || !strings.Contains(stderr.String(), "💡 Run the command with --help") {
+		t.Fatalf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"github.com/mlawd/m-cli/internal/errs"
 	"github.com/spf13/cobra"
 )
 
@@ -10,22 +11,45 @@ func NewRootCmd(version string) *cobra.Command {
 		Short:         "A local orchestration CLI for stacked workflows",
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return setupOutput(cmd)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			outInfo(cmd.OutOrStdout(), "Welcome to m. Run `m --help` to get started.")
 		},
 	}
 
+	rootCmd.PersistentFlags().StringP("output", "o", outputText, "Output format: text or json")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return errs.Wrap(errs.Usage, err)
+	})
+
 	rootCmd.AddCommand(
 		newInitCmd(),
 		newStatusCmd(),
 		newStackRootCmd(),
 		newStageRootCmd(),
 		newWorktreeRootCmd(),
+		newStateRootCmd(),
 		newPromptRootCmd(),
 		newMCPRootCmd(version),
 		newConfigRootCmd(),
 		newVersionCmd(version),
+		newAgentRootCmd(),
 	)
+	markUsageErrors(rootCmd)
 
 	return rootCmd
 }
+
+// markUsageErrors gives argument validation errors the usage error code.
+func markUsageErrors(cmd *cobra.Command) {
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			return errs.Wrap(errs.Usage, validate(cmd, args))
+		}
+	}
+	for _, child := range cmd.Commands() {
+		markUsageErrors(child)
+	}
+}
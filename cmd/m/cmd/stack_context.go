@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/mlawd/m-cli/internal/errs"
 	"github.com/mlawd/m-cli/internal/state"
 	"github.com/spf13/cobra"
 )
@@ -29,7 +30,7 @@ func resolveCurrentStack(stacksFile *state.Stacks, repo *repoContext, override s
 	if trimmed := strings.TrimSpace(override); trimmed != "" {
 		stack, _ := state.FindStack(stacksFile, trimmed)
 		if stack == nil {
-			return nil, fmt.Errorf("stack %q not found", trimmed)
+			return nil, state.StackNotFoundError(trimmed)
 		}
 
 		return stack, nil
@@ -64,7 +65,8 @@ func requireCurrentStack(stacksFile *state.Stacks, repo *repoContext, override s
 		return nil, err
 	}
 	if stack == nil {
-		return nil, fmt.Errorf("could not infer stack from workspace; run from .m/stacks/<stack> (or .m/stacks/<stack>/<stage>) or use `m stage open` for interactive selection")
+		return nil, errs.Wrap(errs.StackNotFound, fmt.Errorf("could not infer stack from workspace"),
+			"Run from .m/stacks/<stack> (or .m/stacks/<stack>/<stage>), pass --stack <name>, or use `m stage open` for interactive selection.")
 	}
 
 	return stack, nil
@@ -77,7 +79,7 @@ func requireCurrentStackWithPlan(stacksFile *state.Stacks, repo *repoContext, ov
 	}
 
 	if strings.TrimSpace(stack.PlanFile) == "" || len(stack.Stages) == 0 {
-		return nil, fmt.Errorf("no plan attached to current stack; run: m stack attach-plan <plan-file>")
+		return nil, errs.New(errs.NoPlan, "no plan attached to stack %q", stack.Name)
 	}
 
 	return stack, nil
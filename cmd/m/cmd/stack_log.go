@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newStackLogCmd() *cobra.Command {
+	var stageID string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "Show the event journal for the current stack",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			stacksFile, err := loadState(repo)
+			if err != nil {
+				return err
+			}
+
+			stack, err := requireCurrentStack(stacksFile, repo, stackNameFromFlag(cmd))
+			if err != nil {
+				return err
+			}
+
+			events, err := state.LoadEvents(repo.rootPath, stack.Name)
+			if err != nil {
+				return err
+			}
+
+			events = filterStackEvents(events, strings.TrimSpace(stageID), limit)
+			if jsonOutput(cmd) {
+				return writeResult(cmd, stackLogResult{Stack: stack.Name, Events: events})
+			}
+			if len(events) == 0 {
+				outInfo(cmd.OutOrStdout(), "No events recorded for stack %q", stack.Name)
+				return nil
+			}
+
+			for _, event := range events {
+				fmt.Fprintln(cmd.OutOrStdout(), formatStackEvent(event))
+				if summary := strings.TrimSpace(event.Payload["summary"]); summary != "" {
+					for _, line := range strings.Split(summary, "\n") {
+						fmt.Fprintf(cmd.OutOrStdout(), "    %s\n", line)
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&stageID, "stage", "", "Only show events for this stage id")
+	cmd.Flags().IntVar(&limit, "limit", 0, "Only show the most recent N events")
+
+	return cmd
+}
+
+type stackLogResult struct {
+	Stack  string        `json:"stack"`
+	Events []state.Event `json:"events"`
+}
+
+func filterStackEvents(events []state.Event, stageID string, limit int) []state.Event {
+	filtered := make([]state.Event, 0, len(events))
+	for _, event := range events {
+		if stageID != "" && event.Stage != stageID {
+			continue
+		}
+		filtered = append(filtered, event)
+	}
+
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[len(filtered)-limit:]
+	}
+
+	return filtered
+}
+
+func formatStackEvent(event state.Event) string {
+	stage := event.Stage
+	if stage == "" {
+		stage = "-"
+	}
+
+	var detail string
+	switch event.Type {
+	case state.EventStageTransition:
+		detail = fmt.Sprintf("%s -> %s", event.Payload["from"], event.Payload["to"])
+		if action := event.Payload["action"]; action != "" {
+			detail += " (" + action + ")"
+		}
+		if reason := event.Payload["reason"]; reason != "" {
+			detail += ": " + reason
+		}
+	case state.EventWorktreeCreated:
+		detail = fmt.Sprintf("created worktree %s (%s)", event.Payload["worktree"], event.Payload["branch"])
+	case state.EventPush:
+		detail = fmt.Sprintf("pushed %s", event.Payload["branch"])
+		if event.Payload["force_with_lease"] == "true" {
+			detail += " (--force-with-lease)"
+		}
+		if prURL := event.Payload["pr_url"]; prURL != "" {
+			detail += " " + prURL
+		}
+	case state.EventSyncRebase:
+		detail = fmt.Sprintf("rebased %s onto %s [%s]", event.Payload["branch"], event.Payload["onto"], event.Payload["mode"])
+	case state.EventSyncConflict:
+		detail = fmt.Sprintf("sync stopped at a conflict rebasing %s onto %s in %s", event.Payload["branch"], event.Payload["onto"], event.Payload["worktree"])
+	case state.EventSyncAbort:
+		detail = fmt.Sprintf("aborted sync, restored %s stage branch(es)", event.Payload["restored"])
+	case state.EventAgentSpawn:
+		detail = fmt.Sprintf("spawned %s agent (%s)", event.Payload["phase"], event.Payload["harness"])
+		if pid := event.Payload["pid"]; pid != "" {
+			detail += " pid " + pid
+		}
+	case state.EventAgentExit:
+		detail = fmt.Sprintf("%s agent pid %s exited with code %s", event.Payload["phase"], event.Payload["pid"], event.Payload["exit_code"])
+	case state.EventPause:
+		detail = "paused the pipeline"
+	case state.EventResume:
+		detail = "resumed the pipeline"
+	case state.EventLegacyImport:
+		detail = fmt.Sprintf("imported %s stage(s) from %s", event.Payload["stages"], event.Payload["source"])
+	default:
+		detail = event.Type + formatEventPayload(event.Payload)
+	}
+
+	line := fmt.Sprintf("%s  %-20s %s", event.Time, stage, detail)
+	if actor := strings.TrimSpace(event.Actor); actor != "" {
+		line = fmt.Sprintf("%s  · %s", line, actor)
+	}
+
+	return line
+}
+
+func formatEventPayload(payload map[string]string) string {
+	if len(payload) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(payload))
+	for key := range payload {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", key, payload[key]))
+	}
+
+	return " " + strings.Join(parts, " ")
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+func TestFormatStackEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event state.Event
+		want  string
+	}{
+		{
+			name: "transition",
+			event: state.Event{
+				Time:    "2026-01-02T03:04:05Z",
+				Type:    state.EventStageTransition,
+				Actor:   "mcp:report_stage_done",
+				Stage:   "foundation",
+				Payload: map[string]string{"from": "implementing", "to": "ai-review"},
+			},
+			want: "2026-01-02T03:04:05Z  foundation           implementing -> ai-review  · mcp:report_stage_done",
+		},
+		{
+			name: "skip with reason",
+			event: state.Event{
+				Time:    "2026-01-02T03:04:05Z",
+				Type:    state.EventStageTransition,
+				Stage:   "docs",
+				Payload: map[string]string{"from": "failed", "to": "done", "action": "skip", "reason": "done by hand"},
+			},
+			want: "2026-01-02T03:04:05Z  docs                 failed -> done (skip): done by hand",
+		},
+		{
+			name: "force push",
+			event: state.Event{
+				Time:    "2026-01-02T03:04:05Z",
+				Type:    state.EventPush,
+				Stage:   "foundation",
+				Payload: map[string]string{"branch": "checkout/1/foundation", "force_with_lease": "true"},
+			},
+			want: "2026-01-02T03:04:05Z  foundation           pushed checkout/1/foundation (--force-with-lease)",
+		},
+		{
+			name: "pause",
+			event: state.Event{
+				Time:  "2026-01-02T03:04:05Z",
+				Type:  state.EventPause,
+				Actor: "m stack pause",
+			},
+			want: "2026-01-02T03:04:05Z  -                    paused the pipeline  · m stack pause",
+		},
+		{
+			name: "unknown type",
+			event: state.Event{
+				Time:    "2026-01-02T03:04:05Z",
+				Type:    "custom",
+				Payload: map[string]string{"b": "2", "a": "1"},
+			},
+			want: "2026-01-02T03:04:05Z  -                    custom a=1 b=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatStackEvent(tt.event); got != tt.want {
+				t.Fatalf("formatStackEvent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterStackEvents(t *testing.T) {
+	events := []state.Event{
+		{Stage: "one", Type: "a"},
+		{Stage: "two", Type: "b"},
+		{Stage: "one", Type: "c"},
+		{Stage: "one", Type: "d"},
+	}
+
+	got := filterStackEvents(events, "one", 2)
+	if len(got) != 2 || got[0].Type != "c" || got[1].Type != "d" {
+		t.Fatalf("filterStackEvents() = %+v, want last two events for stage one", got)
+	}
+
+	if got := filterStackEvents(events, "", 0); len(got) != len(events) {
+		t.Fatalf("len(filterStackEvents()) = %d, want %d", len(got), len(events))
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
+	"github.com/spf13/cobra"
+)
+
+func newStackPauseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pause",
+		Short: "Stop the automated pipeline from starting more stages",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			var stackName string
+			err = updateState(cmd, repo, func(stacksFile *state.Stacks) error {
+				stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
+				if err != nil {
+					return err
+				}
+				stackName = stack.Name
+				return state.SetPaused(stacksFile, stack.Name, true)
+			})
+			if err != nil {
+				return err
+			}
+
+			outSuccess(cmd.OutOrStdout(), "Paused stack %q. Running agents finish their phase; no new stages start.", stackName)
+			outInfo(cmd.OutOrStdout(), "Run `m stack resume` to continue.")
+			return writeResult(cmd, stackRunResult{Stack: stackName, Paused: true, Started: []string{}})
+		},
+	}
+}
+
+func newStackResumeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resume",
+		Short: "Resume a paused pipeline and start stages that are ready",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			stackName, started, err := resumeStack(cmd, repo, stackNameFromFlag(cmd))
+			if err != nil {
+				return err
+			}
+
+			outSuccess(cmd.OutOrStdout(), "Resumed stack %q.", stackName)
+			if len(started) > 0 {
+				outInfo(cmd.OutOrStdout(), "Stages now implementing: %s", strings.Join(started, ", "))
+			}
+			if started == nil {
+				started = []string{}
+			}
+			return writeResult(cmd, stackRunResult{Stack: stackName, Started: started})
+		},
+	}
+}
+
+// resumeStack unpauses a stack and fills its free slots with ready stages.
+// Stages started before a spawn failure are kept.
+func resumeStack(cmd *cobra.Command, repo *repoContext, stackFlag string) (string, []string, error) {
+	var stackName string
+	var launches []workflow.Launch
+	var agent workflow.Agent
+	var startErr error
+	err := updateState(cmd, repo, func(stacksFile *state.Stacks) error {
+		stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackFlag)
+		if err != nil {
+			return err
+		}
+		stackName = stack.Name
+		if err := state.SetPaused(stacksFile, stack.Name, false); err != nil {
+			return err
+		}
+
+		if state.NextPendingStage(stack) == nil || state.FreeSlots(stack) == 0 {
+			return nil
+		}
+		_, agent, err = workflow.LoadAgent()
+		if err != nil {
+			startErr = err
+			return nil
+		}
+		launches, startErr = workflow.ClaimReadyStages(stacksFile, stack)
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	started, launchErr := workflow.LaunchStages(cmd.Context(), repo.rootPath, commandActor(cmd), launches, agent)
+	return stackName, started, errors.Join(startErr, launchErr)
+}
@@ -1,17 +1,14 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
-	"github.com/mlawd/m-cli/internal/gitx"
-	"github.com/mlawd/m-cli/internal/paths"
-	"github.com/mlawd/m-cli/internal/plan"
+	"github.com/mlawd/m-cli/internal/errs"
 	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
 	"github.com/spf13/cobra"
 )
 
@@ -33,6 +30,10 @@ func newStackRootCmd() *cobra.Command {
 		newStackCurrentCmd(),
 		newStackRunCmd(),
 		newStackWatchCmd(),
+		newStackSuperviseCmd(),
+		newStackPauseCmd(),
+		newStackResumeCmd(),
+		newStackLogCmd(),
 	)
 
 	return cmd
@@ -54,36 +55,36 @@ func newStackRemoveCmd() *cobra.Command {
 				return err
 			}
 
-			stacksFile, err := loadState(repo)
-			if err != nil {
-				return err
-			}
-
-			stack, stackIdx := state.FindStack(stacksFile, stackName)
-			if stack == nil {
-				return fmt.Errorf("stack %q not found", stackName)
-			}
+			var removedStackName string
+			var removedWorktrees string
+			err = updateState(cmd, repo, func(stacksFile *state.Stacks) error {
+				stack, stackIdx := state.FindStack(stacksFile, stackName)
+				if stack == nil {
+					return state.StackNotFoundError(stackName)
+				}
 
-			if !force && stackHasStartedStages(stack) {
-				return fmt.Errorf("stack %q has started stages; rerun with --force", stack.Name)
-			}
+				if !force && stackHasStartedStages(stack) {
+					return fmt.Errorf("stack %q has started stages; rerun with --force", stack.Name)
+				}
 
-			if deleteWorktrees {
-				stackWorktreesDir := filepath.Join(state.StacksDir(repo.rootPath), filepath.FromSlash(strings.Trim(stack.Name, "/")))
-				if err := os.RemoveAll(stackWorktreesDir); err != nil {
-					return err
+				if deleteWorktrees {
+					stackWorktreesDir := filepath.Join(state.StacksDir(repo.rootPath), filepath.FromSlash(strings.Trim(stack.Name, "/")))
+					if err := os.RemoveAll(stackWorktreesDir); err != nil {
+						return err
+					}
+					outStyled(cmd.OutOrStdout(), ansiBlue, "🧹", "Removed worktrees: %s", stackWorktreesDir)
+					removedWorktrees = stackWorktreesDir
 				}
-				outStyled(cmd.OutOrStdout(), ansiBlue, "🧹", "Removed worktrees: %s", stackWorktreesDir)
-			}
 
-			var removedStackName string
-			stacksFile.Stacks, removedStackName = removeStackByIndex(stacksFile.Stacks, stackIdx)
-			if err := state.SaveStacks(repo.rootPath, stacksFile); err != nil {
+				stacksFile.Stacks, removedStackName = removeStackByIndex(stacksFile.Stacks, stackIdx)
+				return nil
+			})
+			if err != nil {
 				return err
 			}
 
 			outSuccess(cmd.OutOrStdout(), "Removed stack %q", removedStackName)
-			return nil
+			return writeResult(cmd, stackRemoveResult{Stack: removedStackName, RemovedWorktrees: removedWorktrees})
 		},
 	}
 
@@ -93,6 +94,11 @@ func newStackRemoveCmd() *cobra.Command {
 	return cmd
 }
 
+type stackRemoveResult struct {
+	Stack            string `json:"stack"`
+	RemovedWorktrees string `json:"removed_worktrees,omitempty"`
+}
+
 func removeStackByIndex(stacks []state.Stack, index int) ([]state.Stack, string) {
 	removedName := stacks[index].Name
 	return append(stacks[:index], stacks[index+1:]...), removedName
@@ -109,23 +115,37 @@ func stackHasStartedStages(stack *state.Stack) bool {
 }
 
 func newStackSyncCmd() *cobra.Command {
-	var noPrune bool
+	var opts workflow.SyncOptions
+	var continueSync bool
+	var abortSync bool
 
 	cmd := &cobra.Command{
 		Use:   "sync",
 		Short: "Prune merged stages, remove local resources, and rebase remaining stages",
-		Args:  cobra.NoArgs,
+		Long: "Prune merged stages, remove their worktrees and local branches, and rebase the remaining stages in dependency order.\n" +
+			"A rebase conflict is aborted and fails the sync. With --interactive-conflicts the conflicted worktree is left mid-rebase instead;\n" +
+			"resolve it and run `m stack sync --continue` to finish the remaining stages, or `m stack sync --abort` to put every branch back.",
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runStackSync(cmd, noPrune)
+			if continueSync && abortSync {
+				return errs.New(errs.Usage, "--continue and --abort cannot be used together")
+			}
+			if (continueSync || abortSync) && (opts.NoPrune || opts.InteractiveConflicts) {
+				return errs.New(errs.Usage, "--continue and --abort keep the options of the interrupted sync; drop --no-prune and --interactive-conflicts")
+			}
+			return runStackSync(cmd, opts, continueSync, abortSync)
 		},
 	}
 
-	cmd.Flags().BoolVar(&noPrune, "no-prune", false, "Keep merged stages in state and only rebase started branches")
+	cmd.Flags().BoolVar(&opts.NoPrune, "no-prune", false, "Keep merged stages in state and only rebase started branches")
+	cmd.Flags().BoolVar(&opts.InteractiveConflicts, "interactive-conflicts", false, "Stop at a rebase conflict and leave the worktree mid-rebase to resolve")
+	cmd.Flags().BoolVar(&continueSync, "continue", false, "Continue a sync stopped at a conflict once it is resolved")
+	cmd.Flags().BoolVar(&abortSync, "abort", false, "Abort a sync stopped at a conflict and restore the stage branches")
 
 	return cmd
 }
 
-func runStackSync(cmd *cobra.Command, noPrune bool) error {
+func runStackSync(cmd *cobra.Command, opts workflow.SyncOptions, continueSync, abortSync bool) error {
 	repo, err := discoverRepoContext()
 	if err != nil {
 		return err
@@ -141,384 +161,41 @@ func runStackSync(cmd *cobra.Command, noPrune bool) error {
 		return err
 	}
 
-	repoInfo, err := gitx.DiscoverRepo(repo.rootPath)
-	if err != nil {
-		return err
-	}
-
-	pruneMerged := !noPrune
-	if pruneMerged {
-		if _, err := exec.LookPath("gh"); err != nil {
-			return fmt.Errorf("gh CLI is required for stack sync prune mode; rerun with --no-prune to skip merged-stage pruning")
-		}
-	}
-
-	stageInfos := buildStageSyncInfos(stack, repoInfo.DefaultBranch)
-	mergedByBranch := map[string]bool{}
-	if pruneMerged {
-		for _, info := range stageInfos {
-			merged, err := stagePRMerged(repo.rootPath, info.Branch)
-			if err != nil {
-				return err
-			}
-			mergedByBranch[info.Branch] = merged
-		}
-	}
-
-	mutated := false
-	prunedCount := 0
-
-	parentBranch := repoInfo.DefaultBranch
-	rebasedCount := 0
-
-	for _, info := range stageInfos {
-		if pruneMerged && mergedByBranch[info.Branch] {
-			continue
-		}
-
-		stage := &stack.Stages[info.Index]
-		branch := info.Branch
-
-		if !gitx.BranchExists(repo.rootPath, branch) {
-			continue
-		}
-
-		worktree := strings.TrimSpace(stage.Worktree)
-		if worktree == "" {
-			worktree = filepath.Join(state.StacksDir(repo.rootPath), filepath.FromSlash(stack.Name), filepath.FromSlash(stage.ID))
-			mutated = true
-		}
-
-		if _, err := os.Stat(worktree); os.IsNotExist(err) {
-			if err := os.MkdirAll(filepath.Dir(worktree), 0o755); err != nil {
-				return err
-			}
-			if err := gitx.AddWorktree(repo.rootPath, worktree, branch); err != nil {
-				return err
-			}
-			outSuccess(cmd.OutOrStdout(), "Created worktree: %s", worktree)
-		} else if err != nil {
-			return err
-		}
-
-		rebaseArgs := []string{"rebase", parentBranch}
-		rebaseMode := "plain"
-		if shouldTransplantRebase(info, pruneMerged, mergedByBranch, parentBranch) {
-			upstream, err := resolveTransplantUpstream(repo.rootPath, branch, info.OldParent)
-			if err != nil {
-				return err
-			}
-			if strings.TrimSpace(upstream) == "" {
-				outWarn(cmd.OutOrStdout(), "Could not resolve upstream %s for %s; falling back to plain rebase onto %s", info.OldParent, branch, parentBranch)
-			} else {
-				rebaseArgs = []string{"rebase", "--onto", parentBranch, upstream}
-				rebaseMode = "transplant"
-				outStyled(cmd.OutOrStdout(), ansiBlue, "🔄", "Transplant rebasing %s onto %s (from %s)", branch, parentBranch, upstream)
-			}
-		} else {
-			outStyled(cmd.OutOrStdout(), ansiBlue, "🔄", "Rebasing %s onto %s", branch, parentBranch)
-		}
-
-		if err := runRebaseWithAbort(func(dir string, args ...string) (string, error) {
-			return gitx.Run(dir, args...)
-		}, worktree, rebaseArgs, stage.ID, branch, rebaseMode); err != nil {
-			return err
-		}
-
-		stage.Branch = branch
-		stage.Worktree = worktree
-		stage.Parent = parentBranch
-		parentBranch = branch
-		rebasedCount++
-		mutated = true
-	}
-
-	if pruneMerged {
-		removed, changed, err := pruneMergedStages(stack,
-			func(branch string) (bool, error) {
-				return mergedByBranch[branch], nil
-			},
-			func(stage state.Stage, branch string) error {
-				if err := removeStageWorktree(repo.rootPath, stage.Worktree); err != nil {
-					return err
-				}
-				if err := removeLocalStageBranch(repo.rootPath, branch); err != nil {
-					return err
-				}
-				return nil
-			},
-		)
+	service := workflowService(cmd, repo, false)
+	if abortSync {
+		result, err := service.AbortSync(stack.Name)
 		if err != nil {
 			return err
 		}
-		prunedCount = removed
-		mutated = mutated || changed
-	}
-
-	if mutated {
-		if err := state.SaveStacks(repo.rootPath, stacksFile); err != nil {
-			return err
-		}
-	}
-
-	if pruneMerged {
-		if prunedCount == 0 {
-			outInfo(cmd.OutOrStdout(), "No merged stage PRs found to prune")
-		} else {
-			outSuccess(cmd.OutOrStdout(), "Pruned %d merged stage(s)", prunedCount)
-		}
-	}
-
-	if rebasedCount == 0 {
-		outInfo(cmd.OutOrStdout(), "Nothing to rebase (no started stage branches)")
-		return nil
-	}
-
-	outSuccess(cmd.OutOrStdout(), "Synced stack: rebased %d stage branch(es)", rebasedCount)
-	return nil
-}
-
-type stackSyncStageInfo struct {
-	Index        int
-	Branch       string
-	OldParent    string
-	ParentMerged bool
-}
-
-func buildStageSyncInfos(stack *state.Stack, defaultBranch string) []stackSyncStageInfo {
-	infos := make([]stackSyncStageInfo, 0, len(stack.Stages))
-	for idx := range stack.Stages {
-		branch := stageBranchFor(stack, idx)
-		oldParent := strings.TrimSpace(defaultBranch)
-		parentMerged := false
-		if idx > 0 {
-			oldParent = stageBranchFor(stack, idx-1)
-			parentMerged = true
-		}
-
-		infos = append(infos, stackSyncStageInfo{
-			Index:        idx,
-			Branch:       branch,
-			OldParent:    oldParent,
-			ParentMerged: parentMerged,
-		})
-	}
-
-	return infos
-}
-
-func shouldTransplantRebase(info stackSyncStageInfo, pruneMerged bool, mergedByBranch map[string]bool, currentParent string) bool {
-	if !pruneMerged || !info.ParentMerged {
-		return false
-	}
-	if strings.TrimSpace(info.OldParent) == "" {
-		return false
-	}
-	if strings.TrimSpace(currentParent) == strings.TrimSpace(info.OldParent) {
-		return false
-	}
-
-	return mergedByBranch[info.OldParent]
-}
-
-func runRebaseWithAbort(
-	runGit func(dir string, args ...string) (string, error),
-	worktree string,
-	rebaseArgs []string,
-	stageID string,
-	branch string,
-	mode string,
-) error {
-	if _, err := runGit(worktree, rebaseArgs...); err != nil {
-		if _, abortErr := runGit(worktree, "rebase", "--abort"); abortErr != nil {
-			return fmt.Errorf("rebase failed for stage %q (%s) [%s]: %w\nRebase abort also failed in %s: %v\nResolve manually in %s (`git rebase --abort`), then rerun `m stack sync`", stageID, branch, mode, err, worktree, abortErr, worktree)
-		}
-		return fmt.Errorf("rebase failed for stage %q (%s) [%s]: %w\nAborted rebase in %s; resolve issues and rerun `m stack sync`", stageID, branch, mode, err, worktree)
-	}
-
-	return nil
-}
-
-func resolveTransplantUpstream(repoRoot, stageBranch, oldParent string) (string, error) {
-	parent := strings.TrimSpace(oldParent)
-	if parent == "" {
-		return "", nil
+		outSuccess(cmd.OutOrStdout(), "Aborted sync: restored %d stage branch(es)", len(result.Restored))
+		return writeResult(cmd, result)
 	}
 
-	candidates := []string{parent, "origin/" + parent}
-	for _, candidate := range candidates {
-		exists, err := gitCommitRefExists(repoRoot, candidate)
-		if err != nil {
-			return "", err
-		}
-		if !exists {
-			continue
-		}
-
-		isAncestor, err := gitIsAncestor(repoRoot, candidate, stageBranch)
-		if err != nil {
-			return "", err
-		}
-		if isAncestor {
-			return candidate, nil
-		}
-
-		mergeBase, err := gitMergeBase(repoRoot, candidate, stageBranch)
-		if err != nil {
-			continue
-		}
-		if strings.TrimSpace(mergeBase) != "" {
-			return mergeBase, nil
-		}
+	var result *workflow.SyncResult
+	if continueSync {
+		result, err = service.ContinueSync(stack.Name)
+	} else {
+		result, err = service.SyncStack(stack.Name, opts)
 	}
-
-	return "", nil
-}
-
-func gitCommitRefExists(repoRoot, ref string) (bool, error) {
-	if strings.TrimSpace(ref) == "" {
-		return false, nil
-	}
-	_, err := gitx.Run(repoRoot, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
 	if err != nil {
-		return false, nil
-	}
-
-	return true, nil
-}
-
-func gitIsAncestor(repoRoot, ancestor, descendant string) (bool, error) {
-	if strings.TrimSpace(ancestor) == "" || strings.TrimSpace(descendant) == "" {
-		return false, nil
-	}
-	_, err := gitx.Run(repoRoot, "merge-base", "--is-ancestor", ancestor, descendant)
-	if err != nil {
-		return false, nil
-	}
-
-	return true, nil
-}
-
-func gitMergeBase(repoRoot, a, b string) (string, error) {
-	if strings.TrimSpace(a) == "" || strings.TrimSpace(b) == "" {
-		return "", nil
-	}
-
-	return gitx.Run(repoRoot, "merge-base", a, b)
-}
-
-func pruneMergedStages(
-	stack *state.Stack,
-	isMerged func(branch string) (bool, error),
-	cleanup func(stage state.Stage, branch string) error,
-) (int, bool, error) {
-	if stack == nil {
-		return 0, false, fmt.Errorf("stack is required")
-	}
-
-	remaining := make([]state.Stage, 0, len(stack.Stages))
-	prunedCount := 0
-	currentStage := strings.TrimSpace(stack.CurrentStage)
-	preferredCurrentIndex := -1
-	currentStillExists := false
-
-	for i := range stack.Stages {
-		stage := stack.Stages[i]
-		branch := strings.TrimSpace(stage.Branch)
-		if branch == "" {
-			branch = stageBranchName(stack.Name, i, stage.ID)
-		}
-
-		merged, err := isMerged(branch)
-		if err != nil {
-			return 0, false, err
-		}
-
-		if merged {
-			if err := cleanup(stage, branch); err != nil {
-				return 0, false, err
-			}
-			if currentStage != "" && stage.ID == currentStage {
-				preferredCurrentIndex = len(remaining)
-			}
-			prunedCount++
-			continue
-		}
-
-		if currentStage != "" && stage.ID == currentStage {
-			currentStillExists = true
+		if result != nil && result.Conflict != nil && len(result.Rebased) > 0 {
+			outInfo(cmd.OutOrStdout(), "Rebased %d stage branch(es) before the conflict", len(result.Rebased))
 		}
-
-		remaining = append(remaining, stage)
-	}
-
-	if prunedCount == 0 {
-		return 0, false, nil
-	}
-
-	stack.Stages = remaining
-	if currentStillExists {
-		return prunedCount, true, nil
-	}
-
-	if len(remaining) == 0 {
-		stack.CurrentStage = ""
-		return prunedCount, true, nil
-	}
-
-	if preferredCurrentIndex >= 0 && preferredCurrentIndex < len(remaining) {
-		stack.CurrentStage = remaining[preferredCurrentIndex].ID
-		return prunedCount, true, nil
-	}
-
-	stack.CurrentStage = remaining[len(remaining)-1].ID
-	return prunedCount, true, nil
-}
-
-func stagePRMerged(repoRoot, headBranch string) (bool, error) {
-	out, err := runGH(repoRoot, "pr", "list", "--state", "merged", "--head", headBranch, "--json", "number", "--limit", "1")
-	if err != nil {
-		return false, err
-	}
-
-	var prs []struct {
-		Number int `json:"number"`
-	}
-	if err := json.Unmarshal([]byte(out), &prs); err != nil {
-		return false, fmt.Errorf("parse gh pr list output: %w", err)
-	}
-
-	return len(prs) > 0, nil
-}
-
-func removeStageWorktree(repoRoot, worktree string) error {
-	trimmed := strings.TrimSpace(worktree)
-	if trimmed == "" {
-		return nil
-	}
-
-	if _, err := os.Stat(trimmed); os.IsNotExist(err) {
-		return nil
-	} else if err != nil {
 		return err
 	}
 
-	_, err := gitx.Run(repoRoot, "worktree", "remove", "--force", trimmed)
-	return err
-}
-
-func removeLocalStageBranch(repoRoot, branch string) error {
-	trimmed := strings.TrimSpace(branch)
-	if trimmed == "" {
-		return nil
+	if result.Pruned > 0 {
+		outSuccess(cmd.OutOrStdout(), "Pruned %d merged stage(s)", result.Pruned)
+	} else if !opts.NoPrune && !continueSync {
+		outInfo(cmd.OutOrStdout(), "No merged stage PRs found to prune")
 	}
 
-	if !gitx.BranchExists(repoRoot, trimmed) {
-		return nil
+	if len(result.Rebased) == 0 {
+		outInfo(cmd.OutOrStdout(), "Nothing to rebase (no started stage branches)")
+	} else {
+		outSuccess(cmd.OutOrStdout(), "Synced stack: rebased %d stage branch(es)", len(result.Rebased))
 	}
-
-	_, err := gitx.Run(repoRoot, "branch", "-D", trimmed)
-	return err
+	return writeResult(cmd, result)
 }
 
 func newStackPushCmd() *cobra.Command {
@@ -527,10 +204,6 @@ func newStackPushCmd() *cobra.Command {
 		Short: "Push started stage branches with force-with-lease",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if _, err := exec.LookPath("gh"); err != nil {
-				return fmt.Errorf("gh CLI is required for stack push")
-			}
-
 			repo, err := discoverRepoContext()
 			if err != nil {
 				return err
@@ -546,53 +219,21 @@ func newStackPushCmd() *cobra.Command {
 				return err
 			}
 
-			startedStageIndexes, err := startedStageIndexes(stack, func(branch string) bool {
-				return gitx.BranchExists(repo.rootPath, branch)
-			})
+			result, err := workflowService(cmd, repo, true).PushStack(stack)
 			if err != nil {
 				return err
 			}
 
-			if len(startedStageIndexes) == 0 {
+			if len(result.Pushed) == 0 {
 				outInfo(cmd.OutOrStdout(), "Nothing to push (no started stage branches)")
-				return nil
-			}
-
-			for _, stageIndex := range startedStageIndexes {
-				stage := stack.Stages[stageIndex]
-				outAction(cmd.OutOrStdout(), "%s PR", stage.ID)
-				if err := pushStageAndEnsurePROpts(cmd, repo.rootPath, stack, stageIndex, true, "  "); err != nil {
-					return err
-				}
-			}
-
-			if err := syncStackPRDescriptions(cmd, repo.rootPath, stack, startedStageIndexes, "  "); err != nil {
-				return err
+			} else {
+				outAction(cmd.OutOrStdout(), "Pushed %d stage branch(es) with --force-with-lease", len(result.Pushed))
 			}
-
-			outAction(cmd.OutOrStdout(), "Pushed %d stage branch(es) with --force-with-lease", len(startedStageIndexes))
-			return nil
+			return writeResult(cmd, result)
 		},
 	}
 }
 
-func startedStageIndexes(stack *state.Stack, localBranchExists func(branch string) bool) ([]int, error) {
-	if stack == nil {
-		return nil, fmt.Errorf("stack is required")
-	}
-
-	indexes := make([]int, 0, len(stack.Stages))
-	for idx := range stack.Stages {
-		branch := stageBranchFor(stack, idx)
-		if !localBranchExists(branch) {
-			continue
-		}
-		indexes = append(indexes, idx)
-	}
-
-	return indexes, nil
-}
-
 func newStackNewCmd() *cobra.Command {
 	var planFile string
 	var stackType string
@@ -602,57 +243,27 @@ func newStackNewCmd() *cobra.Command {
 		Short: "Create a stack, optionally from a markdown plan file",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			stackName := strings.TrimSpace(args[0])
-			if err := paths.EnsureValidStackName(stackName); err != nil {
-				return err
-			}
-
-			normalizedStackType := state.NormalizeStackType(stackType)
-			if normalizedStackType != "" && !state.IsValidStackType(normalizedStackType) {
-				return fmt.Errorf("invalid stack type %q; valid values: feat, fix, chore", stackType)
-			}
-
 			repo, err := discoverRepoContext()
 			if err != nil {
 				return err
 			}
 
-			stacksFile, err := loadState(repo)
+			stack, err := workflowService(cmd, repo, false).CreateStack(workflow.NewStackOptions{
+				Name:     args[0],
+				Type:     stackType,
+				PlanFile: planFile,
+			})
 			if err != nil {
 				return err
 			}
 
-			if existing, _ := state.FindStack(stacksFile, stackName); existing != nil {
-				return fmt.Errorf("stack %q already exists", stackName)
-			}
-
-			resolvedPlanFile := ""
-			stages := []state.Stage{}
-			if strings.TrimSpace(planFile) != "" {
-				absolutePlanFile, parsedStages, err := parseStagesFromPlanFile(planFile)
-				if err != nil {
-					return err
-				}
-				resolvedPlanFile = absolutePlanFile
-				stages = parsedStages
-			}
-
-			stacksFile.Stacks = append(stacksFile.Stacks, state.NewStack(stackName, normalizedStackType, resolvedPlanFile, stages))
-			if err := state.SaveStacks(repo.rootPath, stacksFile); err != nil {
-				return err
-			}
-
-			if err := os.MkdirAll(filepath.Join(state.StacksDir(repo.rootPath), filepath.FromSlash(stackName)), 0o755); err != nil {
-				return err
-			}
-
-			displayName := formatStackDisplayName(state.Stack{Name: stackName, Type: normalizedStackType})
-			if resolvedPlanFile == "" {
+			displayName := formatStackDisplayName(*stack)
+			if stack.PlanFile == "" {
 				outSuccess(cmd.OutOrStdout(), "Created stack %q (no plan attached yet)", displayName)
 			} else {
-				outSuccess(cmd.OutOrStdout(), "Created stack %q with %d stage(s)", displayName, len(stages))
+				outSuccess(cmd.OutOrStdout(), "Created stack %q with %d stage(s)", displayName, len(stack.Stages))
 			}
-			return nil
+			return writeResult(cmd, newStackResult(*stack))
 		},
 	}
 
@@ -683,68 +294,18 @@ func newStackAttachPlanCmd() *cobra.Command {
 				return err
 			}
 
-			if strings.TrimSpace(stack.PlanFile) != "" {
-				return fmt.Errorf("stack %q already has an attached plan: %s", stack.Name, stack.PlanFile)
-			}
-
-			absolutePlanFile, parsedStages, err := parseStagesFromPlanFile(args[0])
+			attached, err := workflowService(cmd, repo, false).AttachPlan(stack.Name, args[0])
 			if err != nil {
 				return err
 			}
 
-			stack.PlanFile = absolutePlanFile
-			stack.Stages = parsedStages
-			stack.CurrentStage = ""
-
-			if err := state.SaveStacks(repo.rootPath, stacksFile); err != nil {
-				return err
-			}
-
-			outSuccess(cmd.OutOrStdout(), "Attached plan to stack %q with %d stage(s)", stack.Name, len(parsedStages))
-			outInfo(cmd.OutOrStdout(), "Plan file: %s", absolutePlanFile)
-			return nil
+			outSuccess(cmd.OutOrStdout(), "Attached plan to stack %q with %d stage(s)", attached.Name, len(attached.Stages))
+			outInfo(cmd.OutOrStdout(), "Plan file: %s", attached.PlanFile)
+			return writeResult(cmd, newStackResult(*attached))
 		},
 	}
 }
 
-func parseStagesFromPlanFile(planFile string) (string, []state.Stage, error) {
-	absolutePlanFile, err := filepath.Abs(strings.TrimSpace(planFile))
-	if err != nil {
-		return "", nil, err
-	}
-	if ext := strings.ToLower(filepath.Ext(absolutePlanFile)); ext != ".md" {
-		return "", nil, fmt.Errorf("plan file must use .md extension (markdown with YAML frontmatter)")
-	}
-
-	parsedPlan, err := plan.ParseFile(absolutePlanFile)
-	if err != nil {
-		return "", nil, err
-	}
-
-	stages := make([]state.Stage, 0, len(parsedPlan.Stages))
-	for _, stage := range parsedPlan.Stages {
-		risks := make([]state.StageRisk, 0, len(stage.Risks))
-		for _, risk := range stage.Risks {
-			risks = append(risks, state.StageRisk{
-				Risk:       risk.Risk,
-				Mitigation: risk.Mitigation,
-			})
-		}
-
-		stages = append(stages, state.Stage{
-			ID:             stage.ID,
-			Title:          stage.Title,
-			Outcome:        stage.Outcome,
-			Implementation: append([]string(nil), stage.Implementation...),
-			Validation:     append([]string(nil), stage.Validation...),
-			Risks:          risks,
-			Context:        stage.Context,
-		})
-	}
-
-	return absolutePlanFile, stages, nil
-}
-
 func newStackListCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "list",
@@ -761,9 +322,10 @@ func newStackListCmd() *cobra.Command {
 				return err
 			}
 
+			result := stackListResult{Stacks: []stackResult{}}
 			if len(stacksFile.Stacks) == 0 {
 				outInfo(cmd.OutOrStdout(), "No stacks found. Create one with `m stack new <name>`")
-				return nil
+				return writeResult(cmd, result)
 			}
 
 			currentStack, err := resolveCurrentStack(stacksFile, repo, stackNameFromFlag(cmd))
@@ -771,15 +333,19 @@ func newStackListCmd() *cobra.Command {
 				return err
 			}
 			for _, stack := range stacksFile.Stacks {
+				item := newStackResult(stack)
+				item.Current = currentStack != nil && stack.Name == currentStack.Name
+				result.Stacks = append(result.Stacks, item)
+
 				displayName := formatStackDisplayName(stack)
-				if currentStack != nil && stack.Name == currentStack.Name {
+				if item.Current {
 					outCurrent(cmd.OutOrStdout(), "%s  ·  %d stage(s)", displayName, len(stack.Stages))
 					continue
 				}
 				fmt.Fprintf(cmd.OutOrStdout(), "  %s  ·  %d stage(s)\n", displayName, len(stack.Stages))
 			}
 
-			return nil
+			return writeResult(cmd, result)
 		},
 	}
 }
@@ -804,44 +370,48 @@ func newStackCurrentCmd() *cobra.Command {
 			if strings.TrimSpace(override) != "" {
 				stack, _ := state.FindStack(stacksFile, override)
 				if stack == nil {
-					return fmt.Errorf("stack %q not found", override)
+					return state.StackNotFoundError(override)
 				}
-				outCurrent(cmd.OutOrStdout(), "Current stack: %s", formatStackDisplayName(*stack))
-				return nil
+				return printCurrentStack(cmd, stack.Name, formatStackDisplayName(*stack))
 			}
 
 			workspaceStackByPath, _ := state.CurrentWorkspaceStackStageByPath(repo.rootPath, repo.worktreePath)
 			if workspaceStackByPath != "" {
 				if stack, _ := state.FindStack(stacksFile, workspaceStackByPath); stack != nil {
-					outCurrent(cmd.OutOrStdout(), "Current stack: %s", formatStackDisplayName(*stack))
-				} else {
-					outCurrent(cmd.OutOrStdout(), "Current stack: %s", workspaceStackByPath)
+					return printCurrentStack(cmd, stack.Name, formatStackDisplayName(*stack))
 				}
-				return nil
+				return printCurrentStack(cmd, workspaceStackByPath, workspaceStackByPath)
 			}
 
 			workspaceStack, _ := state.CurrentWorkspaceStackStage(stacksFile, repo.worktreePath)
 			if workspaceStack != "" {
 				if stack, _ := state.FindStack(stacksFile, workspaceStack); stack != nil {
-					outCurrent(cmd.OutOrStdout(), "Current stack: %s", formatStackDisplayName(*stack))
-				} else {
-					outCurrent(cmd.OutOrStdout(), "Current stack: %s", workspaceStack)
+					return printCurrentStack(cmd, stack.Name, formatStackDisplayName(*stack))
 				}
-				return nil
+				return printCurrentStack(cmd, workspaceStack, workspaceStack)
 			}
 
 			if state.IsLinkedWorktree(repo.worktreePath, repo.rootPath) {
-				return nil
+				return writeResult(cmd, currentStackResult{})
 			}
 
 			if len(stacksFile.Stacks) == 1 {
-				outCurrent(cmd.OutOrStdout(), "Current stack: %s", formatStackDisplayName(stacksFile.Stacks[0]))
+				return printCurrentStack(cmd, stacksFile.Stacks[0].Name, formatStackDisplayName(stacksFile.Stacks[0]))
 			}
-			return nil
+			return writeResult(cmd, currentStackResult{})
 		},
 	}
 }
 
+type currentStackResult struct {
+	Stack string `json:"stack"`
+}
+
+func printCurrentStack(cmd *cobra.Command, name, displayName string) error {
+	outCurrent(cmd.OutOrStdout(), "Current stack: %s", displayName)
+	return writeResult(cmd, currentStackResult{Stack: name})
+}
+
 func formatStackDisplayName(stack state.Stack) string {
 	displayName := strings.TrimSpace(stack.Name)
 	if displayName == "" {
@@ -855,3 +425,46 @@ func formatStackDisplayName(stack state.Stack) string {
 
 	return fmt.Sprintf("%s (%s)", displayName, stackType)
 }
+
+type stackListResult struct {
+	Stacks []stackResult `json:"stacks"`
+}
+
+// stackResult summarizes a stack for JSON output.
+type stackResult struct {
+	Name     string        `json:"name"`
+	Type     string        `json:"type,omitempty"`
+	PlanFile string        `json:"plan_file,omitempty"`
+	Parallel int           `json:"parallel,omitempty"`
+	Paused   bool          `json:"paused"`
+	Current  bool          `json:"current,omitempty"`
+	Stages   []stageResult `json:"stages"`
+}
+
+type stageResult struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	Branch string `json:"branch,omitempty"`
+}
+
+func newStackResult(stack state.Stack) stackResult {
+	result := stackResult{
+		Name:     stack.Name,
+		Type:     state.NormalizeStackType(stack.Type),
+		PlanFile: stack.PlanFile,
+		Parallel: stack.Parallel,
+		Paused:   stack.Paused,
+		Stages:   make([]stageResult, 0, len(stack.Stages)),
+	}
+	for i := range stack.Stages {
+		stage := &stack.Stages[i]
+		result.Stages = append(result.Stages, stageResult{
+			ID:     stage.ID,
+			Title:  stage.Title,
+			Status: state.EffectiveStatus(stage),
+			Branch: stage.Branch,
+		})
+	}
+	return result
+}
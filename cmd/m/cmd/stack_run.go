@@ -1,131 +1,115 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"os/exec"
 	"strings"
 
-	"github.com/mlawd/m-cli/internal/config"
-	"github.com/mlawd/m-cli/internal/harness"
+	"github.com/mlawd/m-cli/internal/errs"
 	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
 	"github.com/spf13/cobra"
 )
 
 func newStackRunCmd() *cobra.Command {
-	return &cobra.Command{
+	var parallel int
+
+	cmd := &cobra.Command{
 		Use:   "run",
 		Short: "Start the automated implement -> review pipeline for a stack",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			repo, err := discoverRepoContext()
-			if err != nil {
-				return err
+			if parallel < 1 {
+				return errs.New(errs.Usage, "--parallel must be at least 1")
 			}
 
-			stacksFile, err := loadState(repo)
+			repo, err := discoverRepoContext()
 			if err != nil {
 				return err
 			}
 
-			stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
+			cfg, agent, err := workflow.LoadAgent()
 			if err != nil {
 				return err
 			}
 
-			// Must have at least one pending stage
-			firstPending := state.NextPendingStage(stack)
-			if firstPending == nil {
-				return fmt.Errorf("no pending stages in stack %q; all stages are in progress or complete", stack.Name)
-			}
-
-			// Load global config
-			cfg, err := config.Load()
-			if err != nil {
-				return err
-			}
-
-			if err := config.ValidateConfig(cfg); err != nil {
-				return fmt.Errorf("invalid config: %w", err)
-			}
-
-			// Verify harness binary is available
-			harnessName := strings.ToLower(cfg.AgentHarness)
-			if _, lookErr := exec.LookPath(harnessName); lookErr != nil {
-				return fmt.Errorf("%s not found in PATH; install it or run: m config set agent_harness <opencode|claude>", harnessName)
-			}
-
-			// Ensure agent definition files exist
-			if err := ensureAgentDefinitions(repo.rootPath, cfg); err != nil {
-				outWarn(cmd.OutOrStdout(), "Could not write agent definitions: %v", err)
-			}
-
-			// Ensure the first pending stage has a worktree
-			if err := ensureStageWorktree(repo, stacksFile, stack, firstPending); err != nil {
-				return fmt.Errorf("prepare stage worktree: %w", err)
-			}
-
-			// Transition first pending stage to implementing
-			if err := state.TransitionStage(stacksFile, stack.Name, firstPending.ID, state.StatusImplementing); err != nil {
-				return err
-			}
-			if err := state.SaveStacks(repo.rootPath, stacksFile); err != nil {
-				return err
-			}
-
-			// Spawn build agent
-			h, err := harness.ForConfig(cfg)
+			var stack state.Stack
+			var launches []workflow.Launch
+			var startErr error
+			err = updateState(cmd, repo, func(stacksFile *state.Stacks) error {
+				current, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
+				if err != nil {
+					return err
+				}
+
+				// Must have at least one pending stage whose dependencies are complete
+				if state.NextPendingStage(current) == nil {
+					if state.HasPendingStages(current) {
+						return fmt.Errorf("no stages ready in stack %q; pending stages are waiting on dependencies", current.Name)
+					}
+					return fmt.Errorf("no pending stages in stack %q; all stages are in progress or complete", current.Name)
+				}
+
+				// The limit is kept on the stack so the report_stage_done
+				// cascade refills free slots as stages reach human-review.
+				current.Parallel = parallel
+				// Running the stack explicitly resumes a paused pipeline.
+				if err := state.SetPaused(stacksFile, current.Name, false); err != nil {
+					return err
+				}
+				if state.FreeSlots(current) == 0 {
+					return fmt.Errorf("stack %q already has %d active stage(s); rerun with a higher --parallel to start more", current.Name, len(state.ActiveStages(current)))
+				}
+
+				// Ensure agent definition files exist
+				if err := ensureAgentDefinitions(repo.rootPath, cfg); err != nil {
+					outWarn(cmd.OutOrStdout(), "Could not write agent definitions: %v", err)
+				}
+
+				// Claim the ready stages; a failure still saves the stages
+				// already claimed.
+				launches, startErr = workflow.ClaimReadyStages(stacksFile, current)
+				stack = *current
+				return nil
+			})
 			if err != nil {
 				return err
 			}
 
-			worktreePath := firstPending.Worktree
-			if worktreePath == "" {
-				worktreePath = repo.rootPath
+			// Give each claimed stage its own worktree and build agent once
+			// the claim is saved; a stage that fails to start is left blocked.
+			started, launchErr := workflow.LaunchStages(cmd.Context(), repo.rootPath, commandActor(cmd), launches, agent)
+			startErr = errors.Join(startErr, launchErr)
+			if startErr != nil {
+				if len(started) > 0 {
+					outWarn(cmd.OutOrStdout(), "Started %s before the failure.", strings.Join(started, ", "))
+				}
+				return startErr
 			}
 
-			opts := harness.AgentOpts{
-				WorktreePath: worktreePath,
-				StageContext: firstPending.Context,
-				StackName:    stack.Name,
-				StageID:      firstPending.ID,
-				Phase:        "implementing",
+			if len(started) == 1 {
+				outSuccess(cmd.OutOrStdout(), "Stack %q started. Stage %q is now implementing.", formatStackDisplayName(stack), started[0])
+			} else {
+				outSuccess(cmd.OutOrStdout(), "Stack %q started. Stages now implementing: %s", formatStackDisplayName(stack), strings.Join(started, ", "))
 			}
-			opts.SystemPrompt = harness.BuildSystemPrompt(opts)
-
-			if err := h.SpawnBuildAgent(cmd.Context(), opts); err != nil {
-				return fmt.Errorf("spawn build agent: %w", err)
+			if parallel > 1 {
+				outInfo(cmd.OutOrStdout(), "Up to %d stages run at once; ready stages start as others reach human-review.", parallel)
 			}
-
-			outSuccess(cmd.OutOrStdout(), "Stack %q started. Stage %q is now implementing.", formatStackDisplayName(*stack), firstPending.ID)
 			outInfo(cmd.OutOrStdout(), "Run `m stack watch` to follow progress.")
 
-			return nil
+			return writeResult(cmd, stackRunResult{Stack: stack.Name, Started: started, Parallel: parallel})
 		},
 	}
-}
-
-func ensureStageWorktree(repo *repoContext, stacksFile *state.Stacks, stack *state.Stack, stage *state.Stage) error {
-	if strings.TrimSpace(stage.Worktree) != "" {
-		if _, err := os.Stat(stage.Worktree); err == nil {
-			return nil
-		}
-	}
-
-	_, stageIndex := state.FindStage(stack, stage.ID)
-	if stageIndex < 0 {
-		return fmt.Errorf("stage %q not found", stage.ID)
-	}
 
-	branch := strings.TrimSpace(stage.Branch)
-	if branch == "" {
-		branch = stageBranchName(stack.Name, stageIndex, stage.ID)
-	}
+	cmd.Flags().IntVar(&parallel, "parallel", 1, "Maximum number of stages to run at once")
 
-	parentBranch, err := parentBranchForStage(repo.rootPath, stack, stageIndex)
-	if err != nil {
-		return err
-	}
+	return cmd
+}
 
-	return startStageWorktreeOnly(repo, stacksFile, stack, stageIndex, branch, parentBranch)
+// stackRunResult is the JSON result of stack run, pause and resume.
+type stackRunResult struct {
+	Stack    string   `json:"stack"`
+	Paused   bool     `json:"paused"`
+	Started  []string `json:"started"`
+	Parallel int      `json:"parallel,omitempty"`
 }
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// TestScriptHarnessRunsPipeline drives `m stack run` through the
+// report_stage_done cascade with the script harness's built-in recipes.
+func TestScriptHarnessRunsPipeline(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the m binary")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	binDir := t.TempDir()
+	mBin := filepath.Join(binDir, "m")
+	build := exec.Command("go", "build", "-o", mBin, "..")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build m: %v\n%s", err, out)
+	}
+
+	t.Setenv("M_BIN", mBin)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	repoRoot := initGitRepoForCurrentCmdTests(t)
+	plan := `---
+version: 4
+title: Scripted
+stages:
+  - id: api
+    title: API
+  - id: ui
+    title: UI
+    depends_on: [api]
+---
+
+## Stage: api
+Build the API.
+
+## Stage: ui
+Build the UI.
+`
+	if err := os.WriteFile(filepath.Join(repoRoot, "plan.md"), []byte(plan), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"config", "set", "agent_harness", "script"},
+		{"init"},
+		{"stack", "new", "scripted"},
+		{"stack", "attach-plan", "plan.md"},
+		{"stack", "run"},
+	} {
+		if out, err := runRootCmdInDir(repoRoot, args...); err != nil {
+			t.Fatalf("m %v: %v\n%s", args, err, out)
+		}
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		stacks, err := state.LoadStacks(repoRoot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stack, _ := state.FindStack(stacks, "scripted")
+		reviewed := 0
+		for i := range stack.Stages {
+			stage := &stack.Stages[i]
+			// The review agent's exit is recorded by `m agent exec` shortly
+			// after it reports.
+			exited := stage.Agent != nil && stage.Agent.ExitCode != nil && *stage.Agent.ExitCode == 0
+			if state.EffectiveStatus(stage) == state.StatusHumanReview && exited {
+				reviewed++
+			}
+		}
+		if reviewed == len(stack.Stages) {
+			for _, stage := range stack.Stages {
+				if report := state.LatestReport(&stage, state.PhaseImplementing); report == nil || report.Commit == "" {
+					t.Fatalf("stage %q has no implementation report with a commit", stage.ID)
+				}
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pipeline did not finish; stages: %+v", stack.Stages)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
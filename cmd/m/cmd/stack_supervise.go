@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/config"
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
+	"github.com/spf13/cobra"
+)
+
+func newStackSuperviseCmd() *cobra.Command {
+	var interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "supervise",
+		Short: "Enforce agent timeouts for a running stack pipeline without the dashboard",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interval <= 0 {
+				return errs.New(errs.Usage, "--interval must be positive")
+			}
+
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			stacksFile, err := loadState(repo)
+			if err != nil {
+				return err
+			}
+
+			stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
+			if err != nil {
+				return err
+			}
+			stackName := stack.Name
+
+			supervisor, err := newStageSupervisor(repo.rootPath)
+			if err != nil {
+				return err
+			}
+
+			w := cmd.OutOrStdout()
+			outInfo(w, "Supervising stack %q every %s; press ctrl-c to stop.", stackName, interval)
+
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				notices, err := supervisor.check(ctx, stackName)
+				if err != nil {
+					return err
+				}
+				for _, notice := range notices {
+					outWarn(w, "%s", notice)
+					if err := writeWatchEvent(cmd, watchEvent{Event: watchEventNotice, Message: notice}); err != nil {
+						return err
+					}
+				}
+
+				stacksFile, err = state.LoadStacks(repo.rootPath)
+				if err != nil {
+					return err
+				}
+				stack, _ = state.FindStack(stacksFile, stackName)
+				if stack == nil {
+					return fmt.Errorf("stack no longer exists")
+				}
+				if len(state.ActiveStages(stack)) == 0 {
+					outSuccess(w, "No active stages left in stack %q.", stackName)
+					return writeWatchEvent(cmd, watchEvent{Event: watchEventComplete, Message: "no active stages left"})
+				}
+
+				select {
+				case <-ctx.Done():
+					return writeWatchEvent(cmd, watchEvent{Event: watchEventStopped, Message: "supervision stopped"})
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", 30*time.Second, "How often to check running agents")
+
+	return cmd
+}
+
+// stageSupervisor runs workflow.Supervise passes for the watch and supervise
+// commands with limits from global config.
+type stageSupervisor struct {
+	repoRoot string
+	limits   workflow.Limits
+	agent    *workflow.Agent
+}
+
+func newStageSupervisor(repoRoot string) (*stageSupervisor, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ValidateConfig(cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	limits, err := workflow.LimitsFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	supervisor := &stageSupervisor{repoRoot: repoRoot, limits: limits}
+	if limits.Retry {
+		// Without a usable harness, timed out stages are failed instead.
+		if _, agent, err := workflow.LoadAgent(); err == nil {
+			supervisor.agent = &agent
+		}
+	}
+
+	return supervisor, nil
+}
+
+// check runs one supervision pass and describes what it did.
+func (s *stageSupervisor) check(ctx context.Context, stackName string) ([]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	interventions, err := workflow.Supervise(ctx, s.repoRoot, stackName, s.limits, s.agent, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	notices := make([]string, 0, len(interventions))
+	for _, item := range interventions {
+		notices = append(notices, formatIntervention(item))
+	}
+	return notices, nil
+}
+
+func formatIntervention(item workflow.Intervention) string {
+	switch {
+	case item.Skipped:
+		return fmt.Sprintf("Stage %q: %s; agent runs on another host, left running.", item.StageID, item.Reason)
+	case item.Retried:
+		return fmt.Sprintf("Stage %q: %s; agent restarted.", item.StageID, item.Reason)
+	case item.Err != nil:
+		return fmt.Sprintf("Stage %q: %s; retry failed: %v", item.StageID, item.Reason, item.Err)
+	default:
+		return fmt.Sprintf("Stage %q: %s; marked failed.", item.StageID, item.Reason)
+	}
+}
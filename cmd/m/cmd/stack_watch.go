@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -10,7 +12,9 @@ import (
 )
 
 func newStackWatchCmd() *cobra.Command {
-	return &cobra.Command{
+	var plain bool
+
+	cmd := &cobra.Command{
 		Use:   "watch",
 		Short: "Watch the progress of a running stack pipeline",
 		Args:  cobra.NoArgs,
@@ -30,61 +34,248 @@ func newStackWatchCmd() *cobra.Command {
 				return err
 			}
 
-			w := cmd.OutOrStdout()
+			// The dashboard also enforces agent timeouts while it runs.
+			supervisor, supervisorErr := newStageSupervisor(repo.rootPath)
 
-			for {
-				// Reload state each iteration
-				stacksFile, err = state.LoadStacks(repo.rootPath)
-				if err != nil {
-					return err
+			if plain || !isTerminal(cmd.OutOrStdout()) {
+				if supervisorErr != nil {
+					outWarn(cmd.OutOrStdout(), "Agent timeouts are not enforced: %v", supervisorErr)
 				}
+				return watchPlain(cmd, repo, stack.Name, supervisor)
+			}
+
+			return runWatchTUI(cmd, repo, stack.Name, supervisor, supervisorErr)
+		},
+	}
+
+	cmd.Flags().BoolVar(&plain, "plain", false, "Print a refreshing status list instead of the interactive dashboard")
+
+	return cmd
+}
+
+// watchPlain prints the stage list as the stack state changes until the
+// pipeline stops or completes. On a terminal it redraws in place; otherwise
+// it prints each transition as a line.
+func watchPlain(cmd *cobra.Command, repo *repoContext, stackName string, supervisor *stageSupervisor) error {
+	w := cmd.OutOrStdout()
+	terminal := isTerminal(w)
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-				stack, _ = state.FindStack(stacksFile, stack.Name)
-				if stack == nil {
-					return fmt.Errorf("stack no longer exists")
+	updates, err := state.Watch(ctx, repo.rootPath, state.WatchOptions{})
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	var stack *state.Stack
+	var notices, transitions []string
+	printed := false
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return ctx.Err()
+			}
+			if update.Err != nil {
+				return update.Err
+			}
+			stack, _ = state.FindStack(update.Stacks, stackName)
+			if stack == nil {
+				return fmt.Errorf("stack no longer exists")
+			}
+			if !printed {
+				snapshot := newStackResult(*stack)
+				if err := writeWatchEvent(cmd, watchEvent{Event: watchEventSnapshot, Stack: &snapshot}); err != nil {
+					return err
+				}
+			}
+			for _, change := range update.Changes {
+				if change.Stack != stackName {
+					continue
+				}
+				line := fmt.Sprintf("%s  %s", time.Now().Format("15:04:05"), change)
+				transitions = append(transitions, line)
+				if !terminal {
+					fmt.Fprintln(w, line)
 				}
+				if err := writeWatchEvent(cmd, watchEvent{Event: watchEventChange, Change: newChangeResult(change)}); err != nil {
+					return err
+				}
+			}
+			if len(transitions) > 5 {
+				transitions = transitions[len(transitions)-5:]
+			}
 
-				// Clear screen
-				fmt.Fprint(w, "\033[2J\033[H")
-
-				// Header
-				displayName := formatStackDisplayName(*stack)
-				fmt.Fprintf(w, "%s  %d stages\n\n", displayName, len(stack.Stages))
-
-				allDone := true
-				for i := range stack.Stages {
-					s := &stack.Stages[i]
-					status := state.EffectiveStatus(s)
-					icon := statusIcon(status)
-					elapsed := ""
-
-					if status == state.StatusImplementing || status == state.StatusAIReview {
-						allDone = false
-						if s.StartedAt != "" {
-							if t, err := time.Parse(time.RFC3339, s.StartedAt); err == nil {
-								elapsed = "  " + formatDuration(time.Since(t))
-							}
+		case <-ticker.C:
+			// Supervisor changes arrive as state updates; the tick also
+			// refreshes elapsed times on a terminal.
+			if supervisor != nil {
+				found, err := supervisor.check(ctx, stackName)
+				if err != nil {
+					return err
+				}
+				notices = append(notices, found...)
+				if len(notices) > 5 {
+					notices = notices[len(notices)-5:]
+				}
+				if !terminal {
+					for _, notice := range found {
+						outWarn(w, "%s", notice)
+						if err := writeWatchEvent(cmd, watchEvent{Event: watchEventNotice, Message: notice}); err != nil {
+							return err
 						}
-					} else if status == state.StatusPending {
-						allDone = false
 					}
-
-					fmt.Fprintf(w, "   %s  %-20s %-16s%s\n", icon, s.ID, status, elapsed)
 				}
+			}
+			if !terminal {
+				continue
+			}
+		}
+
+		if stack == nil {
+			continue
+		}
+		if terminal {
+			fmt.Fprint(w, "\033[2J\033[H")
+			printPlainWatch(w, stack, notices, transitions)
+		} else if !printed {
+			printPlainWatch(w, stack, nil, nil)
+		}
+		printed = true
+
+		if stopped, running := stoppedStages(stack); len(stopped) > 0 && running == 0 {
+			outWarn(w, "Pipeline stopped at %s. Recover with `m stage retry`, `m stage reset` or `m stage skip`.", strings.Join(stopped, ", "))
+			return writeWatchEvent(cmd, watchEvent{Event: watchEventStopped, Stages: stopped})
+		}
+		if state.AllStagesComplete(stack) {
+			outSuccess(w, "All stages complete.")
+			return writeWatchEvent(cmd, watchEvent{Event: watchEventComplete})
+		}
+		if terminal {
+			fmt.Fprintln(w, "Press ctrl-c to detach (stack continues in background)")
+		}
+	}
+}
+
+// Kinds of line printed by stack watch and supervise with --output json.
+const (
+	watchEventSnapshot = "snapshot"
+	watchEventChange   = "change"
+	watchEventNotice   = "notice"
+	watchEventStopped  = "stopped"
+	watchEventComplete = "complete"
+)
 
-				fmt.Fprintln(w)
+// watchEvent is one line of stack watch or supervise JSON output.
+type watchEvent struct {
+	Time    string        `json:"time"`
+	Event   string        `json:"event"`
+	Stack   *stackResult  `json:"stack,omitempty"`
+	Change  *changeResult `json:"change,omitempty"`
+	Message string        `json:"message,omitempty"`
+	Stages  []string      `json:"stages,omitempty"`
+}
 
-				if allDone {
-					outSuccess(w, "All stages complete. Press ctrl-c to exit.")
-					return nil
-				}
+type changeResult struct {
+	Type  string `json:"type"`
+	Stack string `json:"stack"`
+	Stage string `json:"stage,omitempty"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
 
-				fmt.Fprintln(w, "Press ctrl-c to detach (stack continues in background)")
+func newChangeResult(change state.Change) *changeResult {
+	result := changeResult(change)
+	return &result
+}
 
-				time.Sleep(2 * time.Second)
+// writeWatchEvent prints event as a JSON line with --output json.
+func writeWatchEvent(cmd *cobra.Command, event watchEvent) error {
+	if !jsonOutput(cmd) {
+		return nil
+	}
+	event.Time = time.Now().UTC().Format(time.RFC3339)
+	return writeResultLine(cmd, event)
+}
+
+func printPlainWatch(w io.Writer, stack *state.Stack, notices, transitions []string) {
+	fmt.Fprintf(w, "%s  %d stages\n", formatStackDisplayName(*stack), len(stack.Stages))
+	if active := formatActiveStages(stack); active != "" {
+		fmt.Fprintln(w, active)
+	}
+	if stack.Paused {
+		fmt.Fprintln(w, "Pipeline paused; no new stages start until `m stack resume`.")
+	}
+	fmt.Fprintln(w)
+
+	for i := range stack.Stages {
+		fmt.Fprintln(w, "   "+formatWatchStageLine(&stack.Stages[i]))
+	}
+	fmt.Fprintln(w)
+
+	for _, line := range transitions {
+		fmt.Fprintln(w, line)
+	}
+	for _, notice := range notices {
+		outWarn(w, "%s", notice)
+	}
+}
+
+// stoppedStages lists stages that stopped the pipeline (failed, blocked or
+// with a lost agent) and counts the stages whose agents are still running.
+func stoppedStages(stack *state.Stack) ([]string, int) {
+	stopped := []string{}
+	running := 0
+	for i := range stack.Stages {
+		s := &stack.Stages[i]
+		status := state.EffectiveStatus(s)
+		switch {
+		case status == state.StatusImplementing || status == state.StatusAIReview:
+			if lost, _ := state.AgentLost(s); lost {
+				stopped = append(stopped, fmt.Sprintf("%s (agent exited)", s.ID))
+			} else {
+				running++
 			}
-		},
+		case state.StageStopped(s):
+			stopped = append(stopped, fmt.Sprintf("%s (%s)", s.ID, status))
+		}
+	}
+	return stopped, running
+}
+
+// formatWatchStageLine renders a stage as icon, id, status and what it is
+// doing: elapsed time and agent pid while active, the reason when stopped.
+func formatWatchStageLine(s *state.Stage) string {
+	status := state.EffectiveStatus(s)
+	icon := statusIcon(status)
+	detail := ""
+
+	if status == state.StatusImplementing || status == state.StatusAIReview {
+		if s.StartedAt != "" {
+			if t, err := time.Parse(time.RFC3339, s.StartedAt); err == nil {
+				detail = "  " + formatDuration(time.Since(t))
+			}
+		}
+		if lost, reason := state.AgentLost(s); lost {
+			icon = "!"
+			detail += "  " + reason
+		} else if s.Agent != nil {
+			detail += fmt.Sprintf("  pid %d", s.Agent.PID)
+		}
+	} else if state.StageStopped(s) && s.StatusReason != "" {
+		detail = "  " + s.StatusReason
 	}
+
+	return fmt.Sprintf("%s  %-20s %-16s%s", icon, s.ID, status, detail)
 }
 
 func statusIcon(status string) string {
@@ -99,6 +290,8 @@ func statusIcon(status string) string {
 		return "\u2713" // ✓
 	case state.StatusDone:
 		return "\u2713" // ✓
+	case state.StatusBlocked:
+		return "!"
 	default:
 		return "\u2717" // ✗
 	}
@@ -115,6 +308,22 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%ds", seconds)
 }
 
+// formatActiveStages lists the stages agents are working on and how many of
+// the stack's parallel slots they use.
+func formatActiveStages(stack *state.Stack) string {
+	active := state.ActiveStages(stack)
+	if len(active) == 0 {
+		return ""
+	}
+
+	ids := make([]string, 0, len(active))
+	for _, stage := range active {
+		ids = append(ids, stage.ID)
+	}
+
+	return fmt.Sprintf("Active: %s (%d/%d slots)", strings.Join(ids, ", "), len(active), max(stack.Parallel, 1))
+}
+
 func formatStackRunSummary(stack *state.Stack) string {
 	if stack == nil || len(stack.Stages) == 0 {
 		return ""
@@ -122,6 +331,7 @@ func formatStackRunSummary(stack *state.Stack) string {
 
 	activeIdx := -1
 	activeStatus := ""
+	activeCount := 0
 	completedCount := 0
 
 	for i := range stack.Stages {
@@ -130,8 +340,11 @@ func formatStackRunSummary(stack *state.Stack) string {
 			completedCount++
 		}
 		if s == state.StatusImplementing || s == state.StatusAIReview {
-			activeIdx = i
-			activeStatus = s
+			if activeIdx < 0 {
+				activeIdx = i
+				activeStatus = s
+			}
+			activeCount++
 		}
 	}
 
@@ -140,6 +353,9 @@ func formatStackRunSummary(stack *state.Stack) string {
 	}
 
 	displayName := formatStackDisplayName(*stack)
+	if activeCount > 1 {
+		return fmt.Sprintf("stack: %s \u2014 %d stages running, %d/%d reviewed", displayName, activeCount, completedCount, len(stack.Stages))
+	}
 	if activeIdx >= 0 {
 		return fmt.Sprintf("stack: %s \u2014 stage %d/%d: %s", displayName, activeIdx+1, len(stack.Stages), activeStatus)
 	}
@@ -150,37 +366,3 @@ func formatStackRunSummary(stack *state.Stack) string {
 
 	return fmt.Sprintf("stack: %s \u2014 %d/%d stages reviewed", displayName, completedCount, len(stack.Stages))
 }
-
-func startStageWorktreeOnly(repo *repoContext, stacksFile *state.Stacks, stack *state.Stack, stageIndex int, branch, parentBranch string) error {
-	if stack == nil || stageIndex < 0 || stageIndex >= len(stack.Stages) {
-		return fmt.Errorf("invalid stage index")
-	}
-
-	target := &stack.Stages[stageIndex]
-
-	if !gitxBranchExists(repo.rootPath, branch) {
-		if err := gitxCreateBranch(repo.rootPath, branch, parentBranch); err != nil {
-			return err
-		}
-	}
-
-	worktree := strings.TrimSpace(target.Worktree)
-	if worktree == "" {
-		worktree = stageWorktreePath(repo.rootPath, stack.Name, target.ID)
-	}
-
-	if _, err := statPath(worktree); err != nil {
-		if err := mkdirAll(worktree); err != nil {
-			return err
-		}
-		if err := gitxAddWorktree(repo.rootPath, worktree, branch); err != nil {
-			return err
-		}
-	}
-
-	target.Branch = branch
-	target.Worktree = worktree
-	target.Parent = parentBranch
-
-	return state.SaveStacks(repo.rootPath, stacksFile)
-}
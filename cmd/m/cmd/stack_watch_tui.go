@@ -0,0 +1,489 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
+	"github.com/spf13/cobra"
+)
+
+const (
+	watchTickInterval      = 500 * time.Millisecond
+	watchSuperviseInterval = 15 * time.Second
+	watchLogLines          = 12
+)
+
+var (
+	watchTitleStyle    = lipgloss.NewStyle().Bold(true)
+	watchSelectedStyle = lipgloss.NewStyle().Reverse(true)
+	watchLabelStyle    = lipgloss.NewStyle().Faint(true)
+	watchWarnStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	watchErrorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	watchPaneStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+)
+
+type (
+	watchTickMsg  time.Time
+	watchStateMsg struct {
+		stack   *state.Stack
+		prURLs  map[string]string
+		changes []state.Change
+		err     error
+	}
+	watchLogMsg struct {
+		stageID string
+		path    string
+		tail    string
+	}
+	watchActionMsg struct {
+		text string
+		err  error
+	}
+	watchSuperviseMsg struct {
+		notices []string
+		err     error
+	}
+)
+
+// watchModel is the interactive `m stack watch` dashboard: a stage list, a
+// detail pane for the selected stage and keys to act on it.
+type watchModel struct {
+	ctx        context.Context
+	cmd        *cobra.Command
+	repo       *repoContext
+	stackName  string
+	supervisor *stageSupervisor
+	updates    <-chan state.StateUpdate
+
+	stack         *state.Stack
+	prURLs        map[string]string
+	selected      int
+	logPath       string
+	logTail       string
+	transitions   []string
+	lastSupervise time.Time
+	width         int
+	height        int
+	status        string
+	notices       []string
+	err           error
+}
+
+func runWatchTUI(cmd *cobra.Command, repo *repoContext, stackName string, supervisor *stageSupervisor, supervisorErr error) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	model := &watchModel{
+		ctx:        ctx,
+		cmd:        cmd,
+		repo:       repo,
+		stackName:  stackName,
+		supervisor: supervisor,
+		width:      100,
+		height:     30,
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	updates, err := state.Watch(watchCtx, repo.rootPath, state.WatchOptions{})
+	if err != nil {
+		return err
+	}
+	model.updates = updates
+	if supervisorErr != nil {
+		model.notices = append(model.notices, fmt.Sprintf("Agent timeouts are not enforced: %v", supervisorErr))
+	}
+
+	program := tea.NewProgram(model, tea.WithAltScreen(), tea.WithContext(ctx), tea.WithOutput(cmd.OutOrStdout()))
+	final, err := program.Run()
+	if err != nil {
+		return err
+	}
+	if m, ok := final.(*watchModel); ok && m.err != nil {
+		return m.err
+	}
+	return nil
+}
+
+func (m *watchModel) Init() tea.Cmd {
+	return tea.Batch(m.waitForState(), watchTick())
+}
+
+func watchTick() tea.Cmd {
+	return tea.Tick(watchTickInterval, func(t time.Time) tea.Msg { return watchTickMsg(t) })
+}
+
+func (m *watchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		return m, m.handleKey(msg.String())
+
+	case watchTickMsg:
+		cmds := []tea.Cmd{watchTick(), m.loadLog()}
+		if m.supervisor != nil && time.Since(m.lastSupervise) >= watchSuperviseInterval {
+			m.lastSupervise = time.Now()
+			cmds = append(cmds, m.supervise())
+		}
+		return m, tea.Batch(cmds...)
+
+	case watchStateMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, tea.Quit
+		}
+		m.stack, m.prURLs = msg.stack, msg.prURLs
+		for _, change := range msg.changes {
+			m.transitions = append(m.transitions, fmt.Sprintf("%s  %s", time.Now().Format("15:04:05"), change))
+		}
+		if len(m.transitions) > 3 {
+			m.transitions = m.transitions[len(m.transitions)-3:]
+		}
+		if m.selected >= len(m.stack.Stages) {
+			m.selected = max(len(m.stack.Stages)-1, 0)
+		}
+		return m, tea.Batch(m.loadLog(), m.waitForState())
+
+	case watchLogMsg:
+		if stage := m.selectedStage(); stage != nil && stage.ID == msg.stageID {
+			m.logPath, m.logTail = msg.path, msg.tail
+		}
+		return m, nil
+
+	case watchActionMsg:
+		m.status = msg.text
+		if msg.err != nil {
+			m.status = "Error: " + msg.err.Error()
+		}
+		return m, nil
+
+	case watchSuperviseMsg:
+		if msg.err != nil {
+			m.notices = append(m.notices, fmt.Sprintf("Supervisor: %v", msg.err))
+		}
+		m.notices = append(m.notices, msg.notices...)
+		if len(m.notices) > 3 {
+			m.notices = m.notices[len(m.notices)-3:]
+		}
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m *watchModel) handleKey(key string) tea.Cmd {
+	switch key {
+	case "q", "ctrl+c", "esc":
+		return tea.Quit
+	case "up", "k":
+		if m.selected > 0 {
+			m.selected--
+		}
+		m.logPath, m.logTail = "", ""
+		return m.loadLog()
+	case "down", "j":
+		if m.stack != nil && m.selected < len(m.stack.Stages)-1 {
+			m.selected++
+		}
+		m.logPath, m.logTail = "", ""
+		return m.loadLog()
+	}
+
+	stage := m.selectedStage()
+	if stage == nil {
+		return nil
+	}
+
+	switch key {
+	case "o":
+		m.status = fmt.Sprintf("Opening stage %q...", stage.ID)
+		return tea.ExecProcess(m.selfCommand("stage", "open", "--stage", stage.ID), func(err error) tea.Msg {
+			return watchActionMsg{text: fmt.Sprintf("Returned from stage %q.", stage.ID), err: err}
+		})
+	case "r":
+		if lost, _ := state.AgentLost(stage); !lost && !state.StageStopped(stage) {
+			m.status = fmt.Sprintf("Stage %q is not failed or blocked.", stage.ID)
+			return nil
+		}
+		m.status = fmt.Sprintf("Retrying stage %q...", stage.ID)
+		return m.runSelf("stage", "retry", stage.ID)
+	case "p":
+		m.status = fmt.Sprintf("Pushing stage %q...", stage.ID)
+		return m.runSelf("stage", "push", stage.ID)
+	case "d":
+		if state.EffectiveStatus(stage) != state.StatusHumanReview {
+			m.status = fmt.Sprintf("Only human-review stages can be marked done; %q is %s.", stage.ID, state.EffectiveStatus(stage))
+			return nil
+		}
+		return m.markDone(stage.ID)
+	case " ":
+		return m.togglePause()
+	}
+
+	return nil
+}
+
+func (m *watchModel) selectedStage() *state.Stage {
+	if m.stack == nil || m.selected < 0 || m.selected >= len(m.stack.Stages) {
+		return nil
+	}
+	return &m.stack.Stages[m.selected]
+}
+
+// waitForState delivers the next update from the state change feed. Only
+// changes to the watched stack are kept.
+func (m *watchModel) waitForState() tea.Cmd {
+	updates, repoRoot, stackName := m.updates, m.repo.rootPath, m.stackName
+	return func() tea.Msg {
+		update, ok := <-updates
+		if !ok {
+			return nil
+		}
+		return stateMsgFromUpdate(repoRoot, stackName, update)
+	}
+}
+
+func stateMsgFromUpdate(repoRoot, stackName string, update state.StateUpdate) watchStateMsg {
+	if update.Err != nil {
+		return watchStateMsg{err: update.Err}
+	}
+	stack, _ := state.FindStack(update.Stacks, stackName)
+	if stack == nil {
+		return watchStateMsg{err: fmt.Errorf("stack no longer exists")}
+	}
+
+	msg := watchStateMsg{stack: stack, prURLs: stagePRURLsFromJournal(repoRoot, stackName)}
+	for _, change := range update.Changes {
+		if change.Stack == stackName {
+			msg.changes = append(msg.changes, change)
+		}
+	}
+	return msg
+}
+
+// stagePRURLsFromJournal returns the PR URL last recorded by a push of each
+// stage, without asking GitHub.
+func stagePRURLsFromJournal(repoRoot, stackName string) map[string]string {
+	urls := map[string]string{}
+	events, err := state.LoadEvents(repoRoot, stackName)
+	if err != nil {
+		return urls
+	}
+	for _, event := range events {
+		if event.Type == state.EventPush && event.Payload["pr_url"] != "" {
+			urls[event.Stage] = event.Payload["pr_url"]
+		}
+	}
+	return urls
+}
+
+func (m *watchModel) loadLog() tea.Cmd {
+	stage := m.selectedStage()
+	if stage == nil {
+		return nil
+	}
+	repoRoot, stackName, stageID := m.repo.rootPath, m.stackName, stage.ID
+	return func() tea.Msg {
+		msg := watchLogMsg{stageID: stageID}
+		log, err := workflow.LatestStageLog(repoRoot, stackName, stageID, "")
+		if err != nil || log == nil {
+			return msg
+		}
+		msg.path = log.Path
+		msg.tail, _ = workflow.TailFile(log.Path, watchLogLines)
+		return msg
+	}
+}
+
+func (m *watchModel) supervise() tea.Cmd {
+	ctx, supervisor, stackName := m.ctx, m.supervisor, m.stackName
+	return func() tea.Msg {
+		notices, err := supervisor.check(ctx, stackName)
+		return watchSuperviseMsg{notices: notices, err: err}
+	}
+}
+
+// selfCommand runs this m binary against the watched stack.
+func (m *watchModel) selfCommand(args ...string) *exec.Cmd {
+	bin, err := os.Executable()
+	if err != nil {
+		bin = "m"
+	}
+	cmd := exec.Command(bin, append(args, "--stack", m.stackName)...)
+	cmd.Dir = m.repo.worktreePath
+	return cmd
+}
+
+// runSelf runs an m command in the background and reports its last line of
+// output.
+func (m *watchModel) runSelf(args ...string) tea.Cmd {
+	cmd := m.selfCommand(args...)
+	return func() tea.Msg {
+		out, err := cmd.CombinedOutput()
+		text := lastOutputLine(string(out))
+		if err != nil && text != "" {
+			err = fmt.Errorf("%s", strings.TrimPrefix(text, "❌ "))
+		}
+		return watchActionMsg{text: text, err: err}
+	}
+}
+
+func lastOutputLine(out string) string {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func (m *watchModel) markDone(stageID string) tea.Cmd {
+	cmd, repo, stackName := m.cmd, m.repo, m.stackName
+	return func() tea.Msg {
+		err := updateState(cmd, repo, func(stacksFile *state.Stacks) error {
+			return state.TransitionStage(stacksFile, stackName, stageID, state.StatusDone)
+		})
+		return watchActionMsg{text: fmt.Sprintf("Stage %q marked done.", stageID), err: err}
+	}
+}
+
+func (m *watchModel) togglePause() tea.Cmd {
+	cmd, repo, stackName := m.cmd, m.repo, m.stackName
+	if m.stack != nil && m.stack.Paused {
+		return func() tea.Msg {
+			_, started, err := resumeStack(cmd, repo, stackName)
+			text := "Pipeline resumed."
+			if len(started) > 0 {
+				text += " Started " + strings.Join(started, ", ") + "."
+			}
+			return watchActionMsg{text: text, err: err}
+		}
+	}
+
+	return func() tea.Msg {
+		err := updateState(cmd, repo, func(stacksFile *state.Stacks) error {
+			return state.SetPaused(stacksFile, stackName, true)
+		})
+		return watchActionMsg{text: "Pipeline paused; running agents finish their phase.", err: err}
+	}
+}
+
+func (m *watchModel) View() string {
+	if m.stack == nil {
+		return "Loading stack...\n"
+	}
+
+	var header strings.Builder
+	header.WriteString(watchTitleStyle.Render(fmt.Sprintf("%s  %d stages", formatStackDisplayName(*m.stack), len(m.stack.Stages))))
+	if m.stack.Paused {
+		header.WriteString("  " + watchWarnStyle.Render("PAUSED"))
+	}
+	if active := formatActiveStages(m.stack); active != "" {
+		header.WriteString("\n" + active)
+	}
+
+	listWidth := min(max(m.width*2/5, 30), 60)
+	detailWidth := max(m.width-listWidth-4, 30)
+
+	lines := make([]string, 0, len(m.stack.Stages))
+	for i := range m.stack.Stages {
+		line := truncateRunes(formatWatchStageLine(&m.stack.Stages[i]), listWidth-4)
+		if i == m.selected {
+			line = watchSelectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	list := watchPaneStyle.Width(listWidth - 2).Render(strings.Join(lines, "\n"))
+	detail := watchPaneStyle.Width(detailWidth - 2).Render(m.renderDetail(detailWidth - 4))
+
+	var footer strings.Builder
+	for _, line := range m.transitions {
+		footer.WriteString(watchLabelStyle.Render(line) + "\n")
+	}
+	for _, notice := range m.notices {
+		footer.WriteString(watchWarnStyle.Render(notice) + "\n")
+	}
+	if m.status != "" {
+		style := lipgloss.NewStyle()
+		if strings.HasPrefix(m.status, "Error: ") {
+			style = watchErrorStyle
+		}
+		footer.WriteString(style.Render(m.status) + "\n")
+	}
+	pause := "pause"
+	if m.stack.Paused {
+		pause = "resume"
+	}
+	footer.WriteString(watchLabelStyle.Render(fmt.Sprintf("↑/↓ select · o open · r retry · p push · d done · space %s · q quit", pause)))
+
+	return header.String() + "\n" + lipgloss.JoinHorizontal(lipgloss.Top, list, detail) + "\n" + footer.String()
+}
+
+func (m *watchModel) renderDetail(width int) string {
+	stage := m.selectedStage()
+	if stage == nil {
+		return "No stages."
+	}
+
+	var out strings.Builder
+	field := func(label, value string) {
+		if strings.TrimSpace(value) == "" {
+			return
+		}
+		out.WriteString(watchLabelStyle.Render(fmt.Sprintf("%-9s", label)) + " " + truncateRunes(value, width-10) + "\n")
+	}
+
+	field("Stage", stage.ID)
+	field("Title", stage.Title)
+	field("Status", state.EffectiveStatus(stage))
+	field("Reason", stage.StatusReason)
+	if lost, reason := state.AgentLost(stage); lost {
+		field("Warning", reason)
+	}
+	field("Branch", stage.Branch)
+	field("Worktree", stage.Worktree)
+	field("PR", m.prURLs[stage.ID])
+	field("Model", stage.Model)
+	if agent := stage.Agent; agent != nil {
+		running := "exited"
+		if agent.ExitCode != nil {
+			running = fmt.Sprintf("exited with code %d", *agent.ExitCode)
+		} else if state.AgentRunning(agent) {
+			running = "running"
+		}
+		field("Agent", fmt.Sprintf("%s pid %d, %s", agent.Phase, agent.PID, running))
+	}
+	if n := len(stage.Reports); n > 0 {
+		report := stage.Reports[n-1]
+		field("Summary", fmt.Sprintf("[%s] %s", report.Phase, report.Summary))
+	}
+
+	if m.logPath != "" {
+		out.WriteString("\n" + watchLabelStyle.Render("Log "+m.logPath) + "\n")
+		for _, line := range strings.Split(strings.TrimRight(m.logTail, "\n"), "\n") {
+			out.WriteString(truncateRunes(line, width) + "\n")
+		}
+	}
+
+	return strings.TrimRight(out.String(), "\n")
+}
+
+func truncateRunes(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 || len(runes) <= width {
+		return s
+	}
+	if width == 1 {
+		return "…"
+	}
+	return string(runes[:width-1]) + "…"
+}
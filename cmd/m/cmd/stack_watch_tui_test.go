@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+func TestWatchModelShowsSelectedStageDetail(t *testing.T) {
+	m := &watchModel{
+		repo:      &repoContext{rootPath: t.TempDir()},
+		stackName: "checkout",
+		width:     140,
+		height:    30,
+	}
+	m.Update(watchStateMsg{
+		stack: &state.Stack{Name: "checkout", Paused: true, Stages: []state.Stage{
+			{ID: "api", Title: "API", Status: state.StatusHumanReview, Branch: "checkout/1/api",
+				Reports: []state.StageReport{{Phase: state.PhaseAIReview, Summary: "Looks good"}}},
+			{ID: "ui", Title: "UI", Status: state.StatusPending},
+		}},
+		prURLs: map[string]string{"api": "https://github.com/acme/shop/pull/7"},
+	})
+
+	view := m.View()
+	for _, want := range []string{"PAUSED", "checkout/1/api", "pull/7", "[ai_review] Looks good", "space resume"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	if stage := m.selectedStage(); stage == nil || stage.ID != "ui" {
+		t.Fatalf("selected = %+v, want ui", stage)
+	}
+
+	if cmd := m.handleKey("d"); cmd != nil {
+		t.Fatal("marking a pending stage done should not run a command")
+	}
+	if !strings.Contains(m.status, "Only human-review stages") {
+		t.Fatalf("status = %q", m.status)
+	}
+
+	m.Update(watchStateMsg{
+		stack: &state.Stack{Name: "checkout", Stages: []state.Stage{
+			{ID: "api", Status: state.StatusDone},
+			{ID: "ui", Status: state.StatusImplementing},
+		}},
+		changes: []state.Change{{Type: state.ChangeStageStatus, Stack: "checkout", Stage: "api", From: state.StatusHumanReview, To: state.StatusDone}},
+	})
+	if view := m.View(); !strings.Contains(view, "stage api moved human-review → done") {
+		t.Fatalf("view missing transition:\n%s", view)
+	}
+}
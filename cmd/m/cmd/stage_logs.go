@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
+	"github.com/spf13/cobra"
+)
+
+func newStageLogsCmd() *cobra.Command {
+	var follow bool
+	var phase string
+	var lines int
+
+	cmd := &cobra.Command{
+		Use:   "logs [stage]",
+		Short: "Show captured output of the latest agent for a stage",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logPhase, err := normalizeLogPhase(phase)
+			if err != nil {
+				return err
+			}
+			if lines < 1 {
+				return errs.New(errs.Usage, "--lines must be at least 1")
+			}
+			if follow {
+				if err := requireTextOutput(cmd, "--follow"); err != nil {
+					return err
+				}
+			}
+
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			stacksFile, err := loadState(repo)
+			if err != nil {
+				return err
+			}
+
+			stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
+			if err != nil {
+				return err
+			}
+
+			stage, err := resolveStageArg(stack, repo, args)
+			if err != nil {
+				return err
+			}
+
+			log, err := workflow.LatestStageLog(repo.rootPath, stack.Name, stage.ID, logPhase)
+			if err != nil {
+				return err
+			}
+			if log == nil {
+				return fmt.Errorf("no agent logs captured for stage %q", stage.ID)
+			}
+
+			w := cmd.OutOrStdout()
+			outInfo(w, "%s (%s run %d)", log.Path, log.Phase, log.Run)
+
+			tail, err := workflow.TailFile(log.Path, lines)
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(w, tail)
+
+			if !follow {
+				return writeResult(cmd, stageLogsResult{
+					Stack: stack.Name,
+					Stage: stage.ID,
+					Phase: log.Phase,
+					Run:   log.Run,
+					Path:  log.Path,
+					Tail:  tail,
+				})
+			}
+			return followFile(w, log.Path)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Keep printing output as the agent writes it")
+	cmd.Flags().StringVar(&phase, "phase", "", "Agent phase to show: implementing or ai_review (default: latest)")
+	cmd.Flags().IntVarP(&lines, "lines", "n", 50, "Number of trailing lines to print")
+
+	return cmd
+}
+
+type stageLogsResult struct {
+	Stack string `json:"stack"`
+	Stage string `json:"stage"`
+	Phase string `json:"phase"`
+	Run   int    `json:"run"`
+	Path  string `json:"path"`
+	Tail  string `json:"tail"`
+}
+
+func normalizeLogPhase(phase string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(phase)) {
+	case "":
+		return "", nil
+	case state.PhaseImplementing:
+		return state.PhaseImplementing, nil
+	case state.PhaseAIReview, state.StatusAIReview, "review":
+		return state.PhaseAIReview, nil
+	default:
+		return "", errs.New(errs.Usage, "invalid --phase %q; use implementing or ai_review", phase)
+	}
+}
+
+// followFile prints data appended to path until the command is interrupted.
+func followFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+
+	for {
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
+	"github.com/spf13/cobra"
+)
+
+func newStageRetryCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "retry [stage]",
+		Short: "Restart the agent for a failed or blocked stage",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			cfg, agent, err := workflow.LoadAgent()
+			if err != nil {
+				return err
+			}
+
+			var stackName, stageID, phase string
+			err = updateState(cmd, repo, func(stacksFile *state.Stacks) error {
+				stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
+				if err != nil {
+					return err
+				}
+				stackName = stack.Name
+
+				stage, err := resolveStageArg(stack, repo, args)
+				if err != nil {
+					return err
+				}
+				stageID = stage.ID
+
+				status, err := state.RetryStage(stacksFile, stack.Name, stage.ID)
+				if err != nil {
+					return err
+				}
+				phase = state.PhaseImplementing
+				if status == state.StatusAIReview {
+					phase = state.PhaseAIReview
+				}
+
+				if err := ensureAgentDefinitions(repo.rootPath, cfg); err != nil {
+					outWarn(cmd.OutOrStdout(), "Could not write agent definitions: %v", err)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			// The agent is started once the retry is saved; a stage whose
+			// agent fails to start is left blocked.
+			launch := workflow.Launch{Stack: stackName, Stage: stageID, Phase: phase}
+			started, err := workflow.LaunchStages(cmd.Context(), repo.rootPath, commandActor(cmd), []workflow.Launch{launch}, agent)
+			if err != nil {
+				return fmt.Errorf("retry stage %q: %w", stageID, err)
+			}
+			if len(started) == 0 {
+				return fmt.Errorf("retry stage %q: the stage changed while its agent was starting", stageID)
+			}
+
+			outSuccess(cmd.OutOrStdout(), "Stage %q restarted in %s; agent spawned.", stageID, phase)
+			outInfo(cmd.OutOrStdout(), "Run `m stack watch` to follow progress.")
+			return writeResult(cmd, stageActionResult{Stack: stackName, Stage: stageID, Phase: phase})
+		},
+	}
+}
+
+func newStageResetCmd() *cobra.Command {
+	var to string
+
+	cmd := &cobra.Command{
+		Use:   "reset <stage> --to pending",
+		Short: "Move a stage back to pending so the pipeline starts it again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			var stackName, stageID, from string
+			var agent *state.AgentProcess
+			agentRunning := false
+			err = updateState(cmd, repo, func(stacksFile *state.Stacks) error {
+				stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
+				if err != nil {
+					return err
+				}
+				stackName = stack.Name
+
+				stage, err := resolveStageArg(stack, repo, args)
+				if err != nil {
+					return err
+				}
+				stageID = stage.ID
+
+				from = state.EffectiveStatus(stage)
+				agent, agentRunning = stage.Agent, state.AgentRunning(stage.Agent)
+				return state.ResetStage(stacksFile, stack.Name, stage.ID, strings.TrimSpace(to))
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := workflow.RemoveAgentFiles(repo.rootPath, stackName, stageID); err != nil {
+				outWarn(cmd.OutOrStdout(), "Could not remove the stage's agent files: %v", err)
+			}
+
+			outSuccess(cmd.OutOrStdout(), "Stage %q reset from %s to %s.", args[0], from, state.StatusPending)
+			if agentRunning {
+				if state.AgentOnThisHost(agent) {
+					outInfo(cmd.OutOrStdout(), "Stopped the stage's %s agent (pid %d).", agent.Phase, agent.PID)
+				} else {
+					outWarn(cmd.OutOrStdout(), "The stage's agent (pid %d) runs on host %q and was not stopped; stop it there before the stage restarts, or its report will count for the new attempt.", agent.PID, agent.Hostname)
+				}
+			}
+			return writeResult(cmd, stageActionResult{Stack: stackName, Stage: stageID, From: from, Status: state.StatusPending})
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "Status to reset the stage to (pending)")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+func newStageSkipCmd() *cobra.Command {
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:   "skip [stage]",
+		Short: "Mark a pending, failed or blocked stage done without running it",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			var stackName, stageID, from string
+			err = updateState(cmd, repo, func(stacksFile *state.Stacks) error {
+				stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
+				if err != nil {
+					return err
+				}
+				stackName = stack.Name
+
+				stage, err := resolveStageArg(stack, repo, args)
+				if err != nil {
+					return err
+				}
+				stageID = stage.ID
+				from = state.EffectiveStatus(stage)
+
+				return state.SkipStage(stacksFile, stack.Name, stage.ID, reason)
+			})
+			if err != nil {
+				return err
+			}
+
+			outSuccess(cmd.OutOrStdout(), "Stage %q skipped; stages depending on it can start.", stageID)
+			return writeResult(cmd, stageActionResult{Stack: stackName, Stage: stageID, From: from, Status: state.StatusDone})
+		},
+	}
+
+	cmd.Flags().StringVar(&reason, "reason", "", "Why the stage is being skipped")
+
+	return cmd
+}
+
+// stageActionResult is the JSON result of the stage retry, reset, skip and
+// report commands.
+type stageActionResult struct {
+	Stack   string `json:"stack"`
+	Stage   string `json:"stage"`
+	From    string `json:"from,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Phase   string `json:"phase,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// resolveStageArg returns the stage named in args, or the current stage when
+// args is empty.
+func resolveStageArg(stack *state.Stack, repo *repoContext, args []string) (*state.Stage, error) {
+	stageID := ""
+	if len(args) > 0 {
+		stageID = strings.TrimSpace(args[0])
+	} else {
+		stageID = state.EffectiveCurrentStage(stack, repo.worktreePath)
+	}
+	if stageID == "" {
+		return nil, fmt.Errorf("no current stage; pass a stage id or run `m stage select <stage>`")
+	}
+
+	stage, _ := state.FindStage(stack, stageID)
+	if stage == nil {
+		return nil, fmt.Errorf("stage %q not found in stack %q", stageID, stack.Name)
+	}
+
+	return stage, nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/workflow"
+	"github.com/spf13/cobra"
+)
+
+func newStageReportCmd() *cobra.Command {
+	var summary string
+	var outcome string
+	var findings string
+
+	cmd := &cobra.Command{
+		Use:   "report <implementing|ai_review> [stage]",
+		Short: "Report an agent phase as done, like the report_stage_done MCP tool",
+		Long: "Report that the implementing or ai_review phase of a stage is complete and advance the pipeline.\n" +
+			"This is the command-line equivalent of the report_stage_done MCP tool, for agents and scripts without MCP.",
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			phase, err := normalizeLogPhase(args[0])
+			if err != nil || phase == "" {
+				return errs.New(errs.Usage, "invalid phase %q; use implementing or ai_review", args[0])
+			}
+
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			stacksFile, err := loadState(repo)
+			if err != nil {
+				return err
+			}
+
+			stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
+			if err != nil {
+				return err
+			}
+
+			stage, err := resolveStageArg(stack, repo, args[1:])
+			if err != nil {
+				return err
+			}
+
+			message, err := workflowService(cmd, repo, false).ReportStageDone(cmd.Context(), workflow.PhaseReport{
+				StackName: stack.Name,
+				StageID:   stage.ID,
+				Phase:     phase,
+				Summary:   summary,
+				Outcome:   outcome,
+				Findings:  findings,
+			})
+			if err != nil {
+				return err
+			}
+
+			outSuccess(cmd.OutOrStdout(), "%s", message)
+			return writeResult(cmd, stageActionResult{Stack: stack.Name, Stage: stage.ID, Phase: phase, Message: message})
+		},
+	}
+
+	cmd.Flags().StringVar(&summary, "summary", "", "Summary of the work done")
+	cmd.Flags().StringVar(&outcome, "outcome", "", "Review outcome for ai_review: approved (default) or changes_requested")
+	cmd.Flags().StringVar(&findings, "findings", "", "Review findings; required with --outcome changes_requested")
+
+	return cmd
+}
@@ -1,16 +1,12 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"github.com/manifoldco/promptui"
 	"github.com/mlawd/m-cli/internal/agent"
-	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/errs"
 	"github.com/mlawd/m-cli/internal/state"
 	"github.com/spf13/cobra"
 )
@@ -27,8 +23,14 @@ func newStageRootCmd() *cobra.Command {
 		newStageListCmd(),
 		newStageSelectCmd(),
 		newStageCurrentCmd(),
+		newStageShowCmd(),
+		newStageLogsCmd(),
+		newStageReportCmd(),
 		newStageOpenCmd(),
 		newStagePushCmd(),
+		newStageRetryCmd(),
+		newStageResetCmd(),
+		newStageSkipCmd(),
 	)
 
 	return cmd
@@ -36,14 +38,10 @@ func newStageRootCmd() *cobra.Command {
 
 func newStagePushCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "push",
-		Short: "Push current stage branch and create PR if missing",
-		Args:  cobra.NoArgs,
+		Use:   "push [stage]",
+		Short: "Push current (or given) stage branch and create PR if missing",
+		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if _, err := exec.LookPath("gh"); err != nil {
-				return fmt.Errorf("gh CLI is required for stage push")
-			}
-
 			repo, err := discoverRepoContext()
 			if err != nil {
 				return err
@@ -60,38 +58,23 @@ func newStagePushCmd() *cobra.Command {
 			}
 
 			currentStageID := state.EffectiveCurrentStage(stack, repo.worktreePath)
+			if len(args) > 0 {
+				currentStageID = strings.TrimSpace(args[0])
+			}
 			if currentStageID == "" {
 				return fmt.Errorf("no stage selected; run: m stage select <stage-id>")
 			}
 
 			stage, stageIndex := state.FindStage(stack, currentStageID)
 			if stage == nil {
-				return fmt.Errorf("current stage %q not found in stack %q", currentStageID, stack.Name)
+				return fmt.Errorf("stage %q not found in stack %q", currentStageID, stack.Name)
 			}
 
-			stageIndexes, err := stageIndexesToPush(stack, stageIndex, func(branch string) bool {
-				return gitx.RemoteBranchExists(repo.rootPath, "origin", branch)
-			})
+			result, err := workflowService(cmd, repo, false).PushStage(stack, stageIndex)
 			if err != nil {
 				return err
 			}
-
-			for _, idx := range stageIndexes {
-				if err := pushStageAndEnsurePR(cmd, repo.rootPath, stack, idx); err != nil {
-					return err
-				}
-			}
-
-			if err := pushStageAndEnsurePR(cmd, repo.rootPath, stack, stageIndex); err != nil {
-				return err
-			}
-
-			updatedIndexes := append(stageIndexes, stageIndex)
-			if err := syncStackPRDescriptions(cmd, repo.rootPath, stack, updatedIndexes, ""); err != nil {
-				return err
-			}
-
-			return nil
+			return writeResult(cmd, result)
 		},
 	}
 }
@@ -107,7 +90,7 @@ func newStageOpenCmd() *cobra.Command {
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if next && strings.TrimSpace(stageID) != "" {
-				return fmt.Errorf("--next and --stage cannot be used together")
+				return errs.New(errs.Usage, "--next and --stage cannot be used together")
 			}
 
 			repo, err := discoverRepoContext()
@@ -131,7 +114,7 @@ func newStageOpenCmd() *cobra.Command {
 					return err
 				}
 
-				return startStageAtIndex(cmd, repo, stacksFile, stack, nextIndex, true, !noOpen)
+				return startStageAtIndex(cmd, repo, stack, nextIndex, true, !noOpen)
 			}
 
 			if trimmedStageID := strings.TrimSpace(stageID); trimmedStageID != "" {
@@ -145,9 +128,12 @@ func newStageOpenCmd() *cobra.Command {
 					return fmt.Errorf("stage %q not found in stack %q", trimmedStageID, stack.Name)
 				}
 
-				return startStageAtIndex(cmd, repo, stacksFile, stack, stageIndex, false, !noOpen)
+				return startStageAtIndex(cmd, repo, stack, stageIndex, false, !noOpen)
 			}
 
+			if err := requireTextOutput(cmd, "interactive stage selection (pass --stage or --next)"); err != nil {
+				return err
+			}
 			if len(stacksFile.Stacks) == 0 {
 				return fmt.Errorf("no stacks found; run: m stack new <stack-name>")
 			}
@@ -169,7 +155,7 @@ func newStageOpenCmd() *cobra.Command {
 					return err
 				}
 
-				return startStageAtIndex(cmd, repo, stacksFile, stack, stageChoice, false, !noOpen)
+				return startStageAtIndex(cmd, repo, stack, stageChoice, false, !noOpen)
 			}
 
 			stackIndexes := []int{}
@@ -202,7 +188,7 @@ func newStageOpenCmd() *cobra.Command {
 				return err
 			}
 
-			return startStageAtIndex(cmd, repo, stacksFile, stack, stageChoice, false, !noOpen)
+			return startStageAtIndex(cmd, repo, stack, stageChoice, false, !noOpen)
 		},
 	}
 
@@ -235,10 +221,11 @@ func newStageListCmd() *cobra.Command {
 			}
 
 			effectiveCurrentStage := state.EffectiveCurrentStage(stack, repo.worktreePath)
+			result := stageListResult{Stack: stack.Name, CurrentStage: effectiveCurrentStage, Stages: newStackResult(*stack).Stages}
 
 			if len(stack.Stages) == 0 {
 				outInfo(cmd.OutOrStdout(), "No stages found in current stack plan")
-				return nil
+				return writeResult(cmd, result)
 			}
 
 			for idx, stage := range stack.Stages {
@@ -249,7 +236,7 @@ func newStageListCmd() *cobra.Command {
 				fmt.Fprintf(cmd.OutOrStdout(), "  %d. %s - %s\n", idx+1, stage.ID, stage.Title)
 			}
 
-			return nil
+			return writeResult(cmd, result)
 		},
 	}
 }
@@ -267,27 +254,27 @@ func newStageSelectCmd() *cobra.Command {
 				return err
 			}
 
-			stacksFile, err := loadState(repo)
-			if err != nil {
-				return err
-			}
-
-			stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
-			if err != nil {
-				return err
-			}
+			var stackName string
+			err = updateState(cmd, repo, func(stacksFile *state.Stacks) error {
+				stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
+				if err != nil {
+					return err
+				}
+				stackName = stack.Name
 
-			if stage, _ := state.FindStage(stack, selectedStage); stage == nil {
-				return fmt.Errorf("stage %q not found in stack %q", selectedStage, stack.Name)
-			}
+				if stage, _ := state.FindStage(stack, selectedStage); stage == nil {
+					return fmt.Errorf("stage %q not found in stack %q", selectedStage, stack.Name)
+				}
 
-			stack.CurrentStage = selectedStage
-			if err := state.SaveStacks(repo.rootPath, stacksFile); err != nil {
+				stack.CurrentStage = selectedStage
+				return nil
+			})
+			if err != nil {
 				return err
 			}
 
 			outCurrent(cmd.OutOrStdout(), "Current stage: %s", selectedStage)
-			return nil
+			return writeResult(cmd, currentStageResult{Stack: stackName, Stage: selectedStage})
 		},
 	}
 }
@@ -308,14 +295,14 @@ func newStageCurrentCmd() *cobra.Command {
 				return err
 			}
 
-			_, workspaceStage := state.CurrentWorkspaceStackStage(stacksFile, repo.worktreePath)
+			workspaceStack, workspaceStage := state.CurrentWorkspaceStackStage(stacksFile, repo.worktreePath)
 			if workspaceStage != "" {
 				outCurrent(cmd.OutOrStdout(), "Current stage: %s", workspaceStage)
-				return nil
+				return writeResult(cmd, currentStageResult{Stack: workspaceStack, Stage: workspaceStage})
 			}
 
 			if state.IsLinkedWorktree(repo.worktreePath, repo.rootPath) {
-				return nil
+				return writeResult(cmd, currentStageResult{})
 			}
 
 			stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
@@ -325,16 +312,27 @@ func newStageCurrentCmd() *cobra.Command {
 
 			currentStageID := state.EffectiveCurrentStage(stack, repo.worktreePath)
 			if currentStageID == "" {
-				return nil
+				return writeResult(cmd, currentStageResult{Stack: stack.Name})
 			}
 
 			outCurrent(cmd.OutOrStdout(), "Current stage: %s", currentStageID)
-			return nil
+			return writeResult(cmd, currentStageResult{Stack: stack.Name, Stage: currentStageID})
 		},
 	}
 }
 
-func startStageAtIndex(cmd *cobra.Command, repo *repoContext, stacksFile *state.Stacks, stack *state.Stack, stageIndex int, withPrompt bool, openAgent bool) error {
+type stageListResult struct {
+	Stack        string        `json:"stack"`
+	CurrentStage string        `json:"current_stage,omitempty"`
+	Stages       []stageResult `json:"stages"`
+}
+
+type currentStageResult struct {
+	Stack string `json:"stack,omitempty"`
+	Stage string `json:"stage"`
+}
+
+func startStageAtIndex(cmd *cobra.Command, repo *repoContext, stack *state.Stack, stageIndex int, withPrompt bool, openAgent bool) error {
 	if stack == nil {
 		return fmt.Errorf("stack is required")
 	}
@@ -342,65 +340,26 @@ func startStageAtIndex(cmd *cobra.Command, repo *repoContext, stacksFile *state.
 		return fmt.Errorf("stage index %d out of range", stageIndex)
 	}
 
-	target := &stack.Stages[stageIndex]
-	branch := strings.TrimSpace(target.Branch)
-	if branch == "" {
-		branch = stageBranchName(stack.Name, stageIndex, target.ID)
-	}
-
-	parentBranch, err := parentBranchForStage(repo.rootPath, stack, stageIndex)
+	opened, err := workflowService(cmd, repo, false).OpenStage(stack.Name, stack.Stages[stageIndex].ID)
 	if err != nil {
 		return err
 	}
 
-	if !gitx.BranchExists(repo.rootPath, branch) {
-		if err := gitx.CreateBranch(repo.rootPath, branch, parentBranch); err != nil {
-			return err
-		}
-		outSuccess(cmd.OutOrStdout(), "Created branch %s from %s", branch, parentBranch)
-	} else {
-		outReuse(cmd.OutOrStdout(), "Reusing branch %s", branch)
-	}
-
-	worktree := strings.TrimSpace(target.Worktree)
-	if worktree == "" {
-		worktree = filepath.Join(state.StacksDir(repo.rootPath), filepath.FromSlash(stack.Name), filepath.FromSlash(target.ID))
-	}
-
-	if _, err := os.Stat(worktree); os.IsNotExist(err) {
-		if err := os.MkdirAll(filepath.Dir(worktree), 0o755); err != nil {
-			return err
-		}
-		if err := gitx.AddWorktree(repo.rootPath, worktree, branch); err != nil {
-			return err
-		}
-		outSuccess(cmd.OutOrStdout(), "Created worktree: %s", worktree)
-	} else if err != nil {
+	outCurrent(cmd.OutOrStdout(), "Current stack: %s", opened.Stack)
+	outCurrent(cmd.OutOrStdout(), "Current stage: %s", opened.Stage)
+	if err := writeResult(cmd, opened); err != nil {
 		return err
-	} else {
-		outReuse(cmd.OutOrStdout(), "Reusing worktree: %s", worktree)
 	}
-
-	target.Branch = branch
-	target.Worktree = worktree
-	target.Parent = parentBranch
-	stack.CurrentStage = target.ID
-
-	if err := state.SaveStacks(repo.rootPath, stacksFile); err != nil {
-		return err
-	}
-
-	outCurrent(cmd.OutOrStdout(), "Current stack: %s", stack.Name)
-	outCurrent(cmd.OutOrStdout(), "Current stage: %s", target.ID)
 	if !openAgent {
 		return nil
 	}
 
 	if withPrompt {
-		return agent.StartOpenCodeWithArgs(worktree, "--prompt", stageStartPrompt(target))
+		target := stack.Stages[stageIndex]
+		return agent.StartOpenCodeWithArgs(opened.Worktree, "--prompt", stageStartPrompt(&target))
 	}
 
-	return agent.StartOpenCode(worktree)
+	return agent.StartOpenCode(opened.Worktree)
 }
 
 func promptSelectIndex(label string, options []string) (int, error) {
@@ -448,32 +407,6 @@ func nextStageIndex(stack *state.Stack) (int, error) {
 	return nextIndex, nil
 }
 
-func parentBranchForStage(repoRoot string, stack *state.Stack, stageIndex int) (string, error) {
-	if stageIndex == 0 {
-		repo, err := gitx.DiscoverRepo(repoRoot)
-		if err != nil {
-			return "", err
-		}
-		return repo.DefaultBranch, nil
-	}
-
-	previous := stack.Stages[stageIndex-1]
-	branch := strings.TrimSpace(previous.Branch)
-	if branch == "" {
-		branch = stageBranchName(stack.Name, stageIndex-1, previous.ID)
-	}
-
-	if !gitx.BranchExists(repoRoot, branch) {
-		return "", fmt.Errorf("previous stage branch %q does not exist; start stage %q first", branch, previous.ID)
-	}
-
-	return branch, nil
-}
-
-func stageBranchName(stackName string, stageIndex int, stageID string) string {
-	return fmt.Sprintf("%s/%d/%s", strings.Trim(stackName, "/"), stageIndex+1, stageID)
-}
-
 func stageStartPrompt(stage *state.Stage) string {
 	prompt := fmt.Sprintf("Implement stage %s", stage.ID)
 	if title := strings.TrimSpace(stage.Title); title != "" {
@@ -485,301 +418,3 @@ func stageStartPrompt(stage *state.Stage) string {
 
 	return prompt
 }
-
-func stageBranchFor(stack *state.Stack, stageIndex int) string {
-	stage := stack.Stages[stageIndex]
-	branch := strings.TrimSpace(stage.Branch)
-	if branch == "" {
-		branch = stageBranchName(stack.Name, stageIndex, stage.ID)
-	}
-
-	return branch
-}
-
-func stageIndexesToPush(stack *state.Stack, currentStageIndex int, remoteBranchExists func(branch string) bool) ([]int, error) {
-	if stack == nil {
-		return nil, fmt.Errorf("stack is required")
-	}
-	if currentStageIndex < 0 || currentStageIndex >= len(stack.Stages) {
-		return nil, fmt.Errorf("current stage index %d out of range", currentStageIndex)
-	}
-
-	indexes := make([]int, 0, currentStageIndex)
-	for idx := 0; idx < currentStageIndex; idx++ {
-		branch := stageBranchFor(stack, idx)
-		if remoteBranchExists(branch) {
-			continue
-		}
-		indexes = append(indexes, idx)
-	}
-
-	return indexes, nil
-}
-
-func pushStageAndEnsurePR(cmd *cobra.Command, repoRoot string, stack *state.Stack, stageIndex int) error {
-	return pushStageAndEnsurePROpts(cmd, repoRoot, stack, stageIndex, false, "")
-}
-
-func pushStageAndEnsurePROpts(cmd *cobra.Command, repoRoot string, stack *state.Stack, stageIndex int, forceWithLease bool, linePrefix string) error {
-	stage := &stack.Stages[stageIndex]
-	branch := stageBranchFor(stack, stageIndex)
-	if !gitx.BranchExists(repoRoot, branch) {
-		return fmt.Errorf("stage branch %q does not exist; run: m stage open --next", branch)
-	}
-
-	pushArgs := []string{"push", "-u", "origin", branch}
-	if forceWithLease {
-		pushArgs = append(pushArgs, "--force-with-lease")
-	}
-
-	if _, err := gitx.Run(repoRoot, pushArgs...); err != nil {
-		return err
-	}
-	if forceWithLease {
-		outStyledWithPrefix(cmd.OutOrStdout(), ansiBlue, "🚀", linePrefix, "Force-pushed branch %s (--force-with-lease)", branch)
-	} else {
-		outStyledWithPrefix(cmd.OutOrStdout(), ansiBlue, "🚀", linePrefix, "Pushed branch %s", branch)
-	}
-
-	prURL, err := findOpenPRURL(repoRoot, branch)
-	if err != nil {
-		return err
-	}
-
-	baseBranch, err := parentBranchForStage(repoRoot, stack, stageIndex)
-	if err != nil {
-		return err
-	}
-
-	if stageIndex > 0 && !gitx.RemoteBranchExists(repoRoot, "origin", baseBranch) {
-		if _, err := gitx.Run(repoRoot, "push", "-u", "origin", baseBranch); err != nil {
-			return err
-		}
-		outStyledWithPrefix(cmd.OutOrStdout(), ansiYellow, "⚠️", linePrefix, "Base branch was missing remotely; pushed %s", baseBranch)
-	}
-
-	stackPRURLs, err := collectStackOpenPRURLs(repoRoot, stack)
-	if err != nil {
-		return err
-	}
-
-	title := fmt.Sprintf("%s: %s", stack.Name, stage.Title)
-	if strings.TrimSpace(stage.Title) == "" {
-		title = fmt.Sprintf("%s: %s", stack.Name, stage.ID)
-	}
-	body := stagePRBody(stack, stageIndex, stackPRURLs)
-
-	if strings.TrimSpace(prURL) != "" {
-		if _, err := runGH(repoRoot, "pr", "edit", prURL, "--body", body); err != nil {
-			return err
-		}
-		outStyledWithPrefix(cmd.OutOrStdout(), ansiCyan, "🔗", linePrefix, "Found existing PR for %s: %s", stage.ID, prURL)
-		outStyledWithPrefix(cmd.OutOrStdout(), ansiGreen, "✅", linePrefix, "Updated PR description for %s: %s", stage.ID, prURL)
-		return nil
-	}
-
-	if _, err := runGH(repoRoot, "pr", "create", "--head", branch, "--base", baseBranch, "--title", title, "--body", body); err != nil {
-		return err
-	}
-
-	prURL, err = findOpenPRURL(repoRoot, branch)
-	if err != nil {
-		return err
-	}
-	if strings.TrimSpace(prURL) == "" {
-		return fmt.Errorf("failed to determine PR URL after creation")
-	}
-
-	outStyledWithPrefix(cmd.OutOrStdout(), ansiGreen, "✅", linePrefix, "Created PR for %s: %s", stage.ID, prURL)
-	return nil
-}
-
-func collectStackOpenPRURLs(repoRoot string, stack *state.Stack) (map[int]string, error) {
-	urls := make(map[int]string, len(stack.Stages))
-	for idx := range stack.Stages {
-		branch := stageBranchFor(stack, idx)
-		prURL, err := findOpenPRURL(repoRoot, branch)
-		if err != nil {
-			return nil, err
-		}
-		urls[idx] = strings.TrimSpace(prURL)
-	}
-
-	return urls, nil
-}
-
-func stagePRBody(stack *state.Stack, stageIndex int, stackPRURLs map[int]string) string {
-	stage := stack.Stages[stageIndex]
-	hasDetails := strings.TrimSpace(stage.Outcome) != "" || len(stage.Implementation) > 0 || len(stage.Validation) > 0 || len(stage.Risks) > 0 || strings.TrimSpace(stage.Context) != ""
-
-	var body strings.Builder
-	body.WriteString(fmt.Sprintf("Stage: %s", stage.ID))
-	if outcome := strings.TrimSpace(stage.Outcome); outcome != "" {
-		body.WriteString("\n\n## Outcome\n")
-		body.WriteString(outcome)
-	}
-
-	if len(stage.Implementation) > 0 {
-		body.WriteString("\n\n## Implementation\n")
-		body.WriteString(formatBulletList(stage.Implementation))
-	}
-
-	if len(stage.Validation) > 0 {
-		body.WriteString("\n\n## Validation\n")
-		body.WriteString(formatBulletList(stage.Validation))
-	}
-
-	if len(stage.Risks) > 0 {
-		body.WriteString("\n\n## Risks\n")
-		for i, risk := range stage.Risks {
-			body.WriteString(fmt.Sprintf("- Risk: %s\n  Mitigation: %s", strings.TrimSpace(risk.Risk), strings.TrimSpace(risk.Mitigation)))
-			if i < len(stage.Risks)-1 {
-				body.WriteString("\n")
-			}
-		}
-	}
-
-	if context := strings.TrimSpace(stage.Context); context != "" {
-		body.WriteString("\n\n## Context\n")
-		body.WriteString(context)
-	}
-
-	if !hasDetails {
-		body.WriteString("\n\n")
-		body.WriteString("No implementation details found for this stage.")
-	}
-
-	body.WriteString("\n\n## Stack PRs\n\n### Earlier stages (base chain)\n")
-	upstream := stackPRListLines(stack, stageIndex, stackPRURLs, true)
-	if len(upstream) == 0 {
-		body.WriteString("- None\n")
-	} else {
-		for _, line := range upstream {
-			body.WriteString(line)
-			body.WriteString("\n")
-		}
-	}
-
-	body.WriteString("\n### Later stages (dependent chain)\n")
-	downstream := stackPRListLines(stack, stageIndex, stackPRURLs, false)
-	if len(downstream) == 0 {
-		body.WriteString("- None")
-	} else {
-		for i, line := range downstream {
-			body.WriteString(line)
-			if i < len(downstream)-1 {
-				body.WriteString("\n")
-			}
-		}
-	}
-
-	return body.String()
-}
-
-func formatBulletList(items []string) string {
-	var lines []string
-	for _, item := range items {
-		trimmed := strings.TrimSpace(item)
-		if trimmed == "" {
-			continue
-		}
-		lines = append(lines, fmt.Sprintf("- %s", trimmed))
-	}
-
-	return strings.Join(lines, "\n")
-}
-
-func stackPRListLines(stack *state.Stack, stageIndex int, stackPRURLs map[int]string, upstream bool) []string {
-	lines := []string{}
-	if upstream {
-		for idx := 0; idx < stageIndex; idx++ {
-			stage := stack.Stages[idx]
-			if prURL := strings.TrimSpace(stackPRURLs[idx]); prURL != "" {
-				lines = append(lines, fmt.Sprintf("- %s: %s", stage.ID, prURL))
-				continue
-			}
-			lines = append(lines, fmt.Sprintf("- %s: (not created)", stage.ID))
-		}
-		return lines
-	}
-
-	for idx := stageIndex + 1; idx < len(stack.Stages); idx++ {
-		stage := stack.Stages[idx]
-		if prURL := strings.TrimSpace(stackPRURLs[idx]); prURL != "" {
-			lines = append(lines, fmt.Sprintf("- %s: %s", stage.ID, prURL))
-			continue
-		}
-		lines = append(lines, fmt.Sprintf("- %s: (not created)", stage.ID))
-	}
-
-	return lines
-}
-
-func syncStackPRDescriptions(cmd *cobra.Command, repoRoot string, stack *state.Stack, stageIndexes []int, linePrefix string) error {
-	if len(stageIndexes) == 0 {
-		return nil
-	}
-
-	stackPRURLs, err := collectStackOpenPRURLs(repoRoot, stack)
-	if err != nil {
-		return err
-	}
-
-	updated := map[int]struct{}{}
-	for _, stageIndex := range stageIndexes {
-		if stageIndex < 0 || stageIndex >= len(stack.Stages) {
-			continue
-		}
-		if _, seen := updated[stageIndex]; seen {
-			continue
-		}
-
-		prURL := strings.TrimSpace(stackPRURLs[stageIndex])
-		if prURL == "" {
-			continue
-		}
-
-		body := stagePRBody(stack, stageIndex, stackPRURLs)
-		if _, err := runGH(repoRoot, "pr", "edit", prURL, "--body", body); err != nil {
-			return err
-		}
-		outStyledWithPrefix(cmd.OutOrStdout(), ansiGreen, "✅", linePrefix, "Synced PR description for %s: %s", stack.Stages[stageIndex].ID, prURL)
-		updated[stageIndex] = struct{}{}
-	}
-
-	return nil
-}
-
-func findOpenPRURL(repoRoot, headBranch string) (string, error) {
-	out, err := runGH(repoRoot, "pr", "list", "--state", "open", "--head", headBranch, "--json", "url", "--limit", "1")
-	if err != nil {
-		return "", err
-	}
-
-	var prs []struct {
-		URL string `json:"url"`
-	}
-	if err := json.Unmarshal([]byte(out), &prs); err != nil {
-		return "", fmt.Errorf("parse gh pr list output: %w", err)
-	}
-	if len(prs) == 0 {
-		return "", nil
-	}
-
-	return strings.TrimSpace(prs[0].URL), nil
-}
-
-func runGH(dir string, args ...string) (string, error) {
-	cmd := exec.Command("gh", args...)
-	cmd.Dir = dir
-	out, err := cmd.CombinedOutput()
-	trimmed := strings.TrimSpace(string(out))
-	if err != nil {
-		if trimmed == "" {
-			trimmed = err.Error()
-		}
-		return "", fmt.Errorf("gh %s: %s", strings.Join(args, " "), trimmed)
-	}
-
-	return trimmed, nil
-}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
+	"github.com/spf13/cobra"
+)
+
+func newStageShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show [stage]",
+		Short: "Show details and agent reports for a stage",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			stacksFile, err := loadState(repo)
+			if err != nil {
+				return err
+			}
+
+			stack, err := requireCurrentStackWithPlan(stacksFile, repo, stackNameFromFlag(cmd))
+			if err != nil {
+				return err
+			}
+
+			stage, err := resolveStageArg(stack, repo, args)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput(cmd) {
+				result := stageShowResult{
+					Stack:        stack.Name,
+					Stage:        stage,
+					Status:       state.EffectiveStatus(stage),
+					AgentRunning: stage.Agent != nil && state.AgentRunning(stage.Agent),
+				}
+				if lost, reason := state.AgentLost(stage); lost {
+					result.Warning = reason
+				}
+				return writeResult(cmd, result)
+			}
+
+			fmt.Fprint(cmd.OutOrStdout(), formatStageShow(stage))
+			return nil
+		},
+	}
+}
+
+// stageShowResult is the stage as m stores it, plus what show derives.
+type stageShowResult struct {
+	Stack        string       `json:"stack"`
+	Stage        *state.Stage `json:"stage"`
+	Status       string       `json:"status"`
+	AgentRunning bool         `json:"agent_running"`
+	Warning      string       `json:"warning,omitempty"`
+}
+
+func formatStageShow(stage *state.Stage) string {
+	var out strings.Builder
+
+	field := func(label, value string) {
+		if strings.TrimSpace(value) == "" {
+			return
+		}
+		fmt.Fprintf(&out, "%-12s %s\n", label+":", value)
+	}
+
+	field("Stage", stage.ID)
+	field("Title", stage.Title)
+	field("Status", state.EffectiveStatus(stage))
+	field("Branch", stage.Branch)
+	field("Worktree", stage.Worktree)
+	field("Parent", stage.Parent)
+	field("Model", stage.Model)
+	field("Started", stage.StartedAt)
+	field("Reviewed", stage.ReviewedAt)
+	if stage.ReviewRounds > 0 {
+		field("Rounds", fmt.Sprintf("%d review round(s) requested changes", stage.ReviewRounds))
+	}
+	field("Reason", stage.StatusReason)
+	if agent := stage.Agent; agent != nil {
+		running := "exited"
+		if agent.ExitCode != nil {
+			running = fmt.Sprintf("exited with code %d at %s", *agent.ExitCode, agent.ExitedAt)
+		} else if state.AgentRunning(agent) {
+			running = "running"
+		}
+		field("Agent", fmt.Sprintf("%s pid %d, started %s, %s", agent.Phase, agent.PID, agent.StartedAt, running))
+		field("Command", state.FormatAgentCommand(agent))
+		field("Log", agent.Log)
+	}
+	if lost, reason := state.AgentLost(stage); lost {
+		field("Warning", reason)
+	}
+
+	for _, phase := range []struct {
+		key   string
+		label string
+	}{
+		{state.PhaseImplementing, "Implementation summary"},
+		{state.PhaseAIReview, "Review summary"},
+	} {
+		report := state.LatestReport(stage, phase.key)
+		if report == nil {
+			continue
+		}
+
+		heading := fmt.Sprintf("%s (%s", phase.label, report.ReportedAt)
+		if report.Commit != "" {
+			heading += ", " + workflow.ShortCommit(report.Commit)
+		}
+		fmt.Fprintf(&out, "\n%s):\n", heading)
+
+		summary := report.Summary
+		if summary == "" {
+			summary = "(no summary provided)"
+		}
+		for _, line := range strings.Split(summary, "\n") {
+			fmt.Fprintf(&out, "  %s\n", line)
+		}
+
+		if findings := state.PendingReviewFindings(stage); phase.key == state.PhaseAIReview && findings != "" {
+			fmt.Fprintln(&out, "  Changes requested:")
+			for _, line := range strings.Split(findings, "\n") {
+				fmt.Fprintf(&out, "    %s\n", line)
+			}
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/stacks"
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newStateRootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspect and maintain repo-local m state",
+	}
+
+	cmd.AddCommand(
+		newStateMigrateCmd(),
+		newStateImportLegacyCmd(),
+	)
+
+	return cmd
+}
+
+func newStateMigrateCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade .m/stacks/index.json to the current schema version",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			var result *state.MigrationResult
+			if dryRun {
+				result, err = state.PlanMigration(repo.rootPath)
+			} else {
+				result, err = state.Migrate(repo.rootPath)
+			}
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput(cmd) {
+				if result.Steps == nil {
+					result.Steps = []state.MigrationStep{}
+				}
+				return writeResult(cmd, stateMigrateResult{MigrationResult: result, DryRun: dryRun, Pending: result.Pending()})
+			}
+
+			out := cmd.OutOrStdout()
+			if result.FromVersion > state.SchemaVersion {
+				outWarn(out, "Stack index is version %d, newer than this m supports (%d); upgrade m", result.FromVersion, state.SchemaVersion)
+				return nil
+			}
+			if !result.Pending() {
+				outInfo(out, "Stack index is already at version %d", result.FromVersion)
+				return nil
+			}
+
+			if dryRun {
+				outInfo(out, "Would migrate stack index from version %d to %d:", result.FromVersion, result.ToVersion)
+			} else {
+				outSuccess(out, "Migrated stack index from version %d to %d:", result.FromVersion, result.ToVersion)
+			}
+			printMigrationSteps(out, result.Steps)
+
+			if dryRun {
+				outInfo(out, "Dry run: no files changed. A backup would be written to %s", state.BackupPath(repo.rootPath, result.FromVersion))
+			} else {
+				outInfo(out, "Backup of the previous index: %s", result.Backup)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would change without writing")
+
+	return cmd
+}
+
+type stateMigrateResult struct {
+	*state.MigrationResult
+	DryRun  bool `json:"dry_run"`
+	Pending bool `json:"pending"`
+}
+
+func printMigrationSteps(w io.Writer, steps []state.MigrationStep) {
+	for _, step := range steps {
+		fmt.Fprintf(w, "  v%d -> v%d: %s\n", step.From, step.To, step.Description)
+		if len(step.Changes) == 0 {
+			fmt.Fprintln(w, "    (no data changes)")
+			continue
+		}
+		for _, change := range step.Changes {
+			fmt.Fprintf(w, "    - %s\n", change)
+		}
+	}
+}
+
+func newStateImportLegacyCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "import-legacy [stack-name...]",
+		Short: "Import stacks from the legacy <git-common-dir>/m/stacks.json state",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := discoverRepoContext()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			legacyPath := stacks.StatePath(repo.common)
+			if _, err := os.Stat(legacyPath); os.IsNotExist(err) {
+				outInfo(out, "No legacy state found at %s", legacyPath)
+				return writeResult(cmd, legacyImportResult{Source: legacyPath, DryRun: dryRun, Results: []state.LegacyImport{}})
+			}
+
+			legacy, err := stacks.Load(legacyPath)
+			if err != nil {
+				return fmt.Errorf("load legacy state: %w", err)
+			}
+
+			opts := state.LegacyImportOptions{
+				RepoRoot: repo.rootPath,
+				Source:   legacyPath,
+				BranchExists: func(branch string) bool {
+					return gitx.BranchExists(repo.rootPath, branch)
+				},
+				Only: args,
+			}
+
+			var results []state.LegacyImport
+			if dryRun {
+				stacksFile, err := loadState(repo)
+				if err != nil {
+					return err
+				}
+				results = state.ImportLegacy(stacksFile, legacy, opts)
+			} else {
+				err = updateState(cmd, repo, func(stacksFile *state.Stacks) error {
+					results = state.ImportLegacy(stacksFile, legacy, opts)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			if jsonOutput(cmd) {
+				if results == nil {
+					results = []state.LegacyImport{}
+				}
+				return writeResult(cmd, legacyImportResult{Source: legacyPath, DryRun: dryRun, Results: results})
+			}
+			if len(results) == 0 {
+				outInfo(out, "No matching legacy stacks in %s", legacyPath)
+				return nil
+			}
+
+			imported := printLegacyImportResults(out, results, dryRun)
+			if dryRun {
+				outInfo(out, "Dry run: %d stack%s would be imported; no files changed", imported, pluralSuffix(imported))
+			} else {
+				outSuccess(out, "Imported %d stack%s from %s", imported, pluralSuffix(imported), legacyPath)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be imported without writing")
+
+	return cmd
+}
+
+type legacyImportResult struct {
+	Source  string               `json:"source"`
+	DryRun  bool                 `json:"dry_run"`
+	Results []state.LegacyImport `json:"results"`
+}
+
+func printLegacyImportResults(w io.Writer, results []state.LegacyImport, dryRun bool) int {
+	imported := 0
+	for _, result := range results {
+		switch {
+		case result.Imported && dryRun:
+			outInfo(w, "Would import stack %q (%d stage%s)", result.Stack, result.Stages, pluralSuffix(result.Stages))
+		case result.Imported:
+			outSuccess(w, "Imported stack %q (%d stage%s)", result.Stack, result.Stages, pluralSuffix(result.Stages))
+		default:
+			outWarn(w, "Skipped stack %q", result.Stack)
+		}
+		if result.Imported {
+			imported++
+		}
+
+		for _, conflict := range result.Conflicts {
+			fmt.Fprintf(w, "    conflict: %s\n", conflict)
+		}
+		for _, warning := range result.Warnings {
+			fmt.Fprintf(w, "    note: %s\n", warning)
+		}
+	}
+
+	return imported
+}
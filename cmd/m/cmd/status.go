@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"os"
 	"strings"
 
 	"github.com/mlawd/m-cli/internal/gitx"
@@ -25,10 +24,8 @@ func newStatusCmd() *cobra.Command {
 				return err
 			}
 
-			initialized := false
-			if _, err := os.Stat(state.Dir(repo.rootPath)); err == nil {
-				initialized = true
-			} else if !os.IsNotExist(err) {
+			initialized, err := state.Initialized(repo.rootPath)
+			if err != nil {
 				return err
 			}
 
@@ -92,20 +89,54 @@ func newStatusCmd() *cobra.Command {
 				outInfo(cmd.OutOrStdout(), "Linked worktree is not mapped to a stack stage")
 			}
 
+			result := statusResult{
+				RepoRoot:     repo.rootPath,
+				Worktree:     repo.worktreePath,
+				Branch:       strings.TrimSpace(branch),
+				Initialized:  initialized,
+				WorktreesDir: managedRoot,
+				StacksDir:    stackRoot,
+				Stacks:       len(stacksFile.Stacks),
+				CurrentStack: currentStack,
+				CurrentStage: currentStage,
+			}
+
 			// Stack run summary
 			if currentStack != "" {
 				if stack, _ := state.FindStack(stacksFile, currentStack); stack != nil {
-					if summary := formatStackRunSummary(stack); summary != "" {
-						outInfo(cmd.OutOrStdout(), "%s", summary)
+					result.Stages = len(stack.Stages)
+					result.RunSummary = formatStackRunSummary(stack)
+					if result.RunSummary != "" {
+						outInfo(cmd.OutOrStdout(), "%s", result.RunSummary)
+					}
+					if conflict := stack.Sync.Conflict(); conflict != nil {
+						result.SyncConflict = conflict.Stage
+						outWarn(cmd.OutOrStdout(), "Sync stopped at a conflict in stage %s (%s); resolve it, then run m stack sync --continue or --abort", conflict.Stage, conflict.Worktree)
 					}
 				}
 			}
 
-			return nil
+			return writeResult(cmd, result)
 		},
 	}
 }
 
+type statusResult struct {
+	RepoRoot     string `json:"repo_root"`
+	Worktree     string `json:"worktree"`
+	Branch       string `json:"branch"`
+	Initialized  bool   `json:"initialized"`
+	WorktreesDir string `json:"worktrees_dir"`
+	StacksDir    string `json:"stacks_dir"`
+	Stacks       int    `json:"stacks"`
+	CurrentStack string `json:"current_stack,omitempty"`
+	CurrentStage string `json:"current_stage,omitempty"`
+	Stages       int    `json:"stages,omitempty"`
+	RunSummary   string `json:"run_summary,omitempty"`
+	// SyncConflict is the stage an interactive stack sync is stopped at.
+	SyncConflict string `json:"sync_conflict,omitempty"`
+}
+
 func boolWord(v bool) string {
 	if v {
 		return "yes"
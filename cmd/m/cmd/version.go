@@ -10,8 +10,16 @@ func newVersionCmd(version string) *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
 		Short: "Print the current version",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println(version)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput(cmd) {
+				return writeResult(cmd, versionResult{Version: version})
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), version)
+			return nil
 		},
 	}
 }
+
+type versionResult struct {
+	Version string `json:"version"`
+}
@@ -65,6 +65,7 @@ func newWorktreeListCmd() *cobra.Command {
 			managedStacksRoot := state.StacksDir(repo.rootPath)
 			currentWorktree := normalizeCmdPath(repo.worktreePath)
 
+			result := worktreeListResult{Worktrees: make([]worktreeResult, 0, len(worktrees))}
 			for _, wt := range worktrees {
 				entryPath := normalizeCmdPath(wt.Path)
 				currentMarker := " "
@@ -84,8 +85,18 @@ func newWorktreeListCmd() *cobra.Command {
 					branch = "(detached)"
 				}
 
+				owner := strings.TrimSpace(stageByWorktree[entryPath])
+				result.Worktrees = append(result.Worktrees, worktreeResult{
+					Path:     wt.Path,
+					Branch:   wt.Branch,
+					Detached: wt.Detached,
+					Kind:     kind,
+					Owner:    owner,
+					Current:  entryPath == currentWorktree,
+				})
+
 				line := fmt.Sprintf("%s %s  [%s]  %s", currentMarker, wt.Path, kind, branch)
-				if owner := strings.TrimSpace(stageByWorktree[entryPath]); owner != "" {
+				if owner != "" {
 					line = fmt.Sprintf("%s  ·  %s", line, owner)
 				}
 
@@ -93,7 +104,7 @@ func newWorktreeListCmd() *cobra.Command {
 			}
 
 			outInfo(cmd.OutOrStdout(), "Total worktrees: %d", len(worktrees))
-			return nil
+			return writeResult(cmd, result)
 		},
 	}
 }
@@ -132,35 +143,31 @@ func newWorktreePruneCmd() *cobra.Command {
 				return err
 			}
 
-			stacksFile, err := state.LoadStacks(repo.rootPath)
-			if err != nil {
-				return err
-			}
-
-			clearedRefs := clearMissingStageWorktrees(stacksFile, func(path string) bool {
-				normalized := normalizeCmdPath(path)
-				if normalized == "" {
-					return false
-				}
+			clearedRefs := 0
+			err = state.UpdateAs(repo.rootPath, commandActor(cmd), func(stacksFile *state.Stacks) error {
+				clearedRefs = clearMissingStageWorktrees(stacksFile, func(path string) bool {
+					normalized := normalizeCmdPath(path)
+					if normalized == "" {
+						return false
+					}
 
-				if _, ok := active[normalized]; ok {
-					return true
-				}
+					if _, ok := active[normalized]; ok {
+						return true
+					}
 
-				_, err := os.Stat(normalized)
-				return err == nil
+					_, err := os.Stat(normalized)
+					return err == nil
+				})
+				return nil
 			})
-
-			if clearedRefs > 0 {
-				if err := state.SaveStacks(repo.rootPath, stacksFile); err != nil {
-					return err
-				}
+			if err != nil {
+				return err
 			}
 
 			outSuccess(cmd.OutOrStdout(), "Pruned git worktrees")
 			outInfo(cmd.OutOrStdout(), "Removed orphan managed directories: %d", removedDirs)
 			outInfo(cmd.OutOrStdout(), "Cleared stale stage worktree references: %d", clearedRefs)
-			return nil
+			return writeResult(cmd, worktreePruneResult{RemovedDirs: removedDirs, ClearedRefs: clearedRefs})
 		},
 	}
 }
@@ -195,10 +202,13 @@ func newWorktreeOpenCmd() *cobra.Command {
 			}
 
 			fromBranch := resolveBaseBranch(baseBranch, repoInfo.DefaultBranch)
+			result := worktreeOpenResult{Branch: branch}
 			if !gitx.BranchExists(repo.rootPath, branch) {
 				if err := gitx.CreateBranch(repo.rootPath, branch, fromBranch); err != nil {
 					return err
 				}
+				result.Base = fromBranch
+				result.CreatedBranch = true
 				outSuccess(cmd.OutOrStdout(), "Created branch %s from %s", branch, fromBranch)
 			} else {
 				outReuse(cmd.OutOrStdout(), "Reusing branch %s", branch)
@@ -217,12 +227,17 @@ func newWorktreeOpenCmd() *cobra.Command {
 					return err
 				}
 				outSuccess(cmd.OutOrStdout(), "Created worktree: %s", resolvedPath)
+				result.CreatedWorktree = true
 			} else if err != nil {
 				return err
 			} else {
 				outReuse(cmd.OutOrStdout(), "Reusing worktree: %s", resolvedPath)
 			}
 
+			result.Path = resolvedPath
+			if err := writeResult(cmd, result); err != nil {
+				return err
+			}
 			if noOpen {
 				return nil
 			}
@@ -238,6 +253,33 @@ func newWorktreeOpenCmd() *cobra.Command {
 	return cmd
 }
 
+type worktreeListResult struct {
+	Worktrees []worktreeResult `json:"worktrees"`
+}
+
+type worktreeResult struct {
+	Path     string `json:"path"`
+	Branch   string `json:"branch,omitempty"`
+	Detached bool   `json:"detached"`
+	// Kind is "stack", "ad-hoc" or "external".
+	Kind    string `json:"kind"`
+	Owner   string `json:"owner,omitempty"`
+	Current bool   `json:"current"`
+}
+
+type worktreePruneResult struct {
+	RemovedDirs int `json:"removed_dirs"`
+	ClearedRefs int `json:"cleared_refs"`
+}
+
+type worktreeOpenResult struct {
+	Branch          string `json:"branch"`
+	Base            string `json:"base,omitempty"`
+	Path            string `json:"path"`
+	CreatedBranch   bool   `json:"created_branch"`
+	CreatedWorktree bool   `json:"created_worktree"`
+}
+
 func normalizeBranchName(raw string) (string, error) {
 	branch := strings.TrimSpace(raw)
 	if branch == "" {
@@ -1,17 +1,18 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/mlawd/m-cli/cmd/m/cmd"
+	"github.com/mlawd/m-cli/internal/errs"
 )
 
 var version = "dev"
 
 func main() {
-	if err := cmd.NewRootCmd(version).Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, cmd.FormatCLIError(os.Stderr, err))
-		os.Exit(1)
+	rootCmd := cmd.NewRootCmd(version)
+	if err := rootCmd.Execute(); err != nil {
+		cmd.PrintCLIError(rootCmd, os.Stderr, err)
+		os.Exit(errs.ExitCode(err))
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+
+	"github.com/mlawd/m-cli/internal/errs"
 )
 
 func StartOpenCode(dir string) error {
@@ -13,7 +15,7 @@ func StartOpenCode(dir string) error {
 func StartOpenCodeWithArgs(dir string, args ...string) error {
 	path, err := exec.LookPath("opencode")
 	if err != nil {
-		return fmt.Errorf("opencode not found in PATH")
+		return errs.Wrap(errs.AgentMissing, fmt.Errorf("opencode not found in PATH"), "Install opencode, or pass --no-open to skip launching it.")
 	}
 
 	cmd := exec.Command(path, args...)
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
+	"time"
 )
 
 type AgentConfig struct {
@@ -43,16 +45,84 @@ func (e AgentEntry) MarshalJSON() ([]byte, error) {
 var validHarnesses = map[string]struct{}{
 	"opencode": {},
 	"claude":   {},
+	"command":  {},
+	"script":   {},
+}
+
+// DefaultMaxReviewRounds is how many times an AI review may send a stage back
+// to implementing before the stage is marked failed. With 0 the first review
+// that requests changes fails the stage.
+const DefaultMaxReviewRounds = 3
+
+// Timeout policies applied when an agent exceeds its budget.
+const (
+	OnTimeoutFail  = "fail"
+	OnTimeoutRetry = "retry"
+)
+
+// Timeouts bounds how long pipeline agents may run. Durations use Go syntax
+// ("90m", "2h"); "0" disables a limit. Idle is how long an agent may go
+// without committing or writing to its log. With OnTimeout "retry", a stage
+// is restarted up to Retries times before it is marked failed.
+type Timeouts struct {
+	Implementing string `json:"implementing,omitempty"`
+	AIReview     string `json:"ai_review,omitempty"`
+	Idle         string `json:"idle,omitempty"`
+	OnTimeout    string `json:"on_timeout,omitempty"`
+	Retries      int    `json:"retries"`
+}
+
+// Prompt delivery modes for the command harness.
+const (
+	PromptArg   = "arg"
+	PromptStdin = "stdin"
+	PromptFile  = "file"
+)
+
+// CommandHarness configures the "command" agent harness, which runs any CLI
+// from an argv template. Args[0] is the executable. Args and Env values are
+// Go templates over {{.Agent}}, {{.Model}}, {{.Prompt}}, {{.PromptFile}},
+// {{.Worktree}}, {{.StackName}}, {{.StageID}} and {{.Phase}}. Prompt selects
+// how the system prompt is delivered: as an argument via {{.Prompt}} (the
+// default), on stdin, or in a temporary file named by {{.PromptFile}}.
+type CommandHarness struct {
+	Args   []string          `json:"args"`
+	Env    map[string]string `json:"env,omitempty"`
+	Prompt string            `json:"prompt,omitempty"`
+}
+
+// ScriptHarness configures the "script" agent harness, which runs a shell
+// script per phase instead of an AI agent so the pipeline can be exercised end
+// to end, e.g. in CI on a temporary repo. Scripts are Go templates over the
+// same values as CommandHarness and run with Shell -c in the stage worktree.
+// An empty script uses a built-in recipe: implementing commits a file change
+// and ai_review approves, both reporting back with `m stage report`.
+type ScriptHarness struct {
+	Implementing string `json:"implementing,omitempty"`
+	AIReview     string `json:"ai_review,omitempty"`
+	Shell        string `json:"shell,omitempty"`
 }
 
 type Config struct {
-	AgentHarness string                `json:"agent_harness"`
-	Agents       map[string]AgentEntry `json:"agents"`
+	AgentHarness    string                `json:"agent_harness"`
+	Agents          map[string]AgentEntry `json:"agents"`
+	MaxReviewRounds int                   `json:"max_review_rounds"`
+	Timeouts        Timeouts              `json:"timeouts"`
+	Command         *CommandHarness       `json:"command,omitempty"`
+	Script          *ScriptHarness        `json:"script,omitempty"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		AgentHarness: "opencode",
+		AgentHarness:    "opencode",
+		MaxReviewRounds: DefaultMaxReviewRounds,
+		Timeouts: Timeouts{
+			Implementing: "2h",
+			AIReview:     "1h",
+			Idle:         "30m",
+			OnTimeout:    OnTimeoutFail,
+			Retries:      1,
+		},
 		Agents: map[string]AgentEntry{
 			"build":  {AgentConfig{Agent: "build"}},
 			"review": {AgentConfig{Agent: "review"}},
@@ -87,6 +157,16 @@ func Load() (*Config, error) {
 	if err := json.Unmarshal(data, &fileCfg); err != nil {
 		return nil, fmt.Errorf("parse config %s: %w", path, err)
 	}
+	// Counts where 0 is meaningful only override the defaults when present.
+	var counts struct {
+		MaxReviewRounds *int `json:"max_review_rounds"`
+		Timeouts        struct {
+			Retries *int `json:"retries"`
+		} `json:"timeouts"`
+	}
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
 
 	if strings.TrimSpace(fileCfg.AgentHarness) != "" {
 		cfg.AgentHarness = fileCfg.AgentHarness
@@ -96,6 +176,30 @@ func Load() (*Config, error) {
 			cfg.Agents[k] = v
 		}
 	}
+	if counts.MaxReviewRounds != nil {
+		cfg.MaxReviewRounds = *counts.MaxReviewRounds
+	}
+	if fileCfg.Timeouts.Implementing != "" {
+		cfg.Timeouts.Implementing = fileCfg.Timeouts.Implementing
+	}
+	if fileCfg.Timeouts.AIReview != "" {
+		cfg.Timeouts.AIReview = fileCfg.Timeouts.AIReview
+	}
+	if fileCfg.Timeouts.Idle != "" {
+		cfg.Timeouts.Idle = fileCfg.Timeouts.Idle
+	}
+	if fileCfg.Timeouts.OnTimeout != "" {
+		cfg.Timeouts.OnTimeout = fileCfg.Timeouts.OnTimeout
+	}
+	if counts.Timeouts.Retries != nil {
+		cfg.Timeouts.Retries = *counts.Timeouts.Retries
+	}
+	if fileCfg.Command != nil {
+		cfg.Command = fileCfg.Command
+	}
+	if fileCfg.Script != nil {
+		cfg.Script = fileCfg.Script
+	}
 
 	return cfg, nil
 }
@@ -122,12 +226,92 @@ func IsValidHarness(name string) bool {
 
 func ValidateConfig(cfg *Config) error {
 	if !IsValidHarness(cfg.AgentHarness) {
-		return fmt.Errorf("invalid agent_harness %q; valid values: opencode, claude", cfg.AgentHarness)
+		return fmt.Errorf("invalid agent_harness %q; valid values: opencode, claude, command, script", cfg.AgentHarness)
+	}
+	if strings.TrimSpace(strings.ToLower(cfg.AgentHarness)) == "command" && cfg.Command == nil {
+		return fmt.Errorf("agent_harness command requires a \"command\" section in %s", ConfigPath())
+	}
+	if cfg.Command != nil {
+		if err := validateCommandHarness(cfg.Command); err != nil {
+			return fmt.Errorf("invalid command harness: %w", err)
+		}
+	}
+	if cfg.Script != nil {
+		for _, script := range []struct{ key, value string }{
+			{"script.implementing", cfg.Script.Implementing},
+			{"script.ai_review", cfg.Script.AIReview},
+		} {
+			if _, err := template.New(script.key).Parse(script.value); err != nil {
+				return fmt.Errorf("invalid %s: %w", script.key, err)
+			}
+		}
 	}
 	for key, entry := range cfg.Agents {
 		if strings.TrimSpace(entry.Agent) == "" {
 			return fmt.Errorf("agent entry %q has empty agent name", key)
 		}
 	}
+	if cfg.MaxReviewRounds < 0 {
+		return fmt.Errorf("invalid max_review_rounds %d; must not be negative", cfg.MaxReviewRounds)
+	}
+	for _, timeout := range []struct{ key, value string }{
+		{"timeouts.implementing", cfg.Timeouts.Implementing},
+		{"timeouts.ai_review", cfg.Timeouts.AIReview},
+		{"timeouts.idle", cfg.Timeouts.Idle},
+	} {
+		if _, err := ParseTimeout(timeout.value); err != nil {
+			return fmt.Errorf("invalid %s: %w", timeout.key, err)
+		}
+	}
+	if cfg.Timeouts.OnTimeout != OnTimeoutFail && cfg.Timeouts.OnTimeout != OnTimeoutRetry {
+		return fmt.Errorf("invalid timeouts.on_timeout %q; valid values: fail, retry", cfg.Timeouts.OnTimeout)
+	}
+	if cfg.Timeouts.Retries < 0 {
+		return fmt.Errorf("invalid timeouts.retries %d; must not be negative", cfg.Timeouts.Retries)
+	}
+	return nil
+}
+
+func validateCommandHarness(c *CommandHarness) error {
+	if len(c.Args) == 0 || strings.TrimSpace(c.Args[0]) == "" {
+		return fmt.Errorf("args must name an executable")
+	}
+	switch c.Prompt {
+	case "", PromptArg, PromptStdin, PromptFile:
+	default:
+		return fmt.Errorf("invalid prompt %q; valid values: arg, stdin, file", c.Prompt)
+	}
+
+	for idx, arg := range c.Args {
+		if _, err := template.New("arg").Parse(arg); err != nil {
+			return fmt.Errorf("args[%d]: %w", idx, err)
+		}
+	}
+	for key, value := range c.Env {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("env names must be non-empty")
+		}
+		if _, err := template.New(key).Parse(value); err != nil {
+			return fmt.Errorf("env %s: %w", key, err)
+		}
+	}
+
 	return nil
 }
+
+// ParseTimeout parses a timeout duration; empty and "0" mean no limit.
+func ParseTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration like 45m or 2h", value)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%q must not be negative", value)
+	}
+	return d, nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestAgentEntryUnmarshalString(t *testing.T) {
@@ -71,6 +72,12 @@ func TestLoadDefaults(t *testing.T) {
 	if len(cfg.Agents) != 2 {
 		t.Errorf("got %d agents, want 2", len(cfg.Agents))
 	}
+	if cfg.MaxReviewRounds != DefaultMaxReviewRounds {
+		t.Errorf("got max_review_rounds %d, want %d", cfg.MaxReviewRounds, DefaultMaxReviewRounds)
+	}
+	if cfg.Timeouts.Implementing != "2h" || cfg.Timeouts.OnTimeout != OnTimeoutFail {
+		t.Errorf("got timeouts %+v, want defaults", cfg.Timeouts)
+	}
 }
 
 func TestLoadFromFile(t *testing.T) {
@@ -81,7 +88,7 @@ func TestLoadFromFile(t *testing.T) {
 	if err := os.MkdirAll(cfgDir, 0o755); err != nil {
 		t.Fatal(err)
 	}
-	if err := os.WriteFile(filepath.Join(cfgDir, "config.json"), []byte(`{"agent_harness":"claude","agents":{"deploy":"deploy"}}`), 0o644); err != nil {
+	if err := os.WriteFile(filepath.Join(cfgDir, "config.json"), []byte(`{"agent_harness":"claude","agents":{"deploy":"deploy"},"max_review_rounds":5,"timeouts":{"idle":"10m","on_timeout":"retry"}}`), 0o644); err != nil {
 		t.Fatal(err)
 	}
 
@@ -99,6 +106,54 @@ func TestLoadFromFile(t *testing.T) {
 	if cfg.Agents["build"].Agent != "build" {
 		t.Errorf("default build agent missing after merge")
 	}
+	if cfg.MaxReviewRounds != 5 {
+		t.Errorf("got max_review_rounds %d, want 5", cfg.MaxReviewRounds)
+	}
+	if cfg.Timeouts.Idle != "10m" || cfg.Timeouts.OnTimeout != OnTimeoutRetry {
+		t.Errorf("got timeouts %+v, want idle 10m with retry", cfg.Timeouts)
+	}
+	if cfg.Timeouts.Implementing != "2h" {
+		t.Errorf("default implementing timeout missing after merge")
+	}
+}
+
+func TestLoadExplicitZeroCounts(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	cfgDir := filepath.Join(dir, "m")
+	if err := os.MkdirAll(cfgDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfgDir, "config.json"), []byte(`{"max_review_rounds":0,"timeouts":{"on_timeout":"retry","retries":0}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxReviewRounds != 0 {
+		t.Errorf("got max_review_rounds %d, want 0", cfg.MaxReviewRounds)
+	}
+	if cfg.Timeouts.Retries != 0 {
+		t.Errorf("got timeouts.retries %d, want 0", cfg.Timeouts.Retries)
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("unexpected error for explicit zero counts: %v", err)
+	}
+
+	// Saving and reloading keeps the zeros rather than reverting to defaults.
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.MaxReviewRounds != 0 || reloaded.Timeouts.Retries != 0 {
+		t.Errorf("got max_review_rounds %d, retries %d after save; want 0, 0", reloaded.MaxReviewRounds, reloaded.Timeouts.Retries)
+	}
 }
 
 func TestValidateConfig(t *testing.T) {
@@ -111,6 +166,50 @@ func TestValidateConfig(t *testing.T) {
 	if err := ValidateConfig(cfg); err == nil {
 		t.Error("expected error for invalid harness")
 	}
+
+	cfg = DefaultConfig()
+	cfg.MaxReviewRounds = -1
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for negative max_review_rounds")
+	}
+
+	cfg = DefaultConfig()
+	cfg.Timeouts.Idle = "soon"
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for invalid idle timeout")
+	}
+
+	cfg = DefaultConfig()
+	cfg.Timeouts.OnTimeout = "ignore"
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for invalid on_timeout policy")
+	}
+
+	cfg = DefaultConfig()
+	cfg.AgentHarness = "command"
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for command harness without a command section")
+	}
+	cfg.Command = &CommandHarness{Args: []string{"aider", "--message-file", "{{.PromptFile}}"}, Prompt: PromptFile}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("unexpected error for command harness: %v", err)
+	}
+	cfg.Command.Args = append(cfg.Command.Args, "{{.Model")
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for malformed command template")
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	for value, want := range map[string]time.Duration{"": 0, "0": 0, "90m": 90 * time.Minute} {
+		got, err := ParseTimeout(value)
+		if err != nil || got != want {
+			t.Errorf("ParseTimeout(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := ParseTimeout("-5m"); err == nil {
+		t.Error("expected error for negative timeout")
+	}
 }
 
 func TestIsValidHarness(t *testing.T) {
@@ -0,0 +1,120 @@
+// Package errs classifies the errors m reports so that the CLI can exit with
+// a stable code per category and suggest how to recover.
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind is the category of an error. Kinds and their exit codes are part of
+// the CLI's interface; scripts may match on them.
+type Kind string
+
+const (
+	// Unknown is any error that has not been categorized.
+	Unknown           Kind = "error"
+	Usage             Kind = "usage"
+	NotARepo          Kind = "not-a-repo"
+	NotInitialized    Kind = "not-initialized"
+	StackNotFound     Kind = "stack-not-found"
+	NoPlan            Kind = "no-plan"
+	InvalidTransition Kind = "invalid-transition"
+	GitFailure        Kind = "git-failure"
+	GHMissing         Kind = "gh-missing"
+	RebaseConflict    Kind = "rebase-conflict"
+	AgentMissing      Kind = "agent-missing"
+)
+
+var exitCodes = map[Kind]int{
+	Unknown:           1,
+	Usage:             2,
+	NotARepo:          3,
+	NotInitialized:    4,
+	StackNotFound:     5,
+	NoPlan:            6,
+	InvalidTransition: 7,
+	GitFailure:        8,
+	GHMissing:         9,
+	RebaseConflict:    10,
+	AgentMissing:      11,
+}
+
+// defaultHints are shown for errors of a kind that carry no hints of their
+// own.
+var defaultHints = map[Kind][]string{
+	Usage:             {"Run the command with --help to see its arguments and flags."},
+	NotARepo:          {"Run m inside a git repository or one of its worktrees."},
+	NotInitialized:    {"Run `m init` in the repository first."},
+	StackNotFound:     {"Run `m stack list` to see stacks, or pass --stack <name>."},
+	NoPlan:            {"Attach a plan with `m stack attach-plan <plan-file>`."},
+	InvalidTransition: {"Run `m stage show` to see the stage's status; `m stage retry`, `m stage reset` and `m stage skip` move stopped stages on."},
+	GitFailure:        {"Check the git message above, fix the repository state, and rerun."},
+	GHMissing:         {"Install the GitHub CLI (https://cli.github.com) and run `gh auth login`."},
+	RebaseConflict:    {"Resolve the conflict in the stage worktree and run `m stack sync --continue`, or `m stack sync --abort` to roll the sync back."},
+	AgentMissing:      {"Install the agent CLI, or pick another harness with `m config set agent_harness <opencode|claude|command|script>`."},
+}
+
+// Error is an error with a kind and optional remediation hints.
+type Error struct {
+	Kind  Kind
+	Err   error
+	Hints []string
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// New formats an error of the given kind, as fmt.Errorf does.
+func New(kind Kind, format string, args ...any) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap gives err a kind and hints, replacing the default hints for the kind.
+// It returns nil when err is nil.
+func Wrap(kind Kind, err error, hints ...string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err, Hints: hints}
+}
+
+// KindOf returns the kind of the outermost categorized error in err's chain,
+// or Unknown.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Unknown
+}
+
+// Is reports whether err is of the given kind.
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+// ExitCode is the process exit status for err: 0 for nil, 1 for errors that
+// have no kind.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if code, ok := exitCodes[KindOf(err)]; ok {
+		return code
+	}
+	return exitCodes[Unknown]
+}
+
+// Hints returns what the user can do about err.
+func Hints(err error) []string {
+	var e *Error
+	if !errors.As(err, &e) {
+		return nil
+	}
+	if len(e.Hints) > 0 {
+		return e.Hints
+	}
+	return defaultHints[e.Kind]
+}
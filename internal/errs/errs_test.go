@@ -0,0 +1,57 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestExitCodesAreStable guards the documented exit codes, which scripts
+// depend on.
+func TestExitCodesAreStable(t *testing.T) {
+	want := map[Kind]int{
+		Unknown:           1,
+		Usage:             2,
+		NotARepo:          3,
+		NotInitialized:    4,
+		StackNotFound:     5,
+		NoPlan:            6,
+		InvalidTransition: 7,
+		GitFailure:        8,
+		GHMissing:         9,
+		RebaseConflict:    10,
+		AgentMissing:      11,
+	}
+	for kind, code := range want {
+		if got := ExitCode(&Error{Kind: kind, Err: errors.New("x")}); got != code {
+			t.Errorf("ExitCode(%s) = %d, want %d", kind, got, code)
+		}
+	}
+	if len(exitCodes) != len(want) {
+		t.Errorf("exitCodes has %d kinds, want %d", len(exitCodes), len(want))
+	}
+	if got := ExitCode(nil); got != 0 {
+		t.Errorf("ExitCode(nil) = %d, want 0", got)
+	}
+	if got := ExitCode(errors.New("plain")); got != 1 {
+		t.Errorf("ExitCode(plain) = %d, want 1", got)
+	}
+}
+
+func TestOutermostKindWins(t *testing.T) {
+	git := New(GitFailure, "git rebase main: conflict")
+	err := fmt.Errorf("sync: %w", Wrap(RebaseConflict, git, "resolve it"))
+
+	if got := KindOf(err); got != RebaseConflict {
+		t.Fatalf("KindOf() = %q, want %q", got, RebaseConflict)
+	}
+	if hints := Hints(err); len(hints) != 1 || hints[0] != "resolve it" {
+		t.Fatalf("Hints() = %q", hints)
+	}
+	if hints := Hints(git); len(hints) != 1 || hints[0] != defaultHints[GitFailure][0] {
+		t.Fatalf("Hints(git) = %q, want the default", hints)
+	}
+	if err.Error() != "sync: git rebase main: conflict" {
+		t.Fatalf("Error() = %q", err.Error())
+	}
+}
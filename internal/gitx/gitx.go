@@ -8,6 +8,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/mlawd/m-cli/internal/errs"
 )
 
 type RepoInfo struct {
@@ -48,7 +50,7 @@ func Run(dir string, args ...string) (string, error) {
 		if msg == "" {
 			msg = err.Error()
 		}
-		return out, fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+		return out, errs.New(errs.GitFailure, "git %s: %s", strings.Join(args, " "), msg)
 	}
 
 	return out, nil
@@ -57,7 +59,7 @@ func Run(dir string, args ...string) (string, error) {
 func DiscoverRepo(startDir string) (*RepoInfo, error) {
 	common, err := Run(startDir, "rev-parse", "--git-common-dir")
 	if err != nil {
-		return nil, err
+		return nil, errs.Wrap(errs.NotARepo, err)
 	}
 	if !filepath.IsAbs(common) {
 		base, baseErr := Run(startDir, "rev-parse", "--show-toplevel")
@@ -113,6 +115,57 @@ func BranchExists(dir, branch string) bool {
 	return err == nil
 }
 
+// IsAncestor reports whether ancestor is reachable from commit.
+func IsAncestor(dir, ancestor, commit string) bool {
+	_, err := Run(dir, "merge-base", "--is-ancestor", ancestor, commit)
+	return err == nil
+}
+
+// MergeCommit merges the given commits in order without using a worktree and
+// returns the resulting commit. It fails if any merge has conflicts.
+func MergeCommit(dir, message string, commits ...string) (string, error) {
+	if len(commits) == 0 {
+		return "", fmt.Errorf("at least one commit is required")
+	}
+
+	tip, err := Run(dir, "rev-parse", "--verify", commits[0]+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+
+	for _, next := range commits[1:] {
+		if IsAncestor(dir, next, tip) {
+			continue
+		}
+
+		out, err := Run(dir, "merge-tree", "--write-tree", "--name-only", tip, next)
+		lines := strings.Split(out, "\n")
+		if err != nil {
+			// On conflicts merge-tree prints the tree, then the conflicted
+			// paths up to a blank line.
+			var conflicted []string
+			for _, line := range lines[1:] {
+				if strings.TrimSpace(line) == "" {
+					break
+				}
+				conflicted = append(conflicted, strings.TrimSpace(line))
+			}
+			if len(conflicted) == 0 {
+				return "", err
+			}
+			return "", fmt.Errorf("merging %s conflicts in %s", next, strings.Join(conflicted, ", "))
+		}
+		tree := strings.TrimSpace(lines[0])
+
+		tip, err = Run(dir, "commit-tree", tree, "-p", tip, "-p", next, "-m", message)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return tip, nil
+}
+
 func RemoteBranchExists(dir, remote, branch string) bool {
 	remote = strings.TrimSpace(remote)
 	branch = strings.TrimSpace(branch)
@@ -3,7 +3,10 @@ package gitx
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/mlawd/m-cli/internal/errs"
 )
 
 func TestCopyRootDotEnvFilesCopiesEnvFiles(t *testing.T) {
@@ -65,6 +68,66 @@ func TestCopyRootDotEnvFilesDoesNotOverwrite(t *testing.T) {
 	mustHaveFileWithContents(t, filepath.Join(worktreeDir, ".env"), "WORKTREE=1")
 }
 
+func TestMergeCommit(t *testing.T) {
+	dir := initTestRepo(t)
+	mustGit(t, dir, "checkout", "-q", "-b", "api")
+	mustWriteFile(t, filepath.Join(dir, "api.txt"), "api")
+	mustGit(t, dir, "add", ".")
+	mustGit(t, dir, "commit", "-q", "-m", "api")
+	mustGit(t, dir, "checkout", "-q", "-b", "ui", "main")
+	mustWriteFile(t, filepath.Join(dir, "ui.txt"), "ui")
+	mustGit(t, dir, "add", ".")
+	mustGit(t, dir, "commit", "-q", "-m", "ui")
+
+	commit, err := MergeCommit(dir, "Integrate api, ui", "api", "ui")
+	if err != nil {
+		t.Fatalf("MergeCommit: %v", err)
+	}
+	for _, parent := range []string{"api", "ui"} {
+		if !IsAncestor(dir, parent, commit) {
+			t.Fatalf("expected %s to be an ancestor of the merge", parent)
+		}
+	}
+	files := mustGit(t, dir, "ls-tree", "--name-only", commit)
+	if !strings.Contains(files, "api.txt") || !strings.Contains(files, "ui.txt") {
+		t.Fatalf("merge tree = %q, want both files", files)
+	}
+
+	mustGit(t, dir, "checkout", "-q", "-b", "clash", "main")
+	mustWriteFile(t, filepath.Join(dir, "api.txt"), "other")
+	mustGit(t, dir, "add", ".")
+	mustGit(t, dir, "commit", "-q", "-m", "clash")
+
+	_, err = MergeCommit(dir, "Integrate", "api", "clash")
+	if err == nil || !strings.Contains(err.Error(), "conflicts in api.txt") {
+		t.Fatalf("MergeCommit error = %v, want conflict in api.txt", err)
+	}
+}
+
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := t.TempDir()
+	mustGit(t, dir, "init", "-q", "-b", "main")
+	mustWriteFile(t, filepath.Join(dir, "README.md"), "test")
+	mustGit(t, dir, "add", ".")
+	mustGit(t, dir, "commit", "-q", "-m", "init")
+	return dir
+}
+
+func mustGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := Run(dir, args...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return out
+}
+
 func mustWriteFile(t *testing.T, path, content string) {
 	t.Helper()
 	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
@@ -89,3 +152,14 @@ func mustNotExist(t *testing.T, path string) {
 		t.Fatalf("expected %s not to exist, got err=%v", path, err)
 	}
 }
+
+func TestErrorKinds(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := DiscoverRepo(dir); errs.KindOf(err) != errs.NotARepo {
+		t.Fatalf("DiscoverRepo() error = %v, want %s", err, errs.NotARepo)
+	}
+	if _, err := Run(dir, "status"); errs.KindOf(err) != errs.GitFailure {
+		t.Fatalf("Run() error = %v, want %s", err, errs.GitFailure)
+	}
+}
@@ -2,55 +2,76 @@ package harness
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"os"
 	"os/exec"
 
 	"github.com/mlawd/m-cli/internal/config"
+	"github.com/mlawd/m-cli/internal/errs"
 )
 
+// claudeMCPConfigName is the file in AgentOpts.FilesDir passed to
+// --mcp-config.
+const claudeMCPConfigName = "mcp.json"
+
 type ClaudeHarness struct {
 	Config *config.Config
 }
 
-func (h *ClaudeHarness) SpawnBuildAgent(ctx context.Context, opts AgentOpts) error {
-	agentName := "build"
-	if entry, ok := h.Config.Agents["build"]; ok {
-		agentName = entry.Agent
-	}
-
-	return h.spawn(ctx, agentName, opts)
+func (h *ClaudeHarness) SpawnBuildAgent(ctx context.Context, opts AgentOpts) (*Process, error) {
+	agentName, model := resolveAgent(h.Config, "build", opts)
+	return h.spawn(ctx, agentName, model, opts)
 }
 
-func (h *ClaudeHarness) SpawnReviewAgent(ctx context.Context, opts AgentOpts) error {
-	agentName := "review"
-	if entry, ok := h.Config.Agents["review"]; ok {
-		agentName = entry.Agent
-	}
-
-	return h.spawn(ctx, agentName, opts)
+func (h *ClaudeHarness) SpawnReviewAgent(ctx context.Context, opts AgentOpts) (*Process, error) {
+	agentName, model := resolveAgent(h.Config, "review", opts)
+	return h.spawn(ctx, agentName, model, opts)
 }
 
-func (h *ClaudeHarness) spawn(ctx context.Context, agentName string, opts AgentOpts) error {
+func (h *ClaudeHarness) spawn(ctx context.Context, agentName, model string, opts AgentOpts) (*Process, error) {
 	path, err := exec.LookPath("claude")
 	if err != nil {
-		return fmt.Errorf("claude not found in PATH; install it or switch agent_harness to opencode")
+		return nil, errs.Wrap(errs.AgentMissing, fmt.Errorf("claude not found in PATH"), "Install Claude Code, or switch agent_harness to opencode.")
 	}
 
-	args := []string{"--agent", agentName, "--prompt", opts.SystemPrompt}
-
-	cmd := exec.CommandContext(ctx, path, args...)
-	cmd.Dir = opts.WorktreePath
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	args := []string{"--agent", agentName}
+	if model != "" {
+		args = append(args, "--model", model)
+	}
+	if opts.MCP != nil {
+		// The config carries the server's bearer token, so it goes in a
+		// private file rather than on the command line.
+		mcpConfig, err := claudeMCPConfig(opts.MCP)
+		if err != nil {
+			return nil, err
+		}
+		mcpConfigPath, err := writeAgentFile(opts, claudeMCPConfigName, mcpConfig)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--mcp-config", mcpConfigPath)
+	}
+	args = append(args, "--prompt", opts.SystemPrompt)
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("spawn claude %s agent: %w", agentName, err)
+	proc, err := startAgent(ctx, path, args, opts)
+	if err != nil {
+		return nil, fmt.Errorf("spawn claude %s agent: %w", agentName, err)
 	}
 
-	go func() {
-		_ = cmd.Wait()
-	}()
+	return proc, nil
+}
 
-	return nil
+// claudeMCPConfig is the --mcp-config JSON pointing claude at a shared m
+// server.
+func claudeMCPConfig(endpoint *MCPEndpoint) (string, error) {
+	server := map[string]any{"type": "http", "url": endpoint.URL}
+	if headers := endpoint.headers(); headers != nil {
+		server["headers"] = headers
+	}
+
+	data, err := json.Marshal(map[string]any{"mcpServers": map[string]any{mcpServerName: server}})
+	if err != nil {
+		return "", fmt.Errorf("encode claude MCP config: %w", err)
+	}
+	return string(data), nil
 }
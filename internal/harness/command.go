@@ -0,0 +1,171 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/mlawd/m-cli/internal/config"
+	"github.com/mlawd/m-cli/internal/errs"
+)
+
+// promptFileName is the file in AgentOpts.FilesDir holding the system prompt
+// for {{.PromptFile}}.
+const promptFileName = "prompt.md"
+
+// CommandHarness runs the CLI described by config.CommandHarness, letting any
+// agent tool be plugged in without code changes.
+type CommandHarness struct {
+	Config *config.Config
+}
+
+// CommandVars are the values available to command harness templates.
+type CommandVars struct {
+	Agent      string
+	Model      string
+	Prompt     string
+	PromptFile string
+	Worktree   string
+	StackName  string
+	StageID    string
+	Phase      string
+	// M is the path to the m binary, for calling back into it.
+	M string
+	// MCPURL and MCPToken locate a shared `m mcp serve --http` server when
+	// one is running; both are empty otherwise.
+	MCPURL   string
+	MCPToken string
+}
+
+func (h *CommandHarness) SpawnBuildAgent(ctx context.Context, opts AgentOpts) (*Process, error) {
+	agentName, model := resolveAgent(h.Config, "build", opts)
+	return h.spawn(ctx, agentName, model, opts)
+}
+
+func (h *CommandHarness) SpawnReviewAgent(ctx context.Context, opts AgentOpts) (*Process, error) {
+	agentName, model := resolveAgent(h.Config, "review", opts)
+	return h.spawn(ctx, agentName, model, opts)
+}
+
+func (h *CommandHarness) spawn(ctx context.Context, agentName, model string, opts AgentOpts) (*Process, error) {
+	spec := h.Config.Command
+	if spec == nil || len(spec.Args) == 0 {
+		return nil, fmt.Errorf("agent harness command is not configured")
+	}
+
+	vars := CommandVars{
+		Agent:     agentName,
+		Model:     model,
+		Worktree:  opts.WorktreePath,
+		StackName: opts.StackName,
+		StageID:   opts.StageID,
+		Phase:     opts.Phase,
+		M:         mBinary(),
+	}
+	if opts.MCP != nil {
+		vars.MCPURL, vars.MCPToken = opts.MCP.URL, opts.MCP.Token
+	}
+
+	switch spec.Prompt {
+	case config.PromptStdin:
+	case config.PromptFile:
+		path, err := writeAgentFile(opts, promptFileName, opts.SystemPrompt)
+		if err != nil {
+			return nil, err
+		}
+		vars.PromptFile = path
+	default:
+		vars.Prompt = opts.SystemPrompt
+	}
+
+	proc, err := h.start(ctx, spec, vars, opts)
+	if err != nil {
+		return nil, fmt.Errorf("spawn command %s agent: %w", agentName, err)
+	}
+
+	return proc, nil
+}
+
+func (h *CommandHarness) start(ctx context.Context, spec *config.CommandHarness, vars CommandVars, opts AgentOpts) (*Process, error) {
+	args, err := RenderCommandArgs(spec.Args, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return nil, errs.New(errs.AgentMissing, "%s not found in PATH", args[0])
+	}
+
+	cmd := exec.CommandContext(ctx, path, args[1:]...)
+	cmd.Env = os.Environ()
+	keys := make([]string, 0, len(spec.Env))
+	for key := range spec.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := renderCommandTemplate(key, spec.Env[key], vars)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	if spec.Prompt == config.PromptStdin {
+		cmd.Stdin = strings.NewReader(opts.SystemPrompt)
+	}
+
+	return startAgentCmd(cmd, opts)
+}
+
+// RenderCommandArgs expands an argv template. Arguments that render empty,
+// such as {{.Model}} with no model configured, are dropped.
+func RenderCommandArgs(templates []string, vars CommandVars) ([]string, error) {
+	args := make([]string, 0, len(templates))
+	for idx, text := range templates {
+		arg, err := renderCommandTemplate(fmt.Sprintf("args[%d]", idx), text, vars)
+		if err != nil {
+			return nil, err
+		}
+		if arg == "" {
+			continue
+		}
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("command args rendered empty")
+	}
+
+	return args, nil
+}
+
+func renderCommandTemplate(name, text string, vars CommandVars) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// mBinary is the m executable agents should call back into: $M_BIN when set,
+// otherwise the running binary.
+func mBinary() string {
+	if bin := os.Getenv("M_BIN"); bin != "" {
+		return bin
+	}
+	bin, err := os.Executable()
+	if err != nil {
+		return "m"
+	}
+	return bin
+}
@@ -3,7 +3,11 @@ package harness
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/mlawd/m-cli/internal/config"
 )
@@ -15,11 +19,54 @@ type AgentOpts struct {
 	StageID      string
 	Phase        string // "implementing" | "ai_review"
 	SystemPrompt string
+	// Model, if set, overrides the model configured for the agent.
+	Model string
+	// ReviewFindings holds the findings of a review that sent the stage back
+	// to implementing.
+	ReviewFindings string
+	// MCP, if set, is a shared MCP server the agent should use instead of
+	// launching its own.
+	MCP *MCPEndpoint
+	// Output, if set, receives the agent's stdout and stderr instead of the
+	// spawning process's terminal.
+	Output *os.File
+	// FilesDir is a private directory for files handed to the agent, such as
+	// a prompt file or an MCP config holding the server token. The caller
+	// owns it and clears it between spawns.
+	FilesDir string
+	// RecordExit runs the agent under `m agent exec`, which outlives the
+	// spawning process and records the agent's exit code on its stage.
+	RecordExit bool
+}
+
+// MCPEndpoint is a running `m mcp serve --http` server.
+type MCPEndpoint struct {
+	URL   string
+	Token string
+}
+
+// mcpServerName is the MCP server key agents know m by, matching the
+// configuration suggested in the README.
+const mcpServerName = "m_cli"
+
+// mcpHeaders are the HTTP headers an agent sends to the endpoint.
+func (e *MCPEndpoint) headers() map[string]string {
+	if e.Token == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + e.Token}
+}
+
+// Process describes a started agent process.
+type Process struct {
+	PID       int
+	Command   []string
+	StartedAt time.Time
 }
 
 type Harness interface {
-	SpawnBuildAgent(ctx context.Context, opts AgentOpts) error
-	SpawnReviewAgent(ctx context.Context, opts AgentOpts) error
+	SpawnBuildAgent(ctx context.Context, opts AgentOpts) (*Process, error)
+	SpawnReviewAgent(ctx context.Context, opts AgentOpts) (*Process, error)
 }
 
 func ForConfig(cfg *config.Config) (Harness, error) {
@@ -28,7 +75,138 @@ func ForConfig(cfg *config.Config) (Harness, error) {
 		return &OpenCodeHarness{Config: cfg}, nil
 	case "claude":
 		return &ClaudeHarness{Config: cfg}, nil
+	case "command":
+		if cfg.Command == nil {
+			return nil, fmt.Errorf("agent harness command is not configured")
+		}
+		return &CommandHarness{Config: cfg}, nil
+	case "script":
+		return &ScriptHarness{Config: cfg}, nil
 	default:
 		return nil, fmt.Errorf("unsupported agent harness %q", cfg.AgentHarness)
 	}
 }
+
+// Executable returns the program the configured harness runs, so callers
+// can check it is installed before starting a pipeline.
+func Executable(cfg *config.Config) string {
+	name := strings.TrimSpace(strings.ToLower(cfg.AgentHarness))
+	if name == "command" && cfg.Command != nil && len(cfg.Command.Args) > 0 {
+		return cfg.Command.Args[0]
+	}
+	if name == "script" {
+		return scriptShell(cfg)
+	}
+	return name
+}
+
+// resolveAgent returns the configured agent name and model for a role
+// ("build" or "review"). A model in opts takes precedence over config.
+func resolveAgent(cfg *config.Config, role string, opts AgentOpts) (string, string) {
+	agentName, model := role, ""
+	if entry, ok := cfg.Agents[role]; ok {
+		agentName = entry.Agent
+		model = entry.Model
+	}
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
+	return agentName, model
+}
+
+// startAgent starts the agent binary in the stage worktree.
+func startAgent(ctx context.Context, path string, args []string, opts AgentOpts) (*Process, error) {
+	return startAgentCmd(exec.CommandContext(ctx, path, args...), opts)
+}
+
+// startAgentCmd is startAgent for a prepared command, so harnesses can set
+// its environment and stdin.
+func startAgentCmd(cmd *exec.Cmd, opts AgentOpts) (*Process, error) {
+	cmd.Dir = opts.WorktreePath
+	if opts.MCP != nil {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, "M_MCP_URL="+opts.MCP.URL, "M_MCP_TOKEN="+opts.MCP.Token)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if opts.Output != nil {
+		cmd.Stdout = opts.Output
+		cmd.Stderr = opts.Output
+	}
+
+	command := displayCommand(cmd.Path, cmd.Args[1:], opts)
+	if opts.RecordExit {
+		wrapExitRecorder(cmd, opts)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// Reap the agent if it exits while the spawning process is still
+	// running; its exit code is recorded by the wrapper, if any.
+	go func() { _ = cmd.Wait() }()
+
+	return &Process{
+		PID:       cmd.Process.Pid,
+		Command:   command,
+		StartedAt: time.Now().UTC(),
+	}, nil
+}
+
+// wrapExitRecorder rewrites cmd to run under `m agent exec`, so the agent's
+// exit code is recorded on its stage however long it outlives the spawner.
+func wrapExitRecorder(cmd *exec.Cmd, opts AgentOpts) {
+	bin := mBinary()
+	if path, err := exec.LookPath(bin); err == nil {
+		bin = path
+	}
+
+	args := []string{bin, "agent", "exec", "--stack", opts.StackName, "--stage", opts.StageID, "--", cmd.Path}
+	cmd.Args = append(args, cmd.Args[1:]...)
+	cmd.Path = bin
+}
+
+// writeAgentFile writes a file readable only by the current user into
+// opts.FilesDir and returns its path.
+func writeAgentFile(opts AgentOpts, name, content string) (string, error) {
+	if opts.FilesDir == "" {
+		return "", fmt.Errorf("write %s: no agent files directory", name)
+	}
+	if err := os.MkdirAll(opts.FilesDir, 0o700); err != nil {
+		return "", fmt.Errorf("write %s: %w", name, err)
+	}
+
+	path := filepath.Join(opts.FilesDir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return "", fmt.Errorf("write %s: %w", name, err)
+	}
+	return path, nil
+}
+
+// displayCommand is the command line recorded for an agent, with the system
+// prompt elided since it repeats the whole stage context, and any MCP token
+// redacted since the recorded command is stored and shown in plain text.
+func displayCommand(path string, args []string, opts AgentOpts) []string {
+	token := ""
+	if opts.MCP != nil {
+		token = opts.MCP.Token
+	}
+
+	command := make([]string, 0, len(args)+1)
+	command = append(command, path)
+	for _, arg := range args {
+		if opts.SystemPrompt != "" && arg == opts.SystemPrompt {
+			arg = "<prompt>"
+		}
+		if token != "" {
+			arg = strings.ReplaceAll(arg, token, "<token>")
+		}
+		command = append(command, arg)
+	}
+
+	return command
+}
@@ -0,0 +1,171 @@
+package harness
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/config"
+)
+
+func TestResolveAgentModel(t *testing.T) {
+	cfg := &config.Config{Agents: map[string]config.AgentEntry{
+		"build":  {AgentConfig: config.AgentConfig{Agent: "builder", Model: "sonnet"}},
+		"review": {AgentConfig: config.AgentConfig{Agent: "reviewer"}},
+	}}
+
+	tests := []struct {
+		role, override string
+		wantAgent      string
+		wantModel      string
+	}{
+		{role: "build", wantAgent: "builder", wantModel: "sonnet"},
+		{role: "build", override: "haiku", wantAgent: "builder", wantModel: "haiku"},
+		{role: "review", wantAgent: "reviewer"},
+		{role: "review", override: "opus", wantAgent: "reviewer", wantModel: "opus"},
+	}
+
+	for _, tc := range tests {
+		agent, model := resolveAgent(cfg, tc.role, AgentOpts{Model: tc.override})
+		if agent != tc.wantAgent || model != tc.wantModel {
+			t.Errorf("resolveAgent(%s, %q) = %q, %q; want %q, %q", tc.role, tc.override, agent, model, tc.wantAgent, tc.wantModel)
+		}
+	}
+}
+
+func TestRenderCommandArgsDropsEmptyArgs(t *testing.T) {
+	vars := CommandVars{Agent: "build", StageID: "api", PromptFile: "/tmp/prompt.md"}
+	args, err := RenderCommandArgs([]string{"aider", "{{if .Model}}--model={{.Model}}{{end}}", "--message-file", "{{.PromptFile}}", "--name={{.StageID}}"}, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"aider", "--message-file", "/tmp/prompt.md", "--name=api"}
+	if strings.Join(args, " ") != strings.Join(want, " ") {
+		t.Fatalf("args = %q, want %q", args, want)
+	}
+
+	if _, err := RenderCommandArgs([]string{"tool", "{{.Unknown}}"}, vars); err == nil {
+		t.Fatal("expected error for unknown template field")
+	}
+}
+
+func TestCommandHarnessDeliversPrompt(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	for _, mode := range []string{config.PromptArg, config.PromptStdin, config.PromptFile} {
+		t.Run(mode, func(t *testing.T) {
+			dir := t.TempDir()
+			script := map[string]string{
+				config.PromptArg:   `printf '%s' "$1" > out.txt`,
+				config.PromptStdin: `cat > out.txt`,
+				config.PromptFile:  `cat "$1" > out.txt`,
+			}[mode]
+			arg := map[string]string{
+				config.PromptArg:   "{{.Prompt}}",
+				config.PromptStdin: "{{.StageID}}",
+				config.PromptFile:  "{{.PromptFile}}",
+			}[mode]
+
+			h := &CommandHarness{Config: &config.Config{Command: &config.CommandHarness{
+				Args:   []string{"sh", "-c", script + `; printf ' %s' "$AGENT" >> out.txt`, "agent", arg},
+				Env:    map[string]string{"AGENT": "{{.Agent}}-{{.Phase}}"},
+				Prompt: mode,
+			}}}
+
+			_, err := h.SpawnBuildAgent(context.Background(), AgentOpts{
+				WorktreePath: dir,
+				StageID:      "api",
+				Phase:        "implementing",
+				SystemPrompt: "do the work",
+				FilesDir:     filepath.Join(dir, "agent"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := "do the work build-implementing"
+			var got string
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+				out, _ := os.ReadFile(filepath.Join(dir, "out.txt"))
+				if got = string(out); got == want {
+					return
+				}
+			}
+			t.Fatalf("output = %q, want %q", got, want)
+		})
+	}
+}
+
+func TestMCPConfigPointsAgentsAtSharedServer(t *testing.T) {
+	endpoint := &MCPEndpoint{URL: "http://127.0.0.1:7777/mcp", Token: "secret"}
+
+	claude, err := claudeMCPConfig(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"mcpServers":{"m_cli":{"headers":{"Authorization":"Bearer secret"},"type":"http","url":"http://127.0.0.1:7777/mcp"}}}`
+	if claude != want {
+		t.Fatalf("claude config = %s, want %s", claude, want)
+	}
+
+	opencode, err := opencodeMCPConfig(&MCPEndpoint{URL: endpoint.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"mcp":{"m_cli":{"enabled":true,"type":"remote","url":"http://127.0.0.1:7777/mcp"}}}`
+	if opencode != want {
+		t.Fatalf("opencode config = %s, want %s", opencode, want)
+	}
+}
+
+func TestClaudeMCPConfigStaysOffTheCommandLine(t *testing.T) {
+	endpoint := &MCPEndpoint{URL: "http://127.0.0.1:7777/mcp", Token: "s3cret"}
+	opts := AgentOpts{SystemPrompt: "do the work", MCP: endpoint, FilesDir: filepath.Join(t.TempDir(), "agent")}
+
+	mcpConfig, err := claudeMCPConfig(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := writeAgentFile(opts, claudeMCPConfigName, mcpConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != opts.FilesDir {
+		t.Fatalf("config path = %s, want it in %s", path, opts.FilesDir)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("config mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	got := displayCommand("/bin/agent", []string{"--prompt", "do the work", "--header", "Authorization: Bearer s3cret"}, opts)
+	want := []string{"/bin/agent", "--prompt", "<prompt>", "--header", "Authorization: Bearer <token>"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("displayCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestRecordExitRunsAgentUnderWrapper(t *testing.T) {
+	t.Setenv("M_BIN", "/opt/m/bin/m")
+	opts := AgentOpts{StackName: "checkout", StageID: "api", Phase: "implementing", RecordExit: true}
+
+	cmd := exec.Command("/bin/agent", "--print", "go")
+	wrapExitRecorder(cmd, opts)
+
+	want := []string{"/opt/m/bin/m", "agent", "exec", "--stack", "checkout", "--stage", "api", "--", "/bin/agent", "--print", "go"}
+	if cmd.Path != want[0] || !reflect.DeepEqual(cmd.Args, want) {
+		t.Fatalf("command = %s %q, want %q", cmd.Path, cmd.Args, want)
+	}
+}
@@ -2,58 +2,72 @@ package harness
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 
 	"github.com/mlawd/m-cli/internal/config"
+	"github.com/mlawd/m-cli/internal/errs"
 )
 
 type OpenCodeHarness struct {
 	Config *config.Config
 }
 
-func (h *OpenCodeHarness) SpawnBuildAgent(ctx context.Context, opts AgentOpts) error {
-	agentName := "build"
-	if entry, ok := h.Config.Agents["build"]; ok {
-		agentName = entry.Agent
-	}
-
-	return h.spawn(ctx, agentName, opts)
+func (h *OpenCodeHarness) SpawnBuildAgent(ctx context.Context, opts AgentOpts) (*Process, error) {
+	agentName, model := resolveAgent(h.Config, "build", opts)
+	return h.spawn(ctx, agentName, model, opts)
 }
 
-func (h *OpenCodeHarness) SpawnReviewAgent(ctx context.Context, opts AgentOpts) error {
-	agentName := "review"
-	if entry, ok := h.Config.Agents["review"]; ok {
-		agentName = entry.Agent
-	}
-
-	return h.spawn(ctx, agentName, opts)
+func (h *OpenCodeHarness) SpawnReviewAgent(ctx context.Context, opts AgentOpts) (*Process, error) {
+	agentName, model := resolveAgent(h.Config, "review", opts)
+	return h.spawn(ctx, agentName, model, opts)
 }
 
-func (h *OpenCodeHarness) spawn(ctx context.Context, agentName string, opts AgentOpts) error {
+func (h *OpenCodeHarness) spawn(ctx context.Context, agentName, model string, opts AgentOpts) (*Process, error) {
 	path, err := exec.LookPath("opencode")
 	if err != nil {
-		return fmt.Errorf("opencode not found in PATH; install it or switch agent_harness to claude")
+		return nil, errs.Wrap(errs.AgentMissing, fmt.Errorf("opencode not found in PATH"), "Install opencode, or switch agent_harness to claude.")
 	}
 
-	args := []string{"--agent", agentName, "--prompt", opts.SystemPrompt}
+	args := []string{"--agent", agentName}
+	if model != "" {
+		args = append(args, "--model", model)
+	}
+	args = append(args, "--prompt", opts.SystemPrompt)
 
 	cmd := exec.CommandContext(ctx, path, args...)
-	cmd.Dir = opts.WorktreePath
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	if opts.MCP != nil {
+		mcpConfig, err := opencodeMCPConfig(opts.MCP)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Env = append(os.Environ(), "OPENCODE_CONFIG_CONTENT="+mcpConfig)
+	}
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("spawn opencode %s agent: %w", agentName, err)
+	proc, err := startAgentCmd(cmd, opts)
+	if err != nil {
+		return nil, fmt.Errorf("spawn opencode %s agent: %w", agentName, err)
 	}
 
-	go func() {
-		_ = cmd.Wait()
-	}()
+	return proc, nil
+}
+
+// opencodeMCPConfig is inline opencode config replacing the m stdio server
+// with a remote entry for a shared m server.
+func opencodeMCPConfig(endpoint *MCPEndpoint) (string, error) {
+	server := map[string]any{"type": "remote", "url": endpoint.URL, "enabled": true}
+	if headers := endpoint.headers(); headers != nil {
+		server["headers"] = headers
+	}
 
-	return nil
+	data, err := json.Marshal(map[string]any{"mcp": map[string]any{mcpServerName: server}})
+	if err != nil {
+		return "", fmt.Errorf("encode opencode MCP config: %w", err)
+	}
+	return string(data), nil
 }
 
 func BuildSystemPrompt(opts AgentOpts) string {
@@ -71,12 +85,23 @@ func BuildSystemPrompt(opts AgentOpts) string {
 		b.WriteString("\n\n")
 	}
 
+	if opts.ReviewFindings != "" {
+		b.WriteString("## Review Findings\n\n")
+		b.WriteString("A reviewer requested changes to your previous implementation. Address these findings:\n\n")
+		b.WriteString(opts.ReviewFindings)
+		b.WriteString("\n\n")
+	}
+
 	b.WriteString("## Completion\n\n")
 	b.WriteString("When your work is complete, call the report_stage_done MCP tool with:\n")
 	b.WriteString(fmt.Sprintf("- stack_name: %q\n", opts.StackName))
 	b.WriteString(fmt.Sprintf("- stage_id: %q\n", opts.StageID))
 	b.WriteString(fmt.Sprintf("- phase: %q\n", opts.Phase))
 	b.WriteString("- summary: a brief summary of what you did\n")
+	if opts.Phase == "ai_review" {
+		b.WriteString("- outcome: \"approved\", or \"changes_requested\" if the implementation must be reworked rather than fixed in review\n")
+		b.WriteString("- findings: what must change, required when outcome is \"changes_requested\"\n")
+	}
 
 	return b.String()
 }
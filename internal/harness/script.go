@@ -0,0 +1,110 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/config"
+	"github.com/mlawd/m-cli/internal/errs"
+)
+
+// Built-in recipes for phases without a configured script. They use the
+// M_* environment rather than template values so names need no quoting.
+const (
+	defaultImplementingScript = `set -e
+mkdir -p .m-script
+echo "$M_STAGE $M_PHASE" >> ".m-script/$M_STAGE.txt"
+git add .m-script
+git commit -q -m "$M_STAGE: scripted change"
+"$M_BIN" stage report implementing "$M_STAGE" --stack "$M_STACK" --summary "Scripted change for $M_STAGE"
+`
+	defaultAIReviewScript = `set -e
+"$M_BIN" stage report ai_review "$M_STAGE" --stack "$M_STACK" --summary "Scripted review of $M_STAGE"
+`
+)
+
+// ScriptHarness runs the scripts in config.ScriptHarness in place of AI
+// agents. The system prompt is written to the script's stdin.
+type ScriptHarness struct {
+	Config *config.Config
+}
+
+func (h *ScriptHarness) SpawnBuildAgent(ctx context.Context, opts AgentOpts) (*Process, error) {
+	agentName, model := resolveAgent(h.Config, "build", opts)
+	return h.spawn(ctx, agentName, model, opts)
+}
+
+func (h *ScriptHarness) SpawnReviewAgent(ctx context.Context, opts AgentOpts) (*Process, error) {
+	agentName, model := resolveAgent(h.Config, "review", opts)
+	return h.spawn(ctx, agentName, model, opts)
+}
+
+func (h *ScriptHarness) spawn(ctx context.Context, agentName, model string, opts AgentOpts) (*Process, error) {
+	shell, err := exec.LookPath(scriptShell(h.Config))
+	if err != nil {
+		return nil, errs.New(errs.AgentMissing, "%s not found in PATH", scriptShell(h.Config))
+	}
+
+	vars := CommandVars{
+		Agent:     agentName,
+		Model:     model,
+		Worktree:  opts.WorktreePath,
+		StackName: opts.StackName,
+		StageID:   opts.StageID,
+		Phase:     opts.Phase,
+		M:         mBinary(),
+	}
+	script, err := renderCommandTemplate(opts.Phase, h.script(opts.Phase), vars)
+	if err != nil {
+		return nil, fmt.Errorf("render %s script: %w", opts.Phase, err)
+	}
+
+	cmd := exec.CommandContext(ctx, shell, "-c", script)
+	cmd.Env = append(os.Environ(),
+		"M_BIN="+vars.M,
+		"M_STACK="+vars.StackName,
+		"M_STAGE="+vars.StageID,
+		"M_PHASE="+vars.Phase,
+		"M_WORKTREE="+vars.Worktree,
+		"M_AGENT="+vars.Agent,
+		"M_MODEL="+vars.Model,
+	)
+	cmd.Stdin = strings.NewReader(opts.SystemPrompt)
+
+	proc, err := startAgentCmd(cmd, opts)
+	if err != nil {
+		return nil, fmt.Errorf("spawn %s script: %w", opts.Phase, err)
+	}
+	// The script body is noise in status output.
+	proc.Command = []string{shell, "-c", "<" + opts.Phase + " script>"}
+
+	return proc, nil
+}
+
+func (h *ScriptHarness) script(phase string) string {
+	var implementing, review string
+	if h.Config.Script != nil {
+		implementing, review = h.Config.Script.Implementing, h.Config.Script.AIReview
+	}
+
+	if phase == "ai_review" {
+		if strings.TrimSpace(review) == "" {
+			return defaultAIReviewScript
+		}
+		return review
+	}
+	if strings.TrimSpace(implementing) == "" {
+		return defaultImplementingScript
+	}
+	return implementing
+}
+
+func scriptShell(cfg *config.Config) string {
+	if cfg.Script != nil && strings.TrimSpace(cfg.Script.Shell) != "" {
+		return cfg.Script.Shell
+	}
+	return "sh"
+}
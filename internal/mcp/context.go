@@ -116,18 +116,33 @@ func planningGuide() string {
 
 9) Run the automated implement -> review pipeline:
    - Configure the agent harness: m config set agent_harness opencode (or claude)
+   - Any other agent CLI can be used with agent_harness command: add a "command" section to config.json with
+     "args" (argv template using {{.Agent}}, {{.Model}}, {{.Prompt}}, {{.PromptFile}}, {{.Worktree}}, {{.StageID}}, ...),
+     optional "env", and "prompt": arg|stdin|file.
+   - To test the pipeline without an AI CLI, use agent_harness script: each phase runs a shell script
+     (config.json "script": {"implementing": ..., "ai_review": ...}; built-in recipes commit a change and
+     report done with m stage report).
    - Optionally set agent names: m config set agents.build build, m config set agents.review review
+   - Optionally pick models: m config set agents.build.model <model> (a plan stage can override it with model:)
    - Verify config: m config show
    - Start the pipeline: m stack run
    - Watch progress: m stack watch
    - Stages transition through: pending -> implementing -> ai-review -> human-review
    - Build and review agents are spawned automatically via report_stage_done.
+   - A reviewer that finds the implementation fundamentally wrong reports outcome "changes_requested" with findings;
+     the stage returns to implementing, up to max_review_rounds times (m config set max_review_rounds N); the next such review fails it.
+   - Agent PIDs are tracked per stage; an agent that exits without calling report_stage_done fails or flags its stage (see get_stack_run_status agent_lost).
+   - Agents over their phase timeout or idle window are terminated by m stack watch or m stack supervise;
+     the stage fails, or is restarted when timeouts.on_timeout is retry.
+   - A stage whose agent cannot be spawned is blocked. Recover failed or blocked stages with m stage retry, m stage reset or m stage skip.
 
 10) While planning agent work:
     - Prefer one stage-focused goal at a time.
     - Keep changes scoped to the selected stage.
     - For new plans, prefer version 3 and capture prompt-like stage context under "## Stage: <id>".
     - If no stage is selected, pick the earliest incomplete stage.
+    - An agent with the m MCP server can do steps 2-8 itself: create_stack, attach_plan, open_stage,
+      push_stage, push_stack and sync_stack (sync never prunes; opening a stage never launches an agent).
 
 11) Useful guardrails for agents:
    - Read inferred stack/stage before proposing edits.
@@ -170,14 +185,28 @@ Global stack override:
 - m stack push
   Push started stage branches in order with --force-with-lease and create PRs when missing.
 
-- m stack run
+- m stack run [--parallel N]
   Start the automated implement -> review pipeline for the current stack.
-  Transitions the first pending stage to implementing and spawns a build agent.
+  Transitions ready stages to implementing, each in its own worktree, and spawns a build agent for each.
+  --parallel N runs up to N independent stages at once; the report_stage_done cascade refills free slots.
   Requires configured agent harness (m config show). Run m stack watch to follow progress.
 
-- m stack watch
-  Watch the progress of a running stack pipeline.
-  Refreshes every 2 seconds showing per-stage status and elapsed time. Detach with ctrl-c; the pipeline continues in the background.
+- m stack watch [--plain]
+  Interactive dashboard for a running stack pipeline: stage list, detail pane (branch, worktree, PR URL, last summary, log tail)
+  and keys to open (o), retry (r), push (p), mark human-review stages done (d), pause/resume (space) and quit (q).
+  Redraws as soon as stack state changes and lists recent transitions. --plain (or non-terminal output) prints the status list, then one line per transition. Detach with q; the pipeline continues in the background.
+
+- m stack pause | m stack resume
+  Stop the pipeline from starting more stages, or resume it and start ready stages. get_stack_run_status reports paused.
+  Also enforces agent timeouts while running.
+
+- m stack supervise [--interval 30s]
+  Enforce agent timeouts without the dashboard until no stages are active.
+  Agents over timeouts.implementing / timeouts.ai_review, or idle (no commits or log output) for timeouts.idle,
+  are terminated and their stage failed, or retried up to timeouts.retries times when timeouts.on_timeout is retry.
+
+- m stack log [--stage <id>] [--limit N]
+  Print the stack's append-only event journal (.m/stacks/<stack>/events.jsonl): stage transitions with agent summaries, worktree creation, pushes, sync rebases, and agent spawns, each with timestamp and actor.
 
 - m stage list
   List stages for the current stack (requires an attached plan).
@@ -188,11 +217,30 @@ Global stack override:
 - m stage current
   Print the inferred stage id for the current stack.
 
+- m stage show [stage-id]
+  Show stage details and the latest implementation and review summaries reported via report_stage_done. Defaults to the current stage.
+
+- m stage report <implementing|ai_review> [stage-id] [--summary ...] [--outcome approved|changes_requested] [--findings ...]
+  Report an agent phase as done and advance the pipeline; the command-line equivalent of report_stage_done.
+
+- m stage logs [stage-id] [--phase implementing|ai_review] [--lines N] [--follow]
+  Print the tail of the latest captured agent log for a stage; logs live in .m/stacks/<stack>/<stage>/logs/<phase>-<n>.log.
+  Other agents can read the same tail from the m://stacks/{stack}/stages/{stage}/log resource.
+
+- m stage retry [stage-id]
+  Move a failed or blocked stage back to the phase it stopped in and respawn its agent.
+
+- m stage reset <stage-id> --to pending
+  Move a started stage that is not done back to pending so the pipeline starts it again.
+
+- m stage skip [stage-id] [--reason <text>]
+  Mark a pending, failed or blocked stage done without running it so dependent stages can start.
+
 - m stage open
   Open stage worktrees. Default is interactive stack/stage selection; use --next for next-stage flow or --stage <id> for explicit stage selection. Use --no-open to skip launching opencode.
   Stage worktrees are created under .m/stacks/<stack>/<stage>.
 
-- m stage push
+- m stage push [stage-id]
   Push the current stage branch and create a PR if an open one does not exist.
 
 - m worktree open <branch> [--base <branch>] [--path <dir>] [--no-open]
@@ -204,6 +252,12 @@ Global stack override:
 - m worktree prune
   Run git worktree prune, delete orphan directories under .m/worktrees/, and clear stale stage worktree references.
 
+- m state migrate [--dry-run]
+  Upgrade .m/stacks/index.json to the current schema version, keeping a backup of the original. --dry-run shows the changes without writing.
+
+- m state import-legacy [stack-name...] [--dry-run]
+  Import stacks from the legacy <git-common-dir>/m/stacks.json parts state as stacks with stages, reporting name or branch conflicts.
+
 - m prompt default
   Print the default MCP prompt from MCP_PROMPT.md.
 
@@ -211,7 +265,10 @@ Global stack override:
   Print resolved global config as JSON (~/.config/m/config.json).
 
 - m config set <key> <value>
-  Set a config value. Supported keys: agent_harness (opencode|claude), agents.<name> (agent name).
+  Set a config value. Supported keys: agent_harness (opencode|claude|command|script), max_review_rounds (non-negative integer),
+  timeouts.implementing / timeouts.ai_review / timeouts.idle (duration such as 90m; 0 disables), timeouts.on_timeout (fail|retry),
+  timeouts.retries (positive integer), agents.<name> (agent name),
+  agents.<name>.model (model passed to the harness; empty clears it).
 `)
 }
 
@@ -251,13 +308,27 @@ Version-specific requirements:
     (prompt-like details such as defaults, interactions, assumptions, constraints).
   - Every declared stage must have a non-empty stage context section.
 
+- version: 4 (dependency graph)
+  - Same as version 3, plus an optional depends_on list of stage ids per stage.
+  - Stages without depends_on start from the default branch; a stage is ready
+    once every stage it depends on is in human-review or done.
+  - Stages with several dependencies are built on an integration branch that
+    merges them.
+
+Optional per-stage fields (any version):
+- timeouts: map overriding the global agent timeouts for this stage, with keys
+  implementing, ai_review and idle and duration values (e.g. 4h, 45m; 0 disables).
+- model: model for this stage's build and review agents, overriding agents.<name>.model.
+
 Validation rules enforced by m:
-- plan version must be 2 or 3
+- plan version must be 2, 3 or 4
 - at least one stage is required
 - stage ids must match the regex above
 - stage ids must be unique
 - each stage must include all required fields for its version
-- for version 3, markdown stage sections must map to declared stage ids
+- for version 3 and 4, markdown stage sections must map to declared stage ids
+- depends_on is only allowed in version 4, must name declared stage ids, and must not form a cycle
+- timeouts keys must be implementing, ai_review or idle with non-negative durations
 
 Example (version 3):
 
@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// StreamableHTTPPath serves the streamable HTTP transport.
+	StreamableHTTPPath = "/mcp"
+	// SSEPath and SSEMessagePath serve the older HTTP+SSE transport.
+	SSEPath        = "/sse"
+	SSEMessagePath = "/message"
+)
+
+// HTTPOptions configures ServeHTTP.
+type HTTPOptions struct {
+	Version string
+	// Token, if set, must be sent by clients as "Authorization: Bearer <token>".
+	Token string
+}
+
+// ServeHTTP serves the MCP server on listener until ctx is done, with the
+// streamable HTTP transport at StreamableHTTPPath and HTTP+SSE at SSEPath.
+// One server can be shared by every agent working in the repository
+// containing the current directory.
+func ServeHTTP(ctx context.Context, listener net.Listener, opts HTTPOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	srv := newServer(opts.Version)
+	subs := newResourceSubscriptions()
+	notifyStateChanges(ctx, srv, subs, ".")
+
+	httpServer := &http.Server{
+		Handler:           httpHandler(srv, subs, opts.Token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+		defer stop()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func httpHandler(srv *mcpserver.MCPServer, subs *resourceSubscriptions, token string) http.Handler {
+	// Tool calls can spawn agents that must outlive the request.
+	detach := func(ctx context.Context, r *http.Request) context.Context {
+		return context.WithoutCancel(ctx)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(StreamableHTTPPath, mcpserver.NewStreamableHTTPServer(srv,
+		mcpserver.WithEndpointPath(StreamableHTTPPath),
+		mcpserver.WithHTTPContextFunc(detach),
+	))
+	sse := mcpserver.NewSSEServer(srv,
+		mcpserver.WithSSEEndpoint(SSEPath),
+		mcpserver.WithMessageEndpoint(SSEMessagePath),
+	)
+	mux.Handle(SSEPath, sse)
+	mux.Handle(SSEMessagePath, sse)
+
+	return requireBearerToken(token, rewriteSubscriptions(subs, mux))
+}
+
+// rewriteSubscriptions passes posted messages through subs.rewrite. Streamable
+// HTTP names the session in a header, SSE in the query string.
+func rewriteSubscriptions(subs *resourceSubscriptions, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sessionID := r.Header.Get(mcpserver.HeaderKeySessionID)
+		if sessionID == "" {
+			sessionID = r.URL.Query().Get("sessionId")
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "read request body", http.StatusBadRequest)
+			return
+		}
+		body = subs.rewrite(sessionID, body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(strings.TrimSpace(r.Header.Get("Authorization")))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="m"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HTTPURL is the streamable HTTP endpoint clients on this host should use
+// for a server listening on addr.
+func HTTPURL(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "http://" + addr.String() + StreamableHTTPPath
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port) + StreamableHTTPPath
+}
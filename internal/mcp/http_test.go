@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+func TestHTTPHandlerRequiresTokenAndSupportsSubscriptions(t *testing.T) {
+	t.Chdir(t.TempDir())
+	subs := newResourceSubscriptions()
+	server := httptest.NewServer(httpHandler(newServer("test"), subs, "secret"))
+	defer server.Close()
+
+	post := func(token, sessionID, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, server.URL+StreamableHTTPPath, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if sessionID != "" {
+			req.Header.Set(mcpserver.HeaderKeySessionID, sessionID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`
+	if resp := post("wrong", "", initialize); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status with wrong token = %d, want 401", resp.StatusCode)
+	}
+
+	resp := post("secret", "", initialize)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("initialize status = %d", resp.StatusCode)
+	}
+	sessionID := resp.Header.Get(mcpserver.HeaderKeySessionID)
+	if sessionID == "" {
+		t.Fatal("initialize did not return a session id")
+	}
+
+	resp = post("secret", sessionID, `{"jsonrpc":"2.0","id":2,"method":"resources/subscribe","params":{"uri":"m://state/context"}}`)
+	var result struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decode subscribe response: %v", err)
+	}
+	if result.ID != 2 || result.Error != nil || result.Result == nil {
+		t.Fatalf("subscribe response = %+v", result)
+	}
+	if got := subs.subscribers(stateContextURI); len(got) != 1 || got[0] != sessionID {
+		t.Fatalf("subscribers = %v, want [%s]", got, sessionID)
+	}
+}
@@ -0,0 +1,307 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
+
+	mmcp "github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// registerLifecycleTools exposes the stack and stage commands an agent needs
+// to turn a plan into a live stack. They run the same workflow code as the
+// CLI; opening a stage never launches an agent and sync never prunes.
+func registerLifecycleTools(srv *mcpserver.MCPServer) {
+	srv.AddTool(
+		mmcp.NewTool(
+			"create_stack",
+			mmcp.WithDescription("Create a stack, optionally with stages from a markdown plan file (like `m stack new`)"),
+			mmcp.WithString("stack_name", mmcp.Description("Name of the new stack"), mmcp.Required()),
+			mmcp.WithString("type", mmcp.Description("Stack type: feat, fix or chore")),
+			mmcp.WithString("plan_file", mmcp.Description("Markdown plan file, absolute or relative to the server's working directory")),
+		),
+		handleCreateStack,
+	)
+
+	srv.AddTool(
+		mmcp.NewTool(
+			"attach_plan",
+			mmcp.WithDescription("Attach a markdown plan file to a stack that has none (like `m stack attach-plan`)"),
+			mmcp.WithString("stack_name", mmcp.Description("Name of the stack"), mmcp.Required()),
+			mmcp.WithString("plan_file", mmcp.Description("Markdown plan file, absolute or relative to the server's working directory"), mmcp.Required()),
+		),
+		handleAttachPlan,
+	)
+
+	srv.AddTool(
+		mmcp.NewTool(
+			"open_stage",
+			mmcp.WithDescription("Create a stage's branch and worktree if missing and make it the current stage, without launching an agent (like `m stage open --no-open`)"),
+			mmcp.WithString("stack_name", mmcp.Description("Name of the stack"), mmcp.Required()),
+			mmcp.WithString("stage_id", mmcp.Description("ID of the stage"), mmcp.Required()),
+		),
+		handleOpenStage,
+	)
+
+	srv.AddTool(
+		mmcp.NewTool(
+			"push_stage",
+			mmcp.WithDescription("Push a stage branch, and earlier stages not yet on origin, creating or updating their PRs (like `m stage push`)"),
+			mmcp.WithString("stack_name", mmcp.Description("Name of the stack"), mmcp.Required()),
+			mmcp.WithString("stage_id", mmcp.Description("ID of the stage"), mmcp.Required()),
+		),
+		handlePushStage,
+	)
+
+	srv.AddTool(
+		mmcp.NewTool(
+			"push_stack",
+			mmcp.WithDescription("Force-push (with lease) every started stage branch and create or update their PRs (like `m stack push`)"),
+			mmcp.WithString("stack_name", mmcp.Description("Name of the stack"), mmcp.Required()),
+		),
+		handlePushStack,
+	)
+
+	srv.AddTool(
+		mmcp.NewTool(
+			"sync_stack",
+			mmcp.WithDescription("Rebase started stage branches onto their parents in dependency order, keeping merged stages (like `m stack sync --no-prune`)"),
+			mmcp.WithString("stack_name", mmcp.Description("Name of the stack"), mmcp.Required()),
+		),
+		handleSyncStack,
+	)
+}
+
+func handleCreateStack(ctx context.Context, request mmcp.CallToolRequest) (*mmcp.CallToolResult, error) {
+	stackName, err := request.RequireString("stack_name")
+	if err != nil {
+		return nil, err
+	}
+
+	repoRoot, err := sharedRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	stack, err := lifecycleService(ctx, request, repoRoot, "mcp:create_stack").CreateStack(workflow.NewStackOptions{
+		Name:     stackName,
+		Type:     request.GetString("type", ""),
+		PlanFile: request.GetString("plan_file", ""),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stackResult(stack)
+}
+
+func handleAttachPlan(ctx context.Context, request mmcp.CallToolRequest) (*mmcp.CallToolResult, error) {
+	stackName, err := request.RequireString("stack_name")
+	if err != nil {
+		return nil, err
+	}
+	planFile, err := request.RequireString("plan_file")
+	if err != nil {
+		return nil, err
+	}
+
+	repoRoot, err := sharedRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	stack, err := lifecycleService(ctx, request, repoRoot, "mcp:attach_plan").AttachPlan(strings.TrimSpace(stackName), planFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return stackResult(stack)
+}
+
+func handleOpenStage(ctx context.Context, request mmcp.CallToolRequest) (*mmcp.CallToolResult, error) {
+	stackName, err := request.RequireString("stack_name")
+	if err != nil {
+		return nil, err
+	}
+	stageID, err := request.RequireString("stage_id")
+	if err != nil {
+		return nil, err
+	}
+
+	repoRoot, err := sharedRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	opened, err := lifecycleService(ctx, request, repoRoot, "mcp:open_stage").OpenStage(strings.TrimSpace(stackName), strings.TrimSpace(stageID))
+	if err != nil {
+		return nil, err
+	}
+
+	return structuredResult(opened)
+}
+
+func handlePushStage(ctx context.Context, request mmcp.CallToolRequest) (*mmcp.CallToolResult, error) {
+	stackName, err := request.RequireString("stack_name")
+	if err != nil {
+		return nil, err
+	}
+	stageID, err := request.RequireString("stage_id")
+	if err != nil {
+		return nil, err
+	}
+
+	repoRoot, err := sharedRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+	stack, err := loadStackWithPlan(repoRoot, stackName)
+	if err != nil {
+		return nil, err
+	}
+	stageID = strings.TrimSpace(stageID)
+	_, stageIndex := state.FindStage(stack, stageID)
+	if stageIndex < 0 {
+		return nil, fmt.Errorf("stage %q not found in stack %q", stageID, stack.Name)
+	}
+
+	result, err := lifecycleService(ctx, request, repoRoot, "mcp:push_stage").PushStage(stack, stageIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return structuredResult(result)
+}
+
+func handlePushStack(ctx context.Context, request mmcp.CallToolRequest) (*mmcp.CallToolResult, error) {
+	stackName, err := request.RequireString("stack_name")
+	if err != nil {
+		return nil, err
+	}
+
+	repoRoot, err := sharedRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+	stack, err := loadStackWithPlan(repoRoot, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := lifecycleService(ctx, request, repoRoot, "mcp:push_stack").PushStack(stack)
+	if err != nil {
+		return nil, err
+	}
+
+	return structuredResult(result)
+}
+
+func handleSyncStack(ctx context.Context, request mmcp.CallToolRequest) (*mmcp.CallToolResult, error) {
+	stackName, err := request.RequireString("stack_name")
+	if err != nil {
+		return nil, err
+	}
+
+	repoRoot, err := sharedRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+	stack, err := loadStackWithPlan(repoRoot, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := lifecycleService(ctx, request, repoRoot, "mcp:sync_stack").SyncStack(stack.Name, workflow.SyncOptions{NoPrune: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return structuredResult(result)
+}
+
+// sharedRepoRoot is the main worktree of the repository containing the
+// server's working directory, where m keeps its state.
+func sharedRepoRoot() (string, error) {
+	repo, err := gitx.DiscoverRepo(".")
+	if err != nil {
+		return "", fmt.Errorf("discover repo: %w", err)
+	}
+
+	return gitx.SharedRoot(repo.TopLevel, repo.CommonDir), nil
+}
+
+// lifecycleService runs workflow operations as actor, reporting each step to
+// the client as a progress notification when the request asked for them.
+func lifecycleService(ctx context.Context, request mmcp.CallToolRequest, repoRoot, actor string) *workflow.Service {
+	service := &workflow.Service{RepoRoot: repoRoot, Actor: actor}
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return service
+	}
+
+	srv := mcpserver.ServerFromContext(ctx)
+	if srv == nil {
+		return service
+	}
+
+	token := request.Params.Meta.ProgressToken
+	progress := 0
+	service.Reporter = workflow.ReporterFunc(func(p workflow.Progress) {
+		progress++
+		_ = srv.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": token,
+			"progress":      progress,
+			"message":       p.Message,
+		})
+	})
+	return service
+}
+
+func loadStackWithPlan(repoRoot, stackName string) (*state.Stack, error) {
+	stackName = strings.TrimSpace(stackName)
+
+	stacks, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("load stacks: %w", err)
+	}
+
+	stack, _ := state.FindStack(stacks, stackName)
+	if stack == nil {
+		return nil, state.StackNotFoundError(stackName)
+	}
+	if strings.TrimSpace(stack.PlanFile) == "" || len(stack.Stages) == 0 {
+		return nil, errs.New(errs.NoPlan, "no plan attached to stack %q; call attach_plan first", stackName)
+	}
+
+	return stack, nil
+}
+
+func stackResult(stack *state.Stack) (*mmcp.CallToolResult, error) {
+	stageIDs := make([]string, 0, len(stack.Stages))
+	for _, stage := range stack.Stages {
+		stageIDs = append(stageIDs, stage.ID)
+	}
+
+	return structuredResult(map[string]interface{}{
+		"stack_name":       stack.Name,
+		"stack_type":       stack.Type,
+		"plan_file":        stack.PlanFile,
+		"dependency_graph": stack.DependencyGraph,
+		"stages":           stageIDs,
+	})
+}
+
+func structuredResult(result any) (*mmcp.CallToolResult, error) {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return mmcp.NewToolResultStructured(result, string(data)), nil
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mmcp "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
+)
+
+func TestLifecycleToolsTurnAPlanIntoAStack(t *testing.T) {
+	repoRoot := initGitRepoWithMainCommit(t)
+	t.Chdir(repoRoot)
+
+	plan := `---
+version: 3
+title: Checkout
+stages:
+  - id: api
+    title: API
+  - id: ui
+    title: UI
+---
+
+## Stage: api
+Add the checkout endpoint.
+
+## Stage: ui
+Add the checkout page.
+`
+	if err := os.WriteFile(filepath.Join(repoRoot, "plan.md"), []byte(plan), 0o644); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+
+	call := func(handler func(context.Context, mmcp.CallToolRequest) (*mmcp.CallToolResult, error), args map[string]any) *mmcp.CallToolResult {
+		t.Helper()
+		var request mmcp.CallToolRequest
+		request.Params.Arguments = args
+		result, err := handler(context.Background(), request)
+		if err != nil {
+			t.Fatalf("tool call with %v: %v", args, err)
+		}
+		return result
+	}
+
+	call(handleCreateStack, map[string]any{"stack_name": "checkout", "type": "feat"})
+	if _, err := handleOpenStage(context.Background(), mmcp.CallToolRequest{Params: mmcp.CallToolParams{Arguments: map[string]any{"stack_name": "checkout", "stage_id": "api"}}}); err == nil {
+		t.Fatal("open_stage before attach_plan succeeded, want error")
+	}
+
+	call(handleAttachPlan, map[string]any{"stack_name": "checkout", "plan_file": "plan.md"})
+
+	opened := call(handleOpenStage, map[string]any{"stack_name": "checkout", "stage_id": "api"}).StructuredContent.(*workflow.OpenedStage)
+	if !opened.CreatedBranch || !opened.CreatedWorktree || opened.Branch != "checkout/1/api" || opened.Parent != "main" {
+		t.Fatalf("open_stage = %+v", opened)
+	}
+	if _, err := os.Stat(opened.Worktree); err != nil {
+		t.Fatalf("stage worktree: %v", err)
+	}
+
+	synced := call(handleSyncStack, map[string]any{"stack_name": "checkout"}).StructuredContent.(*workflow.SyncResult)
+	if len(synced.Rebased) != 1 || synced.Rebased[0].Stage != "api" || synced.Rebased[0].Onto != "main" {
+		t.Fatalf("sync_stack = %+v", synced)
+	}
+
+	stacks, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatalf("LoadStacks: %v", err)
+	}
+	stack, _ := state.FindStack(stacks, "checkout")
+	if stack == nil || stack.Type != "feat" || len(stack.Stages) != 2 || stack.CurrentStage != "api" {
+		t.Fatalf("stack = %+v", stack)
+	}
+	if !gitx.BranchExists(repoRoot, "checkout/1/api") {
+		t.Fatal("stage branch was not created")
+	}
+
+	events, err := state.LoadEvents(repoRoot, "checkout")
+	if err != nil {
+		t.Fatalf("LoadEvents: %v", err)
+	}
+	actors := map[string]bool{}
+	for _, event := range events {
+		actors[event.Actor] = true
+	}
+	for _, actor := range []string{"mcp:open_stage", "mcp:sync_stack"} {
+		if !actors[actor] {
+			t.Fatalf("journal actors = %v, want %s", actors, actor)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
+
+	mmcp "github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	stageLogTemplate  = "m://stacks/{stack}/stages/{stage}/log"
+	stageLogTailLines = 200
+)
+
+func readStageLogResource(uri string, args map[string]any) ([]mmcp.ResourceContents, error) {
+	stackName := templateArg(args, "stack")
+	stageID := templateArg(args, "stage")
+	if stackName == "" || stageID == "" {
+		return nil, fmt.Errorf("stage log uri must look like %s", stageLogTemplate)
+	}
+
+	repo, err := gitx.DiscoverRepo(".")
+	if err != nil {
+		return nil, fmt.Errorf("discover repo: %w", err)
+	}
+	repoRoot := gitx.SharedRoot(repo.TopLevel, repo.CommonDir)
+
+	text, err := stageLogTail(repoRoot, stackName, stageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return []mmcp.ResourceContents{
+		mmcp.TextResourceContents{URI: uri, MIMEType: "text/plain", Text: text},
+	}, nil
+}
+
+// stageLogTail returns a header naming the latest agent log for a stage
+// followed by its last lines.
+func stageLogTail(repoRoot, stackName, stageID string) (string, error) {
+	stacks, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		return "", fmt.Errorf("load stacks: %w", err)
+	}
+	stack, _ := state.FindStack(stacks, stackName)
+	if stack == nil {
+		return "", state.StackNotFoundError(stackName)
+	}
+	if stage, _ := state.FindStage(stack, stageID); stage == nil {
+		return "", fmt.Errorf("stage %q not found in stack %q", stageID, stackName)
+	}
+
+	log, err := workflow.LatestStageLog(repoRoot, stackName, stageID, "")
+	if err != nil {
+		return "", err
+	}
+	if log == nil {
+		return fmt.Sprintf("No agent logs captured for stage %q.\n", stageID), nil
+	}
+
+	tail, err := workflow.TailFile(log.Path, stageLogTailLines)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("# %s run %d (%s)\n%s", log.Phase, log.Run, log.Path, tail), nil
+}
+
+// stageLogURI returns the log resource URI for a stage.
+func stageLogURI(stackName, stageID string) string {
+	return "m://stacks/" + stackName + "/stages/" + stageID + "/log"
+}
+
+// parseStageLogURI splits a URI built from stageLogTemplate.
+func parseStageLogURI(uri string) (string, string, error) {
+	parts := strings.Split(strings.TrimPrefix(uri, "m://"), "/")
+	if !strings.HasPrefix(uri, "m://") || len(parts) != 5 || parts[0] != "stacks" || parts[2] != "stages" || parts[4] != "log" || parts[1] == "" || parts[3] == "" {
+		return "", "", fmt.Errorf("stage log uri must look like %s", stageLogTemplate)
+	}
+	return parts[1], parts[3], nil
+}
+
+// templateArg returns a variable matched from a resource URI template.
+func templateArg(args map[string]any, name string) string {
+	switch value := args[name].(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case []string:
+		if len(value) > 0 {
+			return strings.TrimSpace(value[0])
+		}
+	}
+	return ""
+}
@@ -7,10 +7,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/mlawd/m-cli/internal/config"
 	"github.com/mlawd/m-cli/internal/gitx"
-	"github.com/mlawd/m-cli/internal/harness"
 	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
 
 	mmcp "github.com/mark3labs/mcp-go/mcp"
 	mcpserver "github.com/mark3labs/mcp-go/server"
@@ -25,6 +24,8 @@ func registerOrchestrationTools(srv *mcpserver.MCPServer) {
 			mmcp.WithString("stage_id", mmcp.Description("ID of the stage"), mmcp.Required()),
 			mmcp.WithString("phase", mmcp.Description("Phase that completed: implementing or ai_review"), mmcp.Required()),
 			mmcp.WithString("summary", mmcp.Description("Optional summary of work done")),
+			mmcp.WithString("outcome", mmcp.Description("Review outcome for the ai_review phase: approved (default) or changes_requested, which sends the stage back to implementing")),
+			mmcp.WithString("findings", mmcp.Description("Review findings for the build agent; required when outcome is changes_requested")),
 		),
 		handleReportStageDone,
 	)
@@ -53,81 +54,25 @@ func handleReportStageDone(ctx context.Context, request mmcp.CallToolRequest) (*
 		return nil, err
 	}
 
-	stackName = strings.TrimSpace(stackName)
-	stageID = strings.TrimSpace(stageID)
-	phase = strings.TrimSpace(phase)
-
-	if phase != "implementing" && phase != "ai_review" {
-		return nil, fmt.Errorf("phase must be \"implementing\" or \"ai_review\", got %q", phase)
-	}
-
-	repo, err := gitx.DiscoverRepo(".")
+	repoRoot, err := sharedRepoRoot()
 	if err != nil {
-		return nil, fmt.Errorf("discover repo: %w", err)
+		return nil, err
 	}
-	repoRoot := gitx.SharedRoot(repo.TopLevel, repo.CommonDir)
 
-	stacks, err := state.LoadStacks(repoRoot)
+	service := &workflow.Service{RepoRoot: repoRoot, Actor: "mcp:report_stage_done"}
+	message, err := service.ReportStageDone(ctx, workflow.PhaseReport{
+		StackName: stackName,
+		StageID:   stageID,
+		Phase:     phase,
+		Summary:   request.GetString("summary", ""),
+		Outcome:   request.GetString("outcome", ""),
+		Findings:  request.GetString("findings", ""),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("load stacks: %w", err)
-	}
-
-	switch phase {
-	case "implementing":
-		if err := state.TransitionStage(stacks, stackName, stageID, state.StatusAIReview); err != nil {
-			return nil, err
-		}
-
-		if err := state.SaveStacks(repoRoot, stacks); err != nil {
-			return nil, fmt.Errorf("save stacks: %w", err)
-		}
-
-		// Spawn review agent
-		if err := spawnReviewAgent(ctx, repoRoot, stacks, stackName, stageID); err != nil {
-			return mmcp.NewToolResultText(fmt.Sprintf("Stage transitioned to ai-review but failed to spawn review agent: %v", err)), nil
-		}
-
-		return mmcp.NewToolResultText(fmt.Sprintf("Stage %q transitioned to ai-review. Review agent spawned.", stageID)), nil
-
-	case "ai_review":
-		if err := state.TransitionStage(stacks, stackName, stageID, state.StatusHumanReview); err != nil {
-			return nil, err
-		}
-
-		if err := state.SaveStacks(repoRoot, stacks); err != nil {
-			return nil, fmt.Errorf("save stacks: %w", err)
-		}
-
-		stack, _ := state.FindStack(stacks, stackName)
-		if stack == nil {
-			return mmcp.NewToolResultText(fmt.Sprintf("Stage %q transitioned to human-review.", stageID)), nil
-		}
-
-		// Find next pending stage and start it
-		next := state.NextPendingStage(stack)
-		if next == nil {
-			if state.AllStagesComplete(stack) {
-				return mmcp.NewToolResultText(fmt.Sprintf("Stage %q transitioned to human-review. All stages complete.", stageID)), nil
-			}
-			return mmcp.NewToolResultText(fmt.Sprintf("Stage %q transitioned to human-review. No more pending stages.", stageID)), nil
-		}
-
-		if err := state.TransitionStage(stacks, stackName, next.ID, state.StatusImplementing); err != nil {
-			return mmcp.NewToolResultText(fmt.Sprintf("Stage %q transitioned to human-review but failed to start next stage: %v", stageID, err)), nil
-		}
-
-		if err := state.SaveStacks(repoRoot, stacks); err != nil {
-			return nil, fmt.Errorf("save stacks: %w", err)
-		}
-
-		if err := spawnBuildAgent(ctx, repoRoot, stacks, stackName, next.ID); err != nil {
-			return mmcp.NewToolResultText(fmt.Sprintf("Stage %q -> human-review. Next stage %q -> implementing but failed to spawn build agent: %v", stageID, next.ID, err)), nil
-		}
-
-		return mmcp.NewToolResultText(fmt.Sprintf("Stage %q -> human-review. Next stage %q -> implementing. Build agent spawned.", stageID, next.ID)), nil
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("unexpected phase: %s", phase)
+	return mmcp.NewToolResultText(message), nil
 }
 
 func handleGetStackRunStatus(ctx context.Context, request mmcp.CallToolRequest) (*mmcp.CallToolResult, error) {
@@ -150,19 +95,29 @@ func handleGetStackRunStatus(ctx context.Context, request mmcp.CallToolRequest)
 
 	stack, _ := state.FindStack(stacks, stackName)
 	if stack == nil {
-		return nil, fmt.Errorf("stack %q not found", stackName)
+		return nil, state.StackNotFoundError(stackName)
 	}
 
 	type stageStatus struct {
-		ID     string `json:"id"`
-		Title  string `json:"title"`
-		Status string `json:"status"`
-		Elapsed string `json:"elapsed,omitempty"`
+		ID                    string `json:"id"`
+		Title                 string `json:"title"`
+		Status                string `json:"status"`
+		Elapsed               string `json:"elapsed,omitempty"`
+		ImplementationSummary string `json:"implementation_summary,omitempty"`
+		ReviewSummary         string `json:"review_summary,omitempty"`
+		ReviewRounds          int    `json:"review_rounds,omitempty"`
+		StatusReason          string `json:"status_reason,omitempty"`
+		AgentPID              int    `json:"agent_pid,omitempty"`
+		AgentRunning          bool   `json:"agent_running,omitempty"`
+		AgentExitCode         *int   `json:"agent_exit_code,omitempty"`
+		AgentLost             string `json:"agent_lost,omitempty"`
 	}
 
 	stages := make([]stageStatus, 0, len(stack.Stages))
-	activeStageID := ""
+	activeStageIDs := []string{}
 	allDone := true
+	stoppedStatus := ""
+	lostStages := []string{}
 
 	for i := range stack.Stages {
 		s := &stack.Stages[i]
@@ -170,7 +125,7 @@ func handleGetStackRunStatus(ctx context.Context, request mmcp.CallToolRequest)
 		elapsed := ""
 
 		if status == state.StatusImplementing || status == state.StatusAIReview {
-			activeStageID = s.ID
+			activeStageIDs = append(activeStageIDs, s.ID)
 			if s.StartedAt != "" {
 				if t, err := time.Parse(time.RFC3339, s.StartedAt); err == nil {
 					elapsed = time.Since(t).Truncate(time.Second).String()
@@ -181,30 +136,68 @@ func handleGetStackRunStatus(ctx context.Context, request mmcp.CallToolRequest)
 		if status != state.StatusHumanReview && status != state.StatusDone {
 			allDone = false
 		}
+		if status == state.StatusFailed || (status == state.StatusBlocked && stoppedStatus == "") {
+			stoppedStatus = status
+		}
 
-		stages = append(stages, stageStatus{
-			ID:      s.ID,
-			Title:   s.Title,
-			Status:  status,
-			Elapsed: elapsed,
-		})
+		entry := stageStatus{
+			ID:           s.ID,
+			Title:        s.Title,
+			Status:       status,
+			Elapsed:      elapsed,
+			ReviewRounds: s.ReviewRounds,
+			StatusReason: s.StatusReason,
+		}
+		if s.Agent != nil {
+			entry.AgentPID = s.Agent.PID
+			entry.AgentRunning = state.AgentRunning(s.Agent)
+			entry.AgentExitCode = s.Agent.ExitCode
+		}
+		if lost, reason := state.AgentLost(s); lost {
+			entry.AgentLost = reason
+			lostStages = append(lostStages, s.ID)
+		}
+		if report := state.LatestReport(s, state.PhaseImplementing); report != nil {
+			entry.ImplementationSummary = report.Summary
+		}
+		if report := state.LatestReport(s, state.PhaseAIReview); report != nil {
+			entry.ReviewSummary = report.Summary
+		}
+		stages = append(stages, entry)
 	}
 
 	stackStatus := "running"
 	if allDone {
 		stackStatus = "complete"
 	}
-	if activeStageID == "" && !allDone {
+	if len(activeStageIDs) == 0 && !allDone {
 		stackStatus = "idle"
+		if stoppedStatus != "" {
+			stackStatus = stoppedStatus
+		}
+	}
+	if len(activeStageIDs) > 0 && len(lostStages) == len(activeStageIDs) {
+		// Every active stage is waiting on an agent that is gone.
+		stackStatus = "agent_lost"
+	}
+
+	// active_stage is kept for callers that predate parallel runs.
+	activeStageID := ""
+	if len(activeStageIDs) > 0 {
+		activeStageID = activeStageIDs[0]
 	}
 
 	result := map[string]interface{}{
-		"stack_name":     stackName,
-		"stack_type":     stack.Type,
-		"stack_status":   stackStatus,
-		"active_stage":   activeStageID,
-		"total_stages":   len(stack.Stages),
-		"stages":         stages,
+		"stack_name":    stackName,
+		"stack_type":    stack.Type,
+		"stack_status":  stackStatus,
+		"active_stage":  activeStageID,
+		"active_stages": activeStageIDs,
+		"parallel":      max(stack.Parallel, 1),
+		"paused":        stack.Paused,
+		"lost_stages":   lostStages,
+		"total_stages":  len(stack.Stages),
+		"stages":        stages,
 	}
 
 	data, err := json.MarshalIndent(result, "", "  ")
@@ -214,79 +207,3 @@ func handleGetStackRunStatus(ctx context.Context, request mmcp.CallToolRequest)
 
 	return mmcp.NewToolResultStructured(result, string(data)), nil
 }
-
-func spawnBuildAgent(ctx context.Context, repoRoot string, stacks *state.Stacks, stackName, stageID string) error {
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("load config: %w", err)
-	}
-
-	h, err := harness.ForConfig(cfg)
-	if err != nil {
-		return err
-	}
-
-	stack, _ := state.FindStack(stacks, stackName)
-	if stack == nil {
-		return fmt.Errorf("stack %q not found", stackName)
-	}
-
-	stage, _ := state.FindStage(stack, stageID)
-	if stage == nil {
-		return fmt.Errorf("stage %q not found", stageID)
-	}
-
-	worktreePath := stage.Worktree
-	if worktreePath == "" {
-		worktreePath = repoRoot
-	}
-
-	opts := harness.AgentOpts{
-		WorktreePath: worktreePath,
-		StageContext: stage.Context,
-		StackName:    stackName,
-		StageID:      stageID,
-		Phase:        "implementing",
-	}
-	opts.SystemPrompt = harness.BuildSystemPrompt(opts)
-
-	return h.SpawnBuildAgent(ctx, opts)
-}
-
-func spawnReviewAgent(ctx context.Context, repoRoot string, stacks *state.Stacks, stackName, stageID string) error {
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("load config: %w", err)
-	}
-
-	h, err := harness.ForConfig(cfg)
-	if err != nil {
-		return err
-	}
-
-	stack, _ := state.FindStack(stacks, stackName)
-	if stack == nil {
-		return fmt.Errorf("stack %q not found", stackName)
-	}
-
-	stage, _ := state.FindStage(stack, stageID)
-	if stage == nil {
-		return fmt.Errorf("stage %q not found", stageID)
-	}
-
-	worktreePath := stage.Worktree
-	if worktreePath == "" {
-		worktreePath = repoRoot
-	}
-
-	opts := harness.AgentOpts{
-		WorktreePath: worktreePath,
-		StageContext: stage.Context,
-		StackName:    stackName,
-		StageID:      stageID,
-		Phase:        "ai_review",
-	}
-	opts.SystemPrompt = harness.BuildSystemPrompt(opts)
-
-	return h.SpawnReviewAgent(ctx, opts)
-}
@@ -12,6 +12,21 @@ import (
 )
 
 func ServeStdio(ctx context.Context, in io.Reader, out io.Writer, version string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	srv := newServer(version)
+	subs := newResourceSubscriptions()
+	notifyStateChanges(ctx, srv, subs, ".")
+
+	filtered, next := io.Pipe()
+	go filterSubscriptions(subs, "stdio", in, next)
+
+	stdio := mcpserver.NewStdioServer(srv)
+	return stdio.Listen(ctx, filtered, out)
+}
+
+func newServer(version string) *mcpserver.MCPServer {
 	trimmedVersion := strings.TrimSpace(version)
 	if trimmedVersion == "" {
 		trimmedVersion = "dev"
@@ -20,8 +35,8 @@ func ServeStdio(ctx context.Context, in io.Reader, out io.Writer, version string
 	srv := mcpserver.NewMCPServer(
 		"m-cli-mcp",
 		trimmedVersion,
-		mcpserver.WithInstructions("Use resources for m workflow guidance and tools to inspect current stack/stage context."),
-		mcpserver.WithResourceCapabilities(false, false),
+		mcpserver.WithInstructions("Use resources for m workflow guidance and tools to inspect current stack/stage context and drive the stack and stage lifecycle."),
+		mcpserver.WithResourceCapabilities(true, true),
 		mcpserver.WithToolCapabilities(false),
 		mcpserver.WithPromptCapabilities(false),
 		mcpserver.WithRecovery(),
@@ -30,10 +45,10 @@ func ServeStdio(ctx context.Context, in io.Reader, out io.Writer, version string
 	registerResources(srv)
 	registerTools(srv)
 	registerOrchestrationTools(srv)
+	registerLifecycleTools(srv)
 	registerPrompts(srv)
 
-	stdio := mcpserver.NewStdioServer(srv)
-	return stdio.Listen(ctx, in, out)
+	return srv
 }
 
 func registerResources(srv *mcpserver.MCPServer) {
@@ -84,6 +99,18 @@ func registerResources(srv *mcpserver.MCPServer) {
 			return readResource(strings.TrimSpace(request.Params.URI))
 		},
 	)
+
+	srv.AddResourceTemplate(
+		mmcp.NewResourceTemplate(
+			stageLogTemplate,
+			"Stage Agent Log",
+			mmcp.WithTemplateDescription("Tail of the latest captured agent log for a stage"),
+			mmcp.WithTemplateMIMEType("text/plain"),
+		),
+		func(ctx context.Context, request mmcp.ReadResourceRequest) ([]mmcp.ResourceContents, error) {
+			return readStageLogResource(request.Params.URI, request.Params.Arguments)
+		},
+	)
 }
 
 func readResource(uri string) ([]mmcp.ResourceContents, error) {
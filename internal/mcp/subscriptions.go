@@ -0,0 +1,188 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	mmcp "github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+const stateContextURI = "m://state/context"
+
+// resourceSubscriptions tracks the resources each client session subscribed
+// to. mcp-go advertises the subscribe capability but does not route
+// resources/subscribe, so transports pass incoming messages through rewrite.
+type resourceSubscriptions struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]bool
+}
+
+func newResourceSubscriptions() *resourceSubscriptions {
+	return &resourceSubscriptions{sessions: map[string]map[string]bool{}}
+}
+
+// rewrite records a resources/subscribe or resources/unsubscribe request from
+// a session and returns a request the MCP server answers in its place: a
+// ping, whose empty result is also the subscribe result, or for an unknown
+// URI a resources/read that fails with "resource not found". Other messages
+// are returned unchanged.
+func (s *resourceSubscriptions) rewrite(sessionID string, message []byte) []byte {
+	var request struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			URI string `json:"uri"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(message, &request); err != nil || len(request.ID) == 0 {
+		return message
+	}
+	if request.Method != "resources/subscribe" && request.Method != "resources/unsubscribe" {
+		return message
+	}
+
+	uri := strings.TrimSpace(request.Params.URI)
+	replacement := map[string]any{"jsonrpc": mmcp.JSONRPC_VERSION, "id": request.ID, "method": "ping"}
+	if !subscribableURI(uri) {
+		replacement["method"] = "resources/read"
+		replacement["params"] = map[string]string{"uri": uri}
+	} else {
+		s.mu.Lock()
+		uris := s.sessions[sessionID]
+		if uris == nil {
+			uris = map[string]bool{}
+			s.sessions[sessionID] = uris
+		}
+		if request.Method == "resources/subscribe" {
+			uris[uri] = true
+		} else {
+			delete(uris, uri)
+		}
+		s.mu.Unlock()
+	}
+
+	data, err := json.Marshal(replacement)
+	if err != nil {
+		return message
+	}
+	return data
+}
+
+// subscribableURI reports whether uri names a resource this server serves.
+func subscribableURI(uri string) bool {
+	switch uri {
+	case "m://plan/format", "m://guide/workflow", "m://commands/reference", stateContextURI:
+		return true
+	}
+	_, _, err := parseStageLogURI(uri)
+	return err == nil
+}
+
+// subscribers lists the sessions subscribed to uri.
+func (s *resourceSubscriptions) subscribers(uri string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions []string
+	for sessionID, uris := range s.sessions {
+		if uris[uri] {
+			sessions = append(sessions, sessionID)
+		}
+	}
+	return sessions
+}
+
+// filterSubscriptions copies newline-delimited JSON-RPC messages from in to
+// next, rewriting subscription requests.
+func filterSubscriptions(subs *resourceSubscriptions, sessionID string, in io.Reader, next *io.PipeWriter) {
+	reader := bufio.NewReader(in)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if _, writeErr := next.Write(append(subs.rewrite(sessionID, trimmed), '\n')); writeErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			next.CloseWithError(err)
+			return
+		}
+	}
+}
+
+// notifyStateChanges follows the stack index of the repository containing
+// cwd and tells subscribed sessions when m://state/context or a stage log
+// resource changes. Stacks or stages being added or removed also change the
+// set of stage log resources, so those send
+// notifications/resources/list_changed.
+// Outside a git repository there is nothing to follow.
+func notifyStateChanges(ctx context.Context, srv *mcpserver.MCPServer, subs *resourceSubscriptions, cwd string) {
+	repo, err := gitx.DiscoverRepo(cwd)
+	if err != nil {
+		return
+	}
+	updates, err := state.Watch(ctx, gitx.SharedRoot(repo.TopLevel, repo.CommonDir), state.WatchOptions{})
+	if err != nil {
+		return
+	}
+
+	go forwardStateChanges(srv, subs, updates)
+}
+
+func forwardStateChanges(srv *mcpserver.MCPServer, subs *resourceSubscriptions, updates <-chan state.StateUpdate) {
+	for update := range updates {
+		if update.Err != nil || len(update.Changes) == 0 {
+			continue
+		}
+
+		for _, uri := range updatedResources(update.Changes) {
+			for _, sessionID := range subs.subscribers(uri) {
+				_ = srv.SendNotificationToSpecificClient(sessionID, mmcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+			}
+		}
+		if resourcesListChanged(update.Changes) {
+			srv.SendNotificationToAllClients(mmcp.MethodNotificationResourcesListChanged, nil)
+		}
+	}
+}
+
+// updatedResources lists the resources affected by changes: the state
+// context, and the log of each stage whose status changed or whose agent
+// started or exited.
+func updatedResources(changes []state.Change) []string {
+	uris := []string{stateContextURI}
+	seen := map[string]bool{}
+	for _, change := range changes {
+		switch change.Type {
+		case state.ChangeStageStatus, state.ChangeAgentStarted, state.ChangeAgentExited:
+			uri := stageLogURI(change.Stack, change.Stage)
+			if !seen[uri] {
+				seen[uri] = true
+				uris = append(uris, uri)
+			}
+		}
+	}
+	return uris
+}
+
+func resourcesListChanged(changes []state.Change) bool {
+	for _, change := range changes {
+		switch change.Type {
+		case state.ChangeStackAdded, state.ChangeStackRemoved, state.ChangeStageAdded, state.ChangeStageRemoved:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+func TestServeStdioNotifiesSubscribersOfStageTransitions(t *testing.T) {
+	repoRoot := initGitRepoWithMainCommit(t)
+	if err := state.SaveStacks(repoRoot, &state.Stacks{Stacks: []state.Stack{{Name: "checkout", Stages: []state.Stage{{ID: "api"}}}}}); err != nil {
+		t.Fatalf("SaveStacks: %v", err)
+	}
+	t.Chdir(repoRoot)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- ServeStdio(ctx, serverIn, serverOut, "test") }()
+	defer func() {
+		clientOut.Close()
+		cancel()
+		<-done
+	}()
+
+	messages := make(chan map[string]any, 16)
+	go func() {
+		scanner := bufio.NewScanner(clientIn)
+		for scanner.Scan() {
+			var message map[string]any
+			if json.Unmarshal(scanner.Bytes(), &message) == nil {
+				messages <- message
+			}
+		}
+	}()
+
+	send := func(message string) {
+		t.Helper()
+		if _, err := fmt.Fprintln(clientOut, message); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	next := func(match func(map[string]any) bool) map[string]any {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case message := <-messages:
+				if match(message) {
+					return message
+				}
+			case <-timeout:
+				t.Fatal("timed out waiting for a message from the server")
+			}
+		}
+	}
+	response := func(id float64) func(map[string]any) bool {
+		return func(message map[string]any) bool { return message["id"] == id }
+	}
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+	initialized := next(response(1))
+	capabilities := initialized["result"].(map[string]any)["capabilities"].(map[string]any)["resources"].(map[string]any)
+	if capabilities["subscribe"] != true || capabilities["listChanged"] != true {
+		t.Fatalf("resource capabilities = %v, want subscribe and listChanged", capabilities)
+	}
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	send(`{"jsonrpc":"2.0","id":2,"method":"resources/subscribe","params":{"uri":"m://nope"}}`)
+	if rejected := next(response(2)); rejected["error"] == nil {
+		t.Fatalf("subscribing to an unknown resource = %v, want error", rejected)
+	}
+
+	send(`{"jsonrpc":"2.0","id":3,"method":"resources/subscribe","params":{"uri":"m://state/context"}}`)
+	if subscribed := next(response(3)); subscribed["error"] != nil {
+		t.Fatalf("subscribe: %v", subscribed["error"])
+	}
+
+	err := state.Update(repoRoot, func(stacks *state.Stacks) error {
+		return state.TransitionStage(stacks, "checkout", "api", state.StatusImplementing)
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	updated := next(func(message map[string]any) bool { return message["method"] == "notifications/resources/updated" })
+	if uri := updated["params"].(map[string]any)["uri"]; uri != stateContextURI {
+		t.Fatalf("updated uri = %v, want %s", uri, stateContextURI)
+	}
+}
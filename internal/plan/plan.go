@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -18,13 +20,23 @@ type File struct {
 }
 
 type FileStage struct {
-	ID             string     `yaml:"id"`
-	Title          string     `yaml:"title"`
-	Outcome        string     `yaml:"outcome"`
-	Implementation []string   `yaml:"implementation"`
-	Validation     []string   `yaml:"validation"`
-	Risks          []FileRisk `yaml:"risks"`
-	Context        string     `yaml:"-"`
+	ID             string            `yaml:"id"`
+	Title          string            `yaml:"title"`
+	Outcome        string            `yaml:"outcome"`
+	Implementation []string          `yaml:"implementation"`
+	Validation     []string          `yaml:"validation"`
+	Risks          []FileRisk        `yaml:"risks"`
+	DependsOn      []string          `yaml:"depends_on"`
+	Timeouts       map[string]string `yaml:"timeouts"`
+	Model          string            `yaml:"model"`
+	Context        string            `yaml:"-"`
+}
+
+// timeoutKeys are the limits a stage may override in its timeouts map.
+var timeoutKeys = map[string]struct{}{
+	"implementing": {},
+	"ai_review":    {},
+	"idle":         {},
 }
 
 type FileRisk struct {
@@ -48,7 +60,7 @@ func ParseFile(path string) (*File, error) {
 		return nil, fmt.Errorf("parse plan frontmatter: %w", err)
 	}
 
-	if parsed.Version == 3 {
+	if parsed.Version >= 3 {
 		contexts, err := extractStageContexts(body)
 		if err != nil {
 			return nil, err
@@ -81,8 +93,8 @@ func Validate(p *File) error {
 	if p == nil {
 		return fmt.Errorf("plan is empty")
 	}
-	if p.Version != 2 && p.Version != 3 {
-		return fmt.Errorf("plan version must be 2 or 3")
+	if p.Version < 2 || p.Version > 4 {
+		return fmt.Errorf("plan version must be 2, 3 or 4")
 	}
 	if len(p.Stages) == 0 {
 		return fmt.Errorf("plan must include at least one stage")
@@ -124,16 +136,130 @@ func Validate(p *File) error {
 			}
 		}
 
-		if p.Version == 3 && strings.TrimSpace(stage.Context) == "" {
+		if p.Version >= 3 && strings.TrimSpace(stage.Context) == "" {
 			return fmt.Errorf("stage %q is missing context section", stageID)
 		}
 
+		if err := validateTimeouts(stage.Timeouts); err != nil {
+			return fmt.Errorf("stage %q has invalid timeouts: %w", stageID, err)
+		}
+
+		if strings.ContainsAny(stage.Model, " \t\n") {
+			return fmt.Errorf("stage %q has invalid model %q", stageID, stage.Model)
+		}
+
+		if p.Version < 4 && len(stage.DependsOn) > 0 {
+			return fmt.Errorf("stage %q uses depends_on, which requires plan version 4", stageID)
+		}
+
 		if _, exists := seen[stageID]; exists {
 			return fmt.Errorf("duplicate stage id %q", stageID)
 		}
 		seen[stageID] = struct{}{}
 	}
 
+	if p.Version >= 4 {
+		return validateDependencies(p.Stages)
+	}
+
+	return nil
+}
+
+// validateDependencies checks that depends_on only names known stages and
+// that the stages form a DAG.
+func validateDependencies(stages []FileStage) error {
+	deps := make(map[string][]string, len(stages))
+	for _, stage := range stages {
+		deps[strings.TrimSpace(stage.ID)] = nil
+	}
+
+	for _, stage := range stages {
+		stageID := strings.TrimSpace(stage.ID)
+		seen := map[string]struct{}{}
+		for _, dep := range stage.DependsOn {
+			dep = strings.TrimSpace(dep)
+			if dep == stageID {
+				return fmt.Errorf("stage %q depends on itself", stageID)
+			}
+			if _, ok := deps[dep]; !ok {
+				return fmt.Errorf("stage %q depends on unknown stage %q", stageID, dep)
+			}
+			if _, dup := seen[dep]; dup {
+				return fmt.Errorf("stage %q lists dependency %q more than once", stageID, dep)
+			}
+			seen[dep] = struct{}{}
+			deps[stageID] = append(deps[stageID], dep)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	marks := make(map[string]int, len(stages))
+	var path []string
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch marks[id] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for i, item := range path {
+				if item == id {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), id)
+			return fmt.Errorf("stage dependencies form a cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		marks[id] = visiting
+		path = append(path, id)
+		for _, dep := range deps[id] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[id] = done
+		return nil
+	}
+
+	for _, stage := range stages {
+		if err := visit(strings.TrimSpace(stage.ID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateTimeouts checks per-stage timeout overrides: known keys with
+// non-negative Go durations.
+func validateTimeouts(timeouts map[string]string) error {
+	keys := make([]string, 0, len(timeouts))
+	for key := range timeouts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, ok := timeoutKeys[key]; !ok {
+			return fmt.Errorf("unknown key %q; use implementing, ai_review or idle", key)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(timeouts[key]))
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a duration like 45m or 2h", key, timeouts[key])
+		}
+		if d < 0 {
+			return fmt.Errorf("%s: %q must not be negative", key, timeouts[key])
+		}
+	}
+
 	return nil
 }
 
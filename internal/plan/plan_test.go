@@ -106,6 +106,7 @@ stages:
     title: Foundation setup
   - id: api-wiring
     title: API wiring
+    model: opus
 ---
 
 ## Stage: foundation
@@ -133,6 +134,9 @@ Endpoints should reuse foundation interfaces. Keep request validation strict.
 	if got := strings.TrimSpace(parsed.Stages[1].Context); !strings.Contains(got, "request validation") {
 		t.Fatalf("unexpected stage context: %q", got)
 	}
+	if parsed.Stages[0].Model != "" || parsed.Stages[1].Model != "opus" {
+		t.Fatalf("models = %q, %q; want none and opus", parsed.Stages[0].Model, parsed.Stages[1].Model)
+	}
 }
 
 func TestValidateV3RequiresContext(t *testing.T) {
@@ -183,3 +187,126 @@ This should fail because the stage id is not declared in frontmatter.
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestParseFileV4DependsOn(t *testing.T) {
+	tempDir := t.TempDir()
+	planPath := filepath.Join(tempDir, "plan.md")
+
+	content := `---
+version: 4
+title: Checkout rollout
+stages:
+  - id: foundation
+    title: Foundation setup
+  - id: api-wiring
+    title: API wiring
+    depends_on: [foundation]
+  - id: ui
+    title: UI
+    depends_on: [foundation]
+  - id: launch
+    title: Launch
+    depends_on: [api-wiring, ui]
+---
+
+## Stage: foundation
+Shared types.
+
+## Stage: api-wiring
+Endpoints.
+
+## Stage: ui
+Screens.
+
+## Stage: launch
+Flip the flag.
+`
+
+	if err := os.WriteFile(planPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+
+	parsed, err := ParseFile(planPath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if got := parsed.Stages[3].DependsOn; len(got) != 2 || got[0] != "api-wiring" || got[1] != "ui" {
+		t.Fatalf("launch depends_on = %v", got)
+	}
+	if parsed.Stages[0].DependsOn != nil {
+		t.Fatalf("foundation depends_on = %v, want none", parsed.Stages[0].DependsOn)
+	}
+}
+
+func TestValidateDependsOn(t *testing.T) {
+	stage := func(id string, deps ...string) FileStage {
+		return FileStage{ID: id, Title: id, Context: "context", DependsOn: deps}
+	}
+
+	tests := []struct {
+		name    string
+		plan    *File
+		wantErr string
+	}{
+		{
+			name:    "requires version 4",
+			plan:    &File{Version: 3, Stages: []FileStage{stage("a"), stage("b", "a")}},
+			wantErr: "requires plan version 4",
+		},
+		{
+			name:    "unknown stage",
+			plan:    &File{Version: 4, Stages: []FileStage{stage("a", "missing")}},
+			wantErr: `depends on unknown stage "missing"`,
+		},
+		{
+			name:    "self dependency",
+			plan:    &File{Version: 4, Stages: []FileStage{stage("a", "a")}},
+			wantErr: "depends on itself",
+		},
+		{
+			name:    "duplicate dependency",
+			plan:    &File{Version: 4, Stages: []FileStage{stage("a"), stage("b", "a", "a")}},
+			wantErr: "more than once",
+		},
+		{
+			name:    "cycle",
+			plan:    &File{Version: 4, Stages: []FileStage{stage("a", "c"), stage("b", "a"), stage("c", "b")}},
+			wantErr: "cycle: a -> c -> b -> a",
+		},
+		{
+			name: "forward reference is allowed",
+			plan: &File{Version: 4, Stages: []FileStage{stage("a", "b"), stage("b")}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.plan)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	stage := func(timeouts map[string]string) *File {
+		return &File{Version: 3, Stages: []FileStage{{ID: "a", Title: "a", Context: "context", Timeouts: timeouts}}}
+	}
+
+	if err := Validate(stage(map[string]string{"implementing": "4h", "idle": "0"})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Validate(stage(map[string]string{"deploy": "1h"})); err == nil || !strings.Contains(err.Error(), `unknown key "deploy"`) {
+		t.Fatalf("Validate() error = %v, want unknown key", err)
+	}
+	if err := Validate(stage(map[string]string{"ai_review": "later"})); err == nil || !strings.Contains(err.Error(), "ai_review") {
+		t.Fatalf("Validate() error = %v, want invalid ai_review", err)
+	}
+}
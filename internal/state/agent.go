@@ -0,0 +1,121 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// pidReuseSlack allows for the second resolution of the recorded start time
+// and of the process start time when checking that a PID is still the agent.
+const pidReuseSlack = 5 * time.Second
+
+// AgentProcess records the agent process last spawned for a stage.
+type AgentProcess struct {
+	Phase     string   `json:"phase"`
+	PID       int      `json:"pid"`
+	Hostname  string   `json:"hostname,omitempty"`
+	Command   []string `json:"command,omitempty"`
+	Log       string   `json:"log,omitempty"`
+	StartedAt string   `json:"started_at"`
+	ExitCode  *int     `json:"exit_code,omitempty"`
+	ExitedAt  string   `json:"exited_at,omitempty"`
+}
+
+// PhaseStatus returns the stage status an agent phase runs in.
+func PhaseStatus(phase string) string {
+	switch phase {
+	case PhaseImplementing:
+		return StatusImplementing
+	case PhaseAIReview:
+		return StatusAIReview
+	default:
+		return ""
+	}
+}
+
+// AgentRunning reports whether the stage's agent process is still alive. It
+// is false once an exit has been recorded or its PID has been reused by a
+// later process, and true when the agent runs on another host, since that
+// cannot be checked from here.
+func AgentRunning(agent *AgentProcess) bool {
+	if agent == nil || agent.ExitCode != nil {
+		return false
+	}
+
+	if !AgentOnThisHost(agent) {
+		return true
+	}
+
+	return processAlive(agent.PID) && !pidReused(agent)
+}
+
+// AgentOnThisHost reports whether the agent was spawned on this machine, so
+// its PID refers to a local process.
+func AgentOnThisHost(agent *AgentProcess) bool {
+	hostname, _ := os.Hostname()
+	return agent.Hostname == "" || agent.Hostname == hostname
+}
+
+// TerminateAgent asks a running local agent to exit. It refuses agents on
+// another host and does nothing when the PID no longer belongs to the agent.
+func TerminateAgent(agent *AgentProcess) error {
+	if !AgentOnThisHost(agent) {
+		return fmt.Errorf("agent pid %d runs on host %q", agent.PID, agent.Hostname)
+	}
+	if !processAlive(agent.PID) || pidReused(agent) {
+		return nil
+	}
+
+	return TerminateProcess(agent.PID)
+}
+
+// pidReused reports whether the process now holding the agent's PID started
+// after the agent did, meaning the agent exited and the PID was recycled.
+func pidReused(agent *AgentProcess) bool {
+	recorded, err := time.Parse(time.RFC3339, agent.StartedAt)
+	if err != nil {
+		return false
+	}
+	started, ok := processStartTime(agent.PID)
+	if !ok {
+		return false
+	}
+
+	return started.After(recorded.Add(pidReuseSlack))
+}
+
+// AgentLost reports whether the stage is waiting on an agent that is no
+// longer running, meaning it exited without calling report_stage_done, and
+// describes what happened.
+func AgentLost(stage *Stage) (bool, string) {
+	if stage == nil || stage.Agent == nil {
+		return false, ""
+	}
+
+	agent := stage.Agent
+	if PhaseStatus(agent.Phase) != EffectiveStatus(stage) || AgentRunning(agent) {
+		return false, ""
+	}
+
+	if agent.ExitCode != nil {
+		return true, fmt.Sprintf("%s agent (pid %d) exited with code %d without calling report_stage_done", agentPhaseName(agent.Phase), agent.PID, *agent.ExitCode)
+	}
+	return true, fmt.Sprintf("%s agent (pid %d) is no longer running and did not call report_stage_done", agentPhaseName(agent.Phase), agent.PID)
+}
+
+func agentPhaseName(phase string) string {
+	if phase == PhaseAIReview {
+		return "review"
+	}
+	return "build"
+}
+
+// FormatAgentCommand renders a recorded agent command line.
+func FormatAgentCommand(agent *AgentProcess) string {
+	if agent == nil {
+		return ""
+	}
+	return strings.Join(agent.Command, " ")
+}
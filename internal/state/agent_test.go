@@ -0,0 +1,83 @@
+package state
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentLost(t *testing.T) {
+	stage := &Stage{
+		ID:     "a",
+		Status: StatusImplementing,
+		Agent:  &AgentProcess{Phase: PhaseImplementing, PID: os.Getpid()},
+	}
+	if lost, _ := AgentLost(stage); lost {
+		t.Fatal("expected a running agent not to be lost")
+	}
+
+	code := 2
+	stage.Agent.ExitCode = &code
+	lost, reason := AgentLost(stage)
+	if !lost || !strings.Contains(reason, "exited with code 2") {
+		t.Fatalf("AgentLost() = %v, %q; want lost with exit code", lost, reason)
+	}
+
+	// An agent from an earlier phase does not matter once the stage moved on.
+	stage.Status = StatusAIReview
+	if lost, _ := AgentLost(stage); lost {
+		t.Fatal("expected the previous phase's agent to be ignored")
+	}
+}
+
+func TestAgentLostWithoutExitCode(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("true not available: %v", err)
+	}
+
+	stage := &Stage{
+		ID:     "a",
+		Status: StatusAIReview,
+		Agent:  &AgentProcess{Phase: PhaseAIReview, PID: cmd.Process.Pid},
+	}
+	lost, reason := AgentLost(stage)
+	if !lost || !strings.Contains(reason, "no longer running") {
+		t.Fatalf("AgentLost() = %v, %q; want lost", lost, reason)
+	}
+
+	stage.Agent.Hostname = "some-other-host"
+	if lost, _ := AgentLost(stage); lost {
+		t.Fatal("expected an agent on another host to be assumed running")
+	}
+}
+
+func TestAgentRunningDetectsReusedPID(t *testing.T) {
+	// This process stands in for whatever now holds the agent's old PID.
+	agent := &AgentProcess{
+		Phase:     PhaseImplementing,
+		PID:       os.Getpid(),
+		StartedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if !AgentRunning(agent) {
+		t.Fatal("expected an agent recorded after its process started to be running")
+	}
+
+	agent.StartedAt = "2000-01-01T00:00:00Z"
+	if _, ok := processStartTime(os.Getpid()); !ok {
+		t.Skip("process start time unavailable")
+	}
+	if AgentRunning(agent) {
+		t.Fatal("expected a PID reused by a later process not to count as the agent")
+	}
+	if err := TerminateAgent(agent); err != nil {
+		t.Fatalf("TerminateAgent() = %v, want no-op for a reused PID", err)
+	}
+
+	agent.Hostname = "some-other-host"
+	if err := TerminateAgent(agent); err == nil {
+		t.Fatal("expected TerminateAgent to refuse an agent on another host")
+	}
+}
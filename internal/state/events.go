@@ -0,0 +1,146 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Event types recorded in a stack's journal.
+const (
+	EventStageTransition = "stage_transition"
+	EventWorktreeCreated = "worktree_created"
+	EventPush            = "push"
+	EventSyncRebase      = "sync_rebase"
+	EventSyncConflict    = "sync_conflict"
+	EventSyncAbort       = "sync_abort"
+	EventAgentSpawn      = "agent_spawn"
+	EventAgentExit       = "agent_exit"
+	EventPause           = "pause"
+	EventResume          = "resume"
+	EventLegacyImport    = "legacy_import"
+)
+
+// Event is a single append-only journal entry for a stack.
+type Event struct {
+	Time    string            `json:"time"`
+	Type    string            `json:"type"`
+	Actor   string            `json:"actor,omitempty"`
+	Stage   string            `json:"stage,omitempty"`
+	Payload map[string]string `json:"payload,omitempty"`
+}
+
+type journalEntry struct {
+	stack string
+	event Event
+}
+
+func EventsPath(repoRoot, stackName string) string {
+	return filepath.Join(StacksDir(repoRoot), filepath.FromSlash(stackName), "events.jsonl")
+}
+
+// Record queues an event for stackName. Queued events are appended to the
+// journal by UpdateAs once the index has been saved, so the journal never
+// describes changes that were discarded.
+func (s *Stacks) Record(stackName string, event Event) {
+	if s == nil {
+		return
+	}
+	if event.Time == "" {
+		event.Time = time.Now().UTC().Format(time.RFC3339)
+	}
+	s.journal = append(s.journal, journalEntry{stack: stackName, event: event})
+}
+
+// TakeEvents moves the events queued on from, typically a snapshot of the
+// index read without the lock, to s so they are journaled when s is saved.
+func (s *Stacks) TakeEvents(from *Stacks) {
+	if s == nil || from == nil {
+		return
+	}
+	s.journal = append(s.journal, from.journal...)
+	from.journal = nil
+}
+
+// AppendEvent appends a single event to the stack's journal.
+func AppendEvent(repoRoot, stackName string, event Event) error {
+	if strings.TrimSpace(stackName) == "" {
+		return fmt.Errorf("stack name is required")
+	}
+	if event.Time == "" {
+		event.Time = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	path := EventsPath(repoRoot, stackName)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// A single write keeps each line intact when several processes append.
+	_, err = f.Write(data)
+	return err
+}
+
+// LoadEvents reads a stack's journal in order. Lines that cannot be parsed,
+// such as a record truncated by a crash, are skipped.
+func LoadEvents(repoRoot, stackName string) ([]Event, error) {
+	f, err := os.Open(EventsPath(repoRoot, stackName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Event{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	events := []Event{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read events: %w", err)
+	}
+
+	return events, nil
+}
+
+func flushJournal(repoRoot, actor string, entries []journalEntry) error {
+	for _, entry := range entries {
+		event := entry.event
+		if event.Actor == "" {
+			event.Actor = actor
+		}
+		if err := AppendEvent(repoRoot, entry.stack, event); err != nil {
+			return fmt.Errorf("record %s event for stack %q: %w", event.Type, entry.stack, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateAsJournalsTransitions(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := SaveStacks(repoRoot, &Stacks{Stacks: []Stack{{Name: "checkout", Stages: []Stage{{ID: "foundation"}}}}}); err != nil {
+		t.Fatalf("SaveStacks: %v", err)
+	}
+
+	err := UpdateAs(repoRoot, "m stack run", func(s *Stacks) error {
+		return TransitionStageWith(s, "checkout", "foundation", StatusImplementing, map[string]string{"summary": "started"})
+	})
+	if err != nil {
+		t.Fatalf("UpdateAs: %v", err)
+	}
+
+	events, err := LoadEvents(repoRoot, "checkout")
+	if err != nil {
+		t.Fatalf("LoadEvents: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("len(events) = %d, want 1", len(events))
+	}
+
+	got := events[0]
+	if got.Type != EventStageTransition || got.Stage != "foundation" || got.Actor != "m stack run" {
+		t.Fatalf("event = %+v, want stage_transition for foundation by m stack run", got)
+	}
+	if got.Payload["from"] != StatusPending || got.Payload["to"] != StatusImplementing || got.Payload["summary"] != "started" {
+		t.Fatalf("payload = %v", got.Payload)
+	}
+	if got.Time == "" {
+		t.Fatal("event time should be set")
+	}
+}
+
+func TestUpdateAsDropsEventsWhenUpdateFails(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := SaveStacks(repoRoot, &Stacks{Stacks: []Stack{{Name: "checkout", Stages: []Stage{{ID: "foundation"}}}}}); err != nil {
+		t.Fatalf("SaveStacks: %v", err)
+	}
+
+	err := UpdateAs(repoRoot, "m stack run", func(s *Stacks) error {
+		if err := TransitionStage(s, "checkout", "foundation", StatusImplementing); err != nil {
+			return err
+		}
+		return TransitionStage(s, "checkout", "foundation", StatusDone)
+	})
+	if err == nil {
+		t.Fatal("expected invalid transition error")
+	}
+
+	events, err := LoadEvents(repoRoot, "checkout")
+	if err != nil {
+		t.Fatalf("LoadEvents: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("len(events) = %d, want 0", len(events))
+	}
+}
+
+func TestLoadEventsSkipsMalformedLines(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := AppendEvent(repoRoot, "checkout", Event{Type: EventPush, Stage: "foundation"}); err != nil {
+		t.Fatalf("AppendEvent: %v", err)
+	}
+
+	path := EventsPath(repoRoot, "checkout")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := f.WriteString("{\"time\":\"trunc"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	f.Close()
+
+	events, err := LoadEvents(repoRoot, "checkout")
+	if err != nil {
+		t.Fatalf("LoadEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != EventPush {
+		t.Fatalf("events = %+v, want single push event", events)
+	}
+
+	if filepath.Base(path) != "events.jsonl" {
+		t.Fatalf("EventsPath() = %q, want events.jsonl", path)
+	}
+}
+
+func TestLoadEventsMissingJournal(t *testing.T) {
+	events, err := LoadEvents(t.TempDir(), "missing")
+	if err != nil {
+		t.Fatalf("LoadEvents: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("len(events) = %d, want 0", len(events))
+	}
+}
@@ -0,0 +1,181 @@
+package state
+
+import "strings"
+
+// StageDependencies returns the IDs of the stages that stageIndex builds on.
+// Stacks created from a v4 plan declare dependencies explicitly; older
+// stacks are linear, so each stage depends on the one before it.
+func StageDependencies(stack *Stack, stageIndex int) []string {
+	if stack == nil || stageIndex < 0 || stageIndex >= len(stack.Stages) {
+		return nil
+	}
+
+	if !stack.DependencyGraph {
+		if stageIndex == 0 {
+			return nil
+		}
+		return []string{stack.Stages[stageIndex-1].ID}
+	}
+
+	deps := make([]string, 0, len(stack.Stages[stageIndex].DependsOn))
+	for _, dep := range stack.Stages[stageIndex].DependsOn {
+		if dep = strings.TrimSpace(dep); dep != "" {
+			deps = append(deps, dep)
+		}
+	}
+
+	return deps
+}
+
+// StageComplete reports whether dependents of stage may start: its work has
+// been handed to human review or is done.
+func StageComplete(stage *Stage) bool {
+	status := EffectiveStatus(stage)
+	return status == StatusHumanReview || status == StatusDone
+}
+
+// DependenciesComplete reports whether every dependency of stageIndex is
+// complete. Dependencies that are no longer in the stack (for example pruned
+// after merging) count as complete.
+func DependenciesComplete(stack *Stack, stageIndex int) bool {
+	for _, dep := range StageDependencies(stack, stageIndex) {
+		if stage, _ := FindStage(stack, dep); stage != nil && !StageComplete(stage) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ReadyStages returns the pending stages whose dependencies are complete, in
+// stack order.
+func ReadyStages(stack *Stack) []*Stage {
+	if stack == nil {
+		return nil
+	}
+
+	var ready []*Stage
+	for i := range stack.Stages {
+		if EffectiveStatus(&stack.Stages[i]) == StatusPending && DependenciesComplete(stack, i) {
+			ready = append(ready, &stack.Stages[i])
+		}
+	}
+
+	return ready
+}
+
+// ActiveStages returns the stages an agent is currently working on.
+func ActiveStages(stack *Stack) []*Stage {
+	if stack == nil {
+		return nil
+	}
+
+	var active []*Stage
+	for i := range stack.Stages {
+		status := EffectiveStatus(&stack.Stages[i])
+		if status == StatusImplementing || status == StatusAIReview {
+			active = append(active, &stack.Stages[i])
+		}
+	}
+
+	return active
+}
+
+// FreeSlots returns how many more stages the pipeline may start before
+// reaching the stack's parallelism limit.
+func FreeSlots(stack *Stack) int {
+	if stack == nil {
+		return 0
+	}
+
+	limit := stack.Parallel
+	if limit < 1 {
+		limit = 1
+	}
+
+	if free := limit - len(ActiveStages(stack)); free > 0 {
+		return free
+	}
+	return 0
+}
+
+// TopologicalOrder returns stage indexes ordered so that every stage comes
+// after its dependencies, preferring stack order among independent stages.
+// Stages caught in a cycle are appended in stack order.
+func TopologicalOrder(stack *Stack) []int {
+	if stack == nil {
+		return nil
+	}
+
+	visited := make([]bool, len(stack.Stages))
+	onPath := make([]bool, len(stack.Stages))
+	order := make([]int, 0, len(stack.Stages))
+
+	var visit func(idx int)
+	visit = func(idx int) {
+		if visited[idx] || onPath[idx] {
+			return
+		}
+		onPath[idx] = true
+		for _, dep := range StageDependencies(stack, idx) {
+			if _, depIndex := FindStage(stack, dep); depIndex >= 0 {
+				visit(depIndex)
+			}
+		}
+		onPath[idx] = false
+		visited[idx] = true
+		order = append(order, idx)
+	}
+
+	for idx := range stack.Stages {
+		visit(idx)
+	}
+
+	return order
+}
+
+// StageAncestors returns the indexes of every stage stageIndex transitively
+// depends on, in topological order.
+func StageAncestors(stack *Stack, stageIndex int) []int {
+	related := map[int]bool{}
+	var walk func(idx int)
+	walk = func(idx int) {
+		for _, dep := range StageDependencies(stack, idx) {
+			if _, depIndex := FindStage(stack, dep); depIndex >= 0 && !related[depIndex] {
+				related[depIndex] = true
+				walk(depIndex)
+			}
+		}
+	}
+	walk(stageIndex)
+
+	return filterOrder(TopologicalOrder(stack), related)
+}
+
+// StageDescendants returns the indexes of every stage that transitively
+// depends on stageIndex, in topological order.
+func StageDescendants(stack *Stack, stageIndex int) []int {
+	related := map[int]bool{}
+	for _, idx := range TopologicalOrder(stack) {
+		for _, dep := range StageDependencies(stack, idx) {
+			_, depIndex := FindStage(stack, dep)
+			if depIndex == stageIndex || related[depIndex] {
+				related[idx] = true
+				break
+			}
+		}
+	}
+
+	return filterOrder(TopologicalOrder(stack), related)
+}
+
+func filterOrder(order []int, keep map[int]bool) []int {
+	filtered := make([]int, 0, len(keep))
+	for _, idx := range order {
+		if keep[idx] {
+			filtered = append(filtered, idx)
+		}
+	}
+
+	return filtered
+}
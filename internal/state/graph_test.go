@@ -0,0 +1,102 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+)
+
+func diamondStack() *Stack {
+	return &Stack{
+		Name:            "diamond",
+		DependencyGraph: true,
+		Stages: []Stage{
+			{ID: "launch", DependsOn: []string{"api", "ui"}},
+			{ID: "base"},
+			{ID: "api", DependsOn: []string{"base"}},
+			{ID: "ui", DependsOn: []string{"base"}},
+			{ID: "docs"},
+		},
+	}
+}
+
+func TestStageDependenciesLinearStack(t *testing.T) {
+	stack := &Stack{Stages: []Stage{{ID: "s1"}, {ID: "s2", DependsOn: []string{"ignored"}}}}
+
+	if deps := StageDependencies(stack, 0); len(deps) != 0 {
+		t.Fatalf("StageDependencies(0) = %v, want none", deps)
+	}
+	if deps := StageDependencies(stack, 1); !reflect.DeepEqual(deps, []string{"s1"}) {
+		t.Fatalf("StageDependencies(1) = %v, want [s1]", deps)
+	}
+}
+
+func TestReadyStagesFollowDependencies(t *testing.T) {
+	stack := diamondStack()
+
+	if got := stageIDs(ReadyStages(stack)); !reflect.DeepEqual(got, []string{"base", "docs"}) {
+		t.Fatalf("ReadyStages() = %v, want [base docs]", got)
+	}
+
+	stack.Stages[1].Status = StatusHumanReview
+	stack.Stages[4].Status = StatusImplementing
+	if got := stageIDs(ReadyStages(stack)); !reflect.DeepEqual(got, []string{"api", "ui"}) {
+		t.Fatalf("ReadyStages() = %v, want [api ui]", got)
+	}
+
+	stack.Stages[2].Status = StatusDone
+	stack.Stages[3].Status = StatusAIReview
+	if next := NextPendingStage(stack); next != nil {
+		t.Fatalf("NextPendingStage() = %v, want nil while ui is in review", next.ID)
+	}
+	if !HasPendingStages(stack) {
+		t.Fatal("expected launch to still be pending")
+	}
+}
+
+func TestTopologicalOrderAndRelatives(t *testing.T) {
+	stack := diamondStack()
+
+	if got := TopologicalOrder(stack); !reflect.DeepEqual(got, []int{1, 2, 3, 0, 4}) {
+		t.Fatalf("TopologicalOrder() = %v, want [1 2 3 0 4]", got)
+	}
+	if got := StageAncestors(stack, 0); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Fatalf("StageAncestors(launch) = %v, want [1 2 3]", got)
+	}
+	if got := StageDescendants(stack, 2); !reflect.DeepEqual(got, []int{0}) {
+		t.Fatalf("StageDescendants(api) = %v, want [0]", got)
+	}
+	if got := StageDescendants(stack, 4); len(got) != 0 {
+		t.Fatalf("StageDescendants(docs) = %v, want none", got)
+	}
+}
+
+func TestFreeSlotsHonoursParallelLimit(t *testing.T) {
+	stack := diamondStack()
+	stack.Stages[1].Status = StatusImplementing
+
+	if got := FreeSlots(stack); got != 0 {
+		t.Fatalf("FreeSlots() = %d, want 0 with the default limit", got)
+	}
+
+	stack.Parallel = 3
+	stack.Stages[4].Status = StatusAIReview
+	if got := stageIDs(ActiveStages(stack)); !reflect.DeepEqual(got, []string{"base", "docs"}) {
+		t.Fatalf("ActiveStages() = %v, want [base docs]", got)
+	}
+	if got := FreeSlots(stack); got != 1 {
+		t.Fatalf("FreeSlots() = %d, want 1", got)
+	}
+
+	stack.Parallel = 1
+	if got := FreeSlots(stack); got != 0 {
+		t.Fatalf("FreeSlots() = %d, want 0 when over the limit", got)
+	}
+}
+
+func stageIDs(stages []*Stage) []string {
+	ids := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		ids = append(ids, stage.ID)
+	}
+	return ids
+}
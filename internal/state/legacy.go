@@ -0,0 +1,190 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/stacks"
+)
+
+// LegacyImportOptions controls how legacy stacks are converted.
+type LegacyImportOptions struct {
+	// RepoRoot resolves relative legacy worktree paths.
+	RepoRoot string
+	// Source is the legacy state file, recorded in the import event.
+	Source string
+	// BranchExists, when set, is used to flag parts whose branch is gone.
+	BranchExists func(branch string) bool
+	// Only limits the import to these stack names when non-empty.
+	Only []string
+}
+
+// LegacyImport reports the outcome of importing one legacy stack. Conflicts
+// prevented the import; Warnings describe adjustments made while converting.
+type LegacyImport struct {
+	Stack     string   `json:"stack"`
+	Stages    int      `json:"stages"`
+	Imported  bool     `json:"imported"`
+	Conflicts []string `json:"conflicts,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// ImportLegacy converts each stack in the legacy parts-based state into a
+// Stack with stages and appends it to target. Stacks whose name or branches
+// are already taken in target are skipped and reported as conflicts.
+func ImportLegacy(target *Stacks, legacy *stacks.State, opts LegacyImportOptions) []LegacyImport {
+	only := map[string]bool{}
+	for _, name := range opts.Only {
+		only[strings.TrimSpace(name)] = true
+	}
+
+	ownedBranches := map[string]string{}
+	for _, stack := range target.Stacks {
+		for _, stage := range stack.Stages {
+			if branch := strings.TrimSpace(stage.Branch); branch != "" {
+				ownedBranches[branch] = stack.Name
+			}
+		}
+	}
+
+	results := make([]LegacyImport, 0, len(legacy.Stacks))
+	for _, legacyStack := range legacy.Stacks {
+		name := strings.TrimSpace(legacyStack.Name)
+		if len(only) > 0 && !only[name] {
+			continue
+		}
+
+		result := LegacyImport{Stack: name}
+		converted, warnings := ConvertLegacyStack(legacyStack, opts)
+		result.Stages = len(converted.Stages)
+		result.Warnings = warnings
+
+		if name == "" {
+			result.Conflicts = append(result.Conflicts, "legacy stack has no name")
+		} else if existing, _ := FindStack(target, name); existing != nil {
+			result.Conflicts = append(result.Conflicts, fmt.Sprintf("stack %q already exists", name))
+		}
+		for _, stage := range converted.Stages {
+			if owner, ok := ownedBranches[stage.Branch]; ok {
+				result.Conflicts = append(result.Conflicts, fmt.Sprintf("branch %q is already used by stack %q", stage.Branch, owner))
+			}
+		}
+
+		if len(result.Conflicts) == 0 {
+			target.Stacks = append(target.Stacks, converted)
+			for _, stage := range converted.Stages {
+				ownedBranches[stage.Branch] = converted.Name
+			}
+			target.Record(converted.Name, Event{
+				Type:    EventLegacyImport,
+				Payload: map[string]string{"source": opts.Source, "stages": fmt.Sprintf("%d", len(converted.Stages))},
+			})
+			result.Imported = true
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// ConvertLegacyStack maps legacy parts, ordered by index, onto stages.
+func ConvertLegacyStack(legacy stacks.Stack, opts LegacyImportOptions) (Stack, []string) {
+	var warnings []string
+
+	parts := append([]stacks.Part(nil), legacy.Parts...)
+	sort.SliceStable(parts, func(i, j int) bool {
+		return parts[i].Index < parts[j].Index
+	})
+
+	name := strings.Trim(strings.TrimSpace(legacy.Name), "/")
+	stack := Stack{
+		Name:   name,
+		Stages: make([]Stage, 0, len(parts)),
+	}
+
+	seenIDs := map[string]bool{}
+	seenIndexes := map[int]bool{}
+	parentBranch := strings.TrimSpace(legacy.BaseBranch)
+	createdAt := ""
+
+	for i, part := range parts {
+		if seenIndexes[part.Index] {
+			warnings = append(warnings, fmt.Sprintf("part index %d is duplicated; keeping file order", part.Index))
+		}
+		seenIndexes[part.Index] = true
+
+		id := strings.TrimSpace(part.Slug)
+		if id == "" {
+			id = stacks.SlugPart(part.Label)
+		}
+		if id == "" {
+			id = fmt.Sprintf("part-%d", part.Index)
+		}
+		if seenIDs[id] {
+			renamed := fmt.Sprintf("%s-%d", id, i+1)
+			warnings = append(warnings, fmt.Sprintf("stage id %q is duplicated; renamed to %q", id, renamed))
+			id = renamed
+		}
+		seenIDs[id] = true
+
+		branch := strings.TrimSpace(part.Branch)
+		if branch == "" {
+			branch = stacks.BuildBranch(name, part.Index, id)
+			warnings = append(warnings, fmt.Sprintf("stage %q had no branch; using %q", id, branch))
+		}
+		if opts.BranchExists != nil && !opts.BranchExists(branch) {
+			warnings = append(warnings, fmt.Sprintf("stage %q branch %q does not exist locally", id, branch))
+		}
+
+		parent := strings.TrimSpace(part.ParentBranch)
+		if parent == "" {
+			parent = parentBranch
+		}
+
+		worktree := strings.TrimSpace(part.Worktree)
+		if worktree != "" && !filepath.IsAbs(worktree) && opts.RepoRoot != "" {
+			worktree = filepath.Join(opts.RepoRoot, worktree)
+		}
+		if worktree != "" {
+			if _, err := os.Stat(worktree); err != nil {
+				warnings = append(warnings, fmt.Sprintf("stage %q worktree %s is missing; cleared", id, worktree))
+				worktree = ""
+			}
+		}
+
+		title := strings.TrimSpace(part.Label)
+		if title == "" {
+			title = id
+		}
+
+		stack.Stages = append(stack.Stages, Stage{
+			ID:       id,
+			Title:    title,
+			Branch:   branch,
+			Worktree: worktree,
+			Parent:   parent,
+			Status:   StatusPending,
+		})
+
+		if part.CreatedAt != "" && (createdAt == "" || part.CreatedAt < createdAt) {
+			createdAt = part.CreatedAt
+		}
+		parentBranch = branch
+	}
+
+	if createdAt == "" {
+		createdAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	stack.CreatedAt = createdAt
+
+	if len(stack.Stages) == 0 {
+		warnings = append(warnings, "legacy stack has no parts")
+	}
+
+	return stack, warnings
+}
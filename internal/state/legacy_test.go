@@ -0,0 +1,127 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mlawd/m-cli/internal/stacks"
+)
+
+func TestConvertLegacyStackOrdersPartsAndChainsParents(t *testing.T) {
+	repoRoot := t.TempDir()
+	worktree := filepath.Join("worktrees", "checkout", "1", "api")
+	if err := os.MkdirAll(filepath.Join(repoRoot, worktree), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	legacy := stacks.Stack{
+		Name:       "checkout",
+		BaseBranch: "main",
+		Parts: []stacks.Part{
+			{Index: 2, Label: "UI", Slug: "ui", Branch: "checkout/2/ui", Worktree: "/does/not/exist", CreatedAt: "2024-02-02T00:00:00Z"},
+			{Index: 1, Label: "API", Slug: "api", Branch: "checkout/1/api", Worktree: worktree, CreatedAt: "2024-01-01T00:00:00Z"},
+		},
+	}
+
+	stack, warnings := ConvertLegacyStack(legacy, LegacyImportOptions{RepoRoot: repoRoot, Source: "legacy.json"})
+	if len(stack.Stages) != 2 {
+		t.Fatalf("len(Stages) = %d, want 2", len(stack.Stages))
+	}
+
+	first, second := stack.Stages[0], stack.Stages[1]
+	if first.ID != "api" || first.Parent != "main" || first.Branch != "checkout/1/api" {
+		t.Fatalf("first stage = %+v", first)
+	}
+	if first.Worktree != filepath.Join(repoRoot, worktree) {
+		t.Fatalf("first worktree = %q, want resolved legacy path", first.Worktree)
+	}
+	if second.ID != "ui" || second.Parent != "checkout/1/api" || second.Worktree != "" {
+		t.Fatalf("second stage = %+v", second)
+	}
+	if first.Status != StatusPending || stack.PlanFile != "" || stack.CreatedAt != "2024-01-01T00:00:00Z" {
+		t.Fatalf("stack = %+v", stack)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "is missing; cleared") {
+		t.Fatalf("warnings = %v, want missing worktree note", warnings)
+	}
+}
+
+func TestConvertLegacyStackFillsMissingIDsAndBranches(t *testing.T) {
+	legacy := stacks.Stack{
+		Name: "cleanup",
+		Parts: []stacks.Part{
+			{Index: 1, Label: "Remove Flags"},
+			{Index: 2, Label: "remove flags"},
+		},
+	}
+
+	stack, warnings := ConvertLegacyStack(legacy, LegacyImportOptions{
+		BranchExists: func(string) bool { return false },
+	})
+	if stack.Stages[0].ID != "remove-flags" || stack.Stages[1].ID != "remove-flags-2" {
+		t.Fatalf("ids = %q, %q", stack.Stages[0].ID, stack.Stages[1].ID)
+	}
+	if stack.Stages[0].Branch != "cleanup/1/remove-flags" {
+		t.Fatalf("branch = %q, want generated legacy branch", stack.Stages[0].Branch)
+	}
+
+	joined := strings.Join(warnings, "\n")
+	for _, want := range []string{"renamed to \"remove-flags-2\"", "had no branch", "does not exist locally"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("warnings missing %q:\n%s", want, joined)
+		}
+	}
+}
+
+func TestImportLegacyReportsConflicts(t *testing.T) {
+	target := &Stacks{Stacks: []Stack{
+		{Name: "existing", Stages: []Stage{{ID: "a", Branch: "shared/1/a"}}},
+	}}
+	legacy := &stacks.State{Stacks: []stacks.Stack{
+		{Name: "existing", Parts: []stacks.Part{{Index: 1, Slug: "x", Branch: "existing/1/x"}}},
+		{Name: "shared", Parts: []stacks.Part{{Index: 1, Slug: "a", Branch: "shared/1/a"}}},
+		{Name: "fresh", Parts: []stacks.Part{{Index: 1, Slug: "b", Branch: "fresh/1/b"}}},
+	}}
+
+	results := ImportLegacy(target, legacy, LegacyImportOptions{Source: "legacy.json"})
+	if len(results) != 3 {
+		t.Fatalf("len(results) = %d, want 3", len(results))
+	}
+	if results[0].Imported || !strings.Contains(results[0].Conflicts[0], "already exists") {
+		t.Fatalf("results[0] = %+v, want name conflict", results[0])
+	}
+	if results[1].Imported || !strings.Contains(results[1].Conflicts[0], "already used by stack \"existing\"") {
+		t.Fatalf("results[1] = %+v, want branch conflict", results[1])
+	}
+	if !results[2].Imported {
+		t.Fatalf("results[2] = %+v, want imported", results[2])
+	}
+
+	if len(target.Stacks) != 2 || target.Stacks[1].Name != "fresh" {
+		t.Fatalf("target stacks = %+v, want fresh appended", target.Stacks)
+	}
+	if len(target.journal) != 1 || target.journal[0].event.Type != EventLegacyImport {
+		t.Fatalf("journal = %+v, want one legacy import event", target.journal)
+	}
+	if source := target.journal[0].event.Payload["source"]; source != "legacy.json" {
+		t.Fatalf("legacy import source = %q, want legacy.json", source)
+	}
+	if fresh := target.Stacks[1]; fresh.PlanFile != "" {
+		t.Fatalf("imported PlanFile = %q, want none so a plan can be attached", fresh.PlanFile)
+	}
+}
+
+func TestImportLegacyOnlyFiltersStacks(t *testing.T) {
+	target := &Stacks{}
+	legacy := &stacks.State{Stacks: []stacks.Stack{
+		{Name: "one", Parts: []stacks.Part{{Index: 1, Slug: "a", Branch: "one/1/a"}}},
+		{Name: "two", Parts: []stacks.Part{{Index: 1, Slug: "a", Branch: "two/1/a"}}},
+	}}
+
+	results := ImportLegacy(target, legacy, LegacyImportOptions{Only: []string{"two"}})
+	if len(results) != 1 || results[0].Stack != "two" || len(target.Stacks) != 1 {
+		t.Fatalf("results = %+v, stacks = %+v", results, target.Stacks)
+	}
+}
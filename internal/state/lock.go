@@ -0,0 +1,237 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLockTimeout = 30 * time.Second
+	defaultStaleAfter  = 10 * time.Minute
+	lockPollInterval   = 50 * time.Millisecond
+
+	// unreadableLockGrace is how long an empty or unparseable lock is given
+	// before it is broken. Locks are linked into place fully written, so this
+	// only covers files left by older versions or a damaged filesystem.
+	unreadableLockGrace = 5 * time.Second
+)
+
+// LockTimeout bounds how long Update waits for another process to release the index lock.
+var LockTimeout = defaultLockTimeout
+
+// LockStaleAfter is the age after which a lock is considered abandoned even if its holder is alive.
+var LockStaleAfter = defaultStaleAfter
+
+type lockInfo struct {
+	PID        int    `json:"pid"`
+	Hostname   string `json:"hostname,omitempty"`
+	AcquiredAt string `json:"acquired_at"`
+}
+
+func LockPath(repoRoot string) string {
+	return StacksPath(repoRoot) + ".lock"
+}
+
+// Lock acquires the advisory lock guarding the stack index. It waits up to
+// LockTimeout, breaking locks whose holder has exited or that are older than
+// LockStaleAfter. The returned function releases the lock.
+func Lock(repoRoot string) (func(), error) {
+	path := LockPath(repoRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	info := lockInfo{
+		PID:      os.Getpid(),
+		Hostname: hostname,
+	}
+
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		info.AcquiredAt = time.Now().UTC().Format(time.RFC3339Nano)
+		acquired, err := tryCreateLock(path, info)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			held := info
+			return func() { releaseLock(path, held) }, nil
+		}
+
+		holder, gone, stale := inspectLock(path, hostname)
+		if gone {
+			continue
+		}
+		if stale {
+			breakStaleLock(path, holder)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			if holder.PID > 0 {
+				return nil, fmt.Errorf("timed out after %s waiting for state lock %s (held by pid %d since %s)", LockTimeout, path, holder.PID, holder.AcquiredAt)
+			}
+			return nil, fmt.Errorf("timed out after %s waiting for state lock %s", LockTimeout, path)
+		}
+
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// Update runs fn against the current stack index while holding the index lock
+// and saves the result when fn succeeds and changed something. Returning an
+// error from fn discards any changes it made.
+func Update(repoRoot string, fn func(*Stacks) error) error {
+	return UpdateAs(repoRoot, "", fn)
+}
+
+// UpdateAs is Update for a named actor (a CLI command or MCP tool). Events
+// recorded through Stacks.Record during fn are appended to the stack journals
+// after the index is saved, attributed to actor unless they name their own.
+func UpdateAs(repoRoot, actor string, fn func(*Stacks) error) error {
+	unlock, err := Lock(repoRoot)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stacks, err := LoadStacks(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	before, err := json.Marshal(stacks)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(stacks); err != nil {
+		return err
+	}
+
+	after, err := json.Marshal(stacks)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(before, after) {
+		if err := SaveStacks(repoRoot, stacks); err != nil {
+			return err
+		}
+	}
+
+	return flushJournal(repoRoot, actor, stacks.journal)
+}
+
+// tryCreateLock writes info to a temporary file and hard-links it to path,
+// so the lock never exists without its holder metadata.
+func tryCreateLock(path string, info lockInfo) (bool, error) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return false, fmt.Errorf("write state lock: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return false, fmt.Errorf("write state lock: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, fmt.Errorf("write state lock: %w", err)
+	}
+
+	if err := os.Link(tmpPath, path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("create state lock: %w", err)
+	}
+
+	return true, nil
+}
+
+// releaseLock removes the lock only if it still belongs to held, so a lock
+// that was broken as stale and re-taken by another process survives.
+func releaseLock(path string, held lockInfo) {
+	if current, err := readLock(path); err == nil && current == held {
+		_ = os.Remove(path)
+	}
+}
+
+// breakStaleLock removes the stale lock held by holder. The lock is first
+// renamed aside, which only one of several waiters can do, and removed only
+// if it still names holder: another waiter may have broken the stale lock
+// and taken it in the meantime, in which case its lock is put back.
+func breakStaleLock(path string, holder lockInfo) {
+	aside := fmt.Sprintf("%s.stale.%d.%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, aside); err != nil {
+		return
+	}
+	defer os.Remove(aside)
+
+	current, err := readLock(aside)
+	if err == nil && current == holder {
+		return
+	}
+	if err != nil && holder == (lockInfo{}) {
+		// The unreadable lock that was inspected.
+		return
+	}
+	_ = os.Link(aside, path)
+}
+
+func readLock(path string) (lockInfo, error) {
+	var info lockInfo
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, err
+	}
+
+	return info, nil
+}
+
+// inspectLock reports the current holder, whether the lock was released in
+// the meantime, and whether it is stale and can be broken.
+func inspectLock(path, hostname string) (holder lockInfo, gone bool, stale bool) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return lockInfo{}, os.IsNotExist(err), false
+	}
+	age := time.Since(fileInfo.ModTime())
+	expired := age > LockStaleAfter
+
+	holder, err = readLock(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return holder, true, false
+		}
+		// An empty or unparseable lock has no holder to wait for; give it a
+		// short grace period in case it is being replaced, then break it.
+		return holder, false, age > unreadableLockGrace
+	}
+	if expired {
+		return holder, false, true
+	}
+
+	sameHost := strings.TrimSpace(holder.Hostname) == "" || holder.Hostname == hostname
+	if sameHost && holder.PID > 0 && !processAlive(holder.PID) {
+		return holder, false, true
+	}
+
+	return holder, false, false
+}
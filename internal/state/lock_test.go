@@ -0,0 +1,201 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateSerializesConcurrentWriters(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := EnsureInitialized(repoRoot); err != nil {
+		t.Fatalf("EnsureInitialized: %v", err)
+	}
+
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- Update(repoRoot, func(s *Stacks) error {
+				s.Stacks = append(s.Stacks, Stack{Name: fmt.Sprintf("stack-%d", i)})
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+	}
+
+	stacks, err := LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatalf("LoadStacks: %v", err)
+	}
+	if len(stacks.Stacks) != writers {
+		t.Fatalf("len(Stacks) = %d, want %d (lost updates)", len(stacks.Stacks), writers)
+	}
+	if _, err := os.Stat(LockPath(repoRoot)); !os.IsNotExist(err) {
+		t.Fatalf("lock file still present after updates: %v", err)
+	}
+}
+
+func TestUpdateDiscardsChangesOnError(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := EnsureInitialized(repoRoot); err != nil {
+		t.Fatalf("EnsureInitialized: %v", err)
+	}
+
+	err := Update(repoRoot, func(s *Stacks) error {
+		s.Stacks = append(s.Stacks, Stack{Name: "discarded"})
+		return fmt.Errorf("boom")
+	})
+	if err == nil {
+		t.Fatal("expected error from Update")
+	}
+
+	stacks, err := LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatalf("LoadStacks: %v", err)
+	}
+	if len(stacks.Stacks) != 0 {
+		t.Fatalf("len(Stacks) = %d, want 0", len(stacks.Stacks))
+	}
+}
+
+func TestLockBreaksLockOfExitedProcess(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeTestLock(t, repoRoot, lockInfo{PID: 999999999, AcquiredAt: time.Now().UTC().Format(time.RFC3339Nano)})
+
+	unlock, err := Lock(repoRoot)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	unlock()
+}
+
+func TestLockTimesOutWhileHolderAlive(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeTestLock(t, repoRoot, lockInfo{PID: os.Getpid(), AcquiredAt: time.Now().UTC().Format(time.RFC3339Nano)})
+
+	previous := LockTimeout
+	LockTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { LockTimeout = previous })
+
+	if _, err := Lock(repoRoot); err == nil {
+		t.Fatal("expected timeout error while lock is held")
+	}
+}
+
+func TestLockBreaksExpiredLock(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeTestLock(t, repoRoot, lockInfo{PID: os.Getpid(), AcquiredAt: "2000-01-01T00:00:00Z"})
+
+	old := time.Now().Add(-2 * LockStaleAfter)
+	if err := os.Chtimes(LockPath(repoRoot), old, old); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	unlock, err := Lock(repoRoot)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	unlock()
+}
+
+func TestLockBreaksEmptyLockAfterGrace(t *testing.T) {
+	repoRoot := t.TempDir()
+	path := LockPath(repoRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+
+	previous := LockTimeout
+	LockTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { LockTimeout = previous })
+
+	if _, err := Lock(repoRoot); err == nil {
+		t.Fatal("expected fresh empty lock to be honoured during its grace period")
+	}
+
+	old := time.Now().Add(-2 * unreadableLockGrace)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	unlock, err := Lock(repoRoot)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	info, err := readLock(path)
+	if err != nil || info.PID != os.Getpid() {
+		t.Fatalf("readLock = %+v, %v; want lock held by this process", info, err)
+	}
+	unlock()
+}
+
+func TestBreakStaleLockKeepsLockRetakenByAnotherHolder(t *testing.T) {
+	repoRoot := t.TempDir()
+	stale := lockInfo{PID: 999999999, AcquiredAt: "2000-01-01T00:00:00Z"}
+	retaken := lockInfo{PID: os.Getpid(), AcquiredAt: time.Now().UTC().Format(time.RFC3339Nano)}
+	writeTestLock(t, repoRoot, retaken)
+
+	path := LockPath(repoRoot)
+	breakStaleLock(path, stale)
+	if info, err := readLock(path); err != nil || info != retaken {
+		t.Fatalf("readLock = %+v, %v; want the re-taken lock kept", info, err)
+	}
+
+	breakStaleLock(path, retaken)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("lock still exists after breaking it: %v", err)
+	}
+	if leftovers, _ := filepath.Glob(path + ".stale.*"); len(leftovers) != 0 {
+		t.Fatalf("stale lock files left behind: %v", leftovers)
+	}
+}
+
+func TestUnlockLeavesLockTakenByAnotherHolder(t *testing.T) {
+	repoRoot := t.TempDir()
+	unlock, err := Lock(repoRoot)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	// Simulate the lock being broken as stale and re-acquired elsewhere.
+	other := lockInfo{PID: 999999999, Hostname: "elsewhere", AcquiredAt: time.Now().UTC().Format(time.RFC3339Nano)}
+	writeTestLock(t, repoRoot, other)
+
+	unlock()
+	if got, err := readLock(LockPath(repoRoot)); err != nil || got != other {
+		t.Fatalf("readLock = %+v, %v; want other holder's lock kept", got, err)
+	}
+}
+
+func writeTestLock(t *testing.T, repoRoot string, info lockInfo) {
+	t.Helper()
+	path := LockPath(repoRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+}
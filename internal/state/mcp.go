@@ -0,0 +1,79 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MCPServer records a running `m mcp serve --http` daemon so spawned agents
+// can connect to it instead of launching their own stdio server.
+type MCPServer struct {
+	URL       string `json:"url"`
+	Token     string `json:"token,omitempty"`
+	PID       int    `json:"pid"`
+	StartedAt string `json:"started_at"`
+}
+
+func MCPServerPath(repoRoot string) string {
+	return filepath.Join(Dir(repoRoot), "mcp-server.json")
+}
+
+// SaveMCPServer records server for the repo. The file holds the bearer
+// token, so only the owner can read it.
+func SaveMCPServer(repoRoot string, server *MCPServer) error {
+	path := MCPServerPath(repoRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(server, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0o600)
+}
+
+// LoadMCPServer returns the recorded MCP daemon, or nil when none is
+// recorded or its process has exited.
+func LoadMCPServer(repoRoot string) (*MCPServer, error) {
+	data, err := os.ReadFile(MCPServerPath(repoRoot))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var server MCPServer
+	if err := json.Unmarshal(data, &server); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", MCPServerPath(repoRoot), err)
+	}
+	if server.URL == "" || !processAlive(server.PID) {
+		return nil, nil
+	}
+
+	return &server, nil
+}
+
+// RemoveMCPServer forgets the recorded daemon if it is the process pid.
+func RemoveMCPServer(repoRoot string, pid int) error {
+	data, err := os.ReadFile(MCPServerPath(repoRoot))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var server MCPServer
+	if err := json.Unmarshal(data, &server); err == nil && server.PID != pid {
+		return nil
+	}
+	return os.Remove(MCPServerPath(repoRoot))
+}
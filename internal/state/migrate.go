@@ -0,0 +1,247 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Migration upgrades a raw stack index document from version From to From+1.
+// Apply works on the decoded JSON rather than on Stacks so that it can read
+// shapes the current structs no longer describe. It returns a human-readable
+// note for every change it made.
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(doc map[string]any) ([]string, error)
+}
+
+// migrations must contain exactly one entry per version below SchemaVersion,
+// in order.
+var migrations = []Migration{
+	{
+		From:        0,
+		Description: "stamp schema version on unversioned index",
+		Apply:       func(doc map[string]any) ([]string, error) { return nil, nil },
+	},
+	{
+		From:        1,
+		Description: "make stage status explicit and normalize stack types",
+		Apply:       migrateExplicitStatus,
+	},
+}
+
+// MigrationStep is a migration that was (or would be) applied to an index.
+type MigrationStep struct {
+	From        int      `json:"from"`
+	To          int      `json:"to"`
+	Description string   `json:"description"`
+	Changes     []string `json:"changes"`
+}
+
+// MigrationResult describes the upgrade of an index file to SchemaVersion.
+type MigrationResult struct {
+	FromVersion int             `json:"from_version"`
+	ToVersion   int             `json:"to_version"`
+	Steps       []MigrationStep `json:"steps"`
+	// Backup is the path of the pre-migration copy, set once written.
+	Backup string `json:"backup,omitempty"`
+}
+
+// Pending reports whether the index needs migrating.
+func (r *MigrationResult) Pending() bool {
+	return r != nil && r.FromVersion < r.ToVersion
+}
+
+// BackupPath is where the index is copied before it is migrated away from
+// fromVersion.
+func BackupPath(repoRoot string, fromVersion int) string {
+	return fmt.Sprintf("%s.v%d.bak", StacksPath(repoRoot), fromVersion)
+}
+
+// PlanMigration reports which migrations the repo's index needs without
+// changing anything on disk.
+func PlanMigration(repoRoot string) (*MigrationResult, error) {
+	data, err := os.ReadFile(StacksPath(repoRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &MigrationResult{FromVersion: SchemaVersion, ToVersion: SchemaVersion}, nil
+		}
+		return nil, err
+	}
+
+	_, result, err := migrateDocument(data)
+	return result, err
+}
+
+// Migrate upgrades the repo's index to SchemaVersion under the state lock,
+// keeping a backup of the original file. It is a no-op for current indexes.
+func Migrate(repoRoot string) (*MigrationResult, error) {
+	unlock, err := Lock(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	path := StacksPath(repoRoot)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &MigrationResult{FromVersion: SchemaVersion, ToVersion: SchemaVersion}, nil
+		}
+		return nil, err
+	}
+
+	migrated, result, err := migrateDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Pending() {
+		return result, nil
+	}
+
+	var stacks Stacks
+	if err := json.Unmarshal(migrated, &stacks); err != nil {
+		return nil, fmt.Errorf("parse migrated stacks: %w", err)
+	}
+	if err := SaveStacks(repoRoot, &stacks); err != nil {
+		return nil, err
+	}
+	result.Backup = BackupPath(repoRoot, result.FromVersion)
+
+	return result, nil
+}
+
+// migrateDocument runs every migration the document needs and returns the
+// upgraded JSON. Documents newer than SchemaVersion are returned unchanged.
+func migrateDocument(data []byte) ([]byte, *MigrationResult, error) {
+	version, err := documentVersion(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &MigrationResult{FromVersion: version, ToVersion: SchemaVersion}
+	if version >= SchemaVersion {
+		result.ToVersion = version
+		return data, result, nil
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("parse stacks: %w", err)
+	}
+
+	for _, migration := range migrations {
+		if migration.From < version {
+			continue
+		}
+
+		changes, err := migration.Apply(doc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("migrate stacks v%d -> v%d: %w", migration.From, migration.From+1, err)
+		}
+		doc["version"] = migration.From + 1
+
+		result.Steps = append(result.Steps, MigrationStep{
+			From:        migration.From,
+			To:          migration.From + 1,
+			Description: migration.Description,
+			Changes:     changes,
+		})
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return migrated, result, nil
+}
+
+func documentVersion(data []byte) (int, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, fmt.Errorf("parse stacks: %w", err)
+	}
+	if header.Version < 0 {
+		return 0, fmt.Errorf("invalid stacks version %d", header.Version)
+	}
+
+	return header.Version, nil
+}
+
+// writeMigrationBackup copies the on-disk index aside before it is first
+// overwritten by a newer schema. An existing backup is never replaced, so the
+// oldest original survives repeated upgrades.
+func writeMigrationBackup(repoRoot string) error {
+	data, err := os.ReadFile(StacksPath(repoRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	version, err := documentVersion(data)
+	if err != nil || version >= SchemaVersion {
+		// Unreadable files are left for LoadStacks to report.
+		return nil
+	}
+
+	backup := BackupPath(repoRoot, version)
+	f, err := os.OpenFile(backup, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return fmt.Errorf("back up stacks before migration: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(backup)
+		return fmt.Errorf("back up stacks before migration: %w", err)
+	}
+
+	return f.Close()
+}
+
+func migrateExplicitStatus(doc map[string]any) ([]string, error) {
+	var changes []string
+
+	stacks, _ := doc["stacks"].([]any)
+	for _, rawStack := range stacks {
+		stack, ok := rawStack.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := stack["name"].(string)
+
+		if stackType, ok := stack["type"].(string); ok {
+			if normalized := NormalizeStackType(stackType); normalized != stackType {
+				stack["type"] = normalized
+				changes = append(changes, fmt.Sprintf("stack %q: type %q -> %q", name, stackType, normalized))
+			}
+		}
+
+		stages, _ := stack["stages"].([]any)
+		for _, rawStage := range stages {
+			stage, ok := rawStage.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			status, _ := stage["status"].(string)
+			if strings.TrimSpace(status) != "" {
+				continue
+			}
+			stage["status"] = StatusPending
+			id, _ := stage["id"].(string)
+			changes = append(changes, fmt.Sprintf("stack %q stage %q: status -> %s", name, id, StatusPending))
+		}
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,167 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const v1Index = `{
+  "version": 1,
+  "stacks": [
+    {
+      "name": "feature",
+      "type": " Feat ",
+      "plan_file": "plan.md",
+      "created_at": "2025-01-01T00:00:00Z",
+      "stages": [
+        {"id": "one", "title": "One", "status": "implementing"},
+        {"id": "two", "title": "Two"}
+      ]
+    }
+  ]
+}
+`
+
+func writeIndex(t *testing.T, repoRoot, content string) {
+	t.Helper()
+	path := StacksPath(repoRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+}
+
+func TestMigrationsCoverEveryVersion(t *testing.T) {
+	if len(migrations) != SchemaVersion {
+		t.Fatalf("len(migrations) = %d, want %d", len(migrations), SchemaVersion)
+	}
+	for i, migration := range migrations {
+		if migration.From != i {
+			t.Fatalf("migrations[%d].From = %d, want %d", i, migration.From, i)
+		}
+	}
+}
+
+func TestLoadStacksMigratesInMemory(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeIndex(t, repoRoot, v1Index)
+
+	stacks, err := LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatalf("LoadStacks: %v", err)
+	}
+	if stacks.Version != SchemaVersion {
+		t.Fatalf("Version = %d, want %d", stacks.Version, SchemaVersion)
+	}
+	stack := stacks.Stacks[0]
+	if stack.Type != "feat" {
+		t.Fatalf("Type = %q, want feat", stack.Type)
+	}
+	if stack.Stages[0].Status != StatusImplementing || stack.Stages[1].Status != StatusPending {
+		t.Fatalf("statuses = %q, %q", stack.Stages[0].Status, stack.Stages[1].Status)
+	}
+
+	data, err := os.ReadFile(StacksPath(repoRoot))
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	if string(data) != v1Index {
+		t.Fatal("expected LoadStacks to leave the file untouched")
+	}
+}
+
+func TestPlanMigrationDoesNotWrite(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeIndex(t, repoRoot, v1Index)
+
+	result, err := PlanMigration(repoRoot)
+	if err != nil {
+		t.Fatalf("PlanMigration: %v", err)
+	}
+	if !result.Pending() || result.FromVersion != 1 || len(result.Steps) != 1 {
+		t.Fatalf("result = %+v, want one pending step from v1", result)
+	}
+	changes := strings.Join(result.Steps[0].Changes, "\n")
+	if !strings.Contains(changes, `stage "two": status -> pending`) || !strings.Contains(changes, `type " Feat " -> "feat"`) {
+		t.Fatalf("unexpected changes:\n%s", changes)
+	}
+
+	if _, err := os.Stat(BackupPath(repoRoot, 1)); !os.IsNotExist(err) {
+		t.Fatalf("expected no backup on dry run, stat err = %v", err)
+	}
+}
+
+func TestMigrateWritesBackupAndUpgrades(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeIndex(t, repoRoot, `{"stacks": []}`)
+
+	result, err := Migrate(repoRoot)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if result.FromVersion != 0 || len(result.Steps) != 2 {
+		t.Fatalf("result = %+v, want two steps from v0", result)
+	}
+
+	backup, err := os.ReadFile(result.Backup)
+	if err != nil {
+		t.Fatalf("read backup: %v", err)
+	}
+	if string(backup) != `{"stacks": []}` {
+		t.Fatalf("backup = %q, want original content", backup)
+	}
+
+	again, err := Migrate(repoRoot)
+	if err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+	if again.Pending() {
+		t.Fatalf("expected index to be current after migrating, got %+v", again)
+	}
+}
+
+func TestUpdateBacksUpOlderIndex(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeIndex(t, repoRoot, v1Index)
+
+	err := Update(repoRoot, func(s *Stacks) error {
+		s.Stacks[0].CurrentStage = "one"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	backup, err := os.ReadFile(BackupPath(repoRoot, 1))
+	if err != nil {
+		t.Fatalf("read backup: %v", err)
+	}
+	if string(backup) != v1Index {
+		t.Fatal("expected backup to hold the v1 index")
+	}
+}
+
+func TestSaveStacksRefusesNewerIndex(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeIndex(t, repoRoot, `{"version": 99, "stacks": [{"name": "future"}]}`)
+
+	stacks, err := LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatalf("LoadStacks: %v", err)
+	}
+	if stacks.Version != 99 || stacks.Stacks[0].Name != "future" {
+		t.Fatalf("expected newer index to load read-only, got %+v", stacks)
+	}
+
+	err = Update(repoRoot, func(s *Stacks) error {
+		s.Stacks[0].CurrentStage = "x"
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "upgrade m") {
+		t.Fatalf("Update error = %v, want refusal to write newer index", err)
+	}
+}
@@ -0,0 +1,22 @@
+package state
+
+// SetPaused pauses or resumes the automated pipeline for a stack and
+// journals the change. It is a no-op when the stack is already in that state.
+func SetPaused(stacks *Stacks, stackName string, paused bool) error {
+	stack, _ := FindStack(stacks, stackName)
+	if stack == nil {
+		return StackNotFoundError(stackName)
+	}
+	if stack.Paused == paused {
+		return nil
+	}
+
+	stack.Paused = paused
+	eventType := EventResume
+	if paused {
+		eventType = EventPause
+	}
+	stacks.Record(stack.Name, Event{Type: eventType})
+
+	return nil
+}
@@ -0,0 +1,82 @@
+//go:build !windows
+
+package state
+
+import (
+	"errors"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// TerminateProcess asks pid to exit.
+func TerminateProcess(pid int) error {
+	if pid <= 0 {
+		return nil
+	}
+
+	err := syscall.Kill(pid, syscall.SIGTERM)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
+
+// processStartTime estimates when pid started from its elapsed time as
+// reported by ps, which is available on Linux and macOS alike.
+func processStartTime(pid int) (time.Time, bool) {
+	out, err := exec.Command("ps", "-o", "etime=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return time.Time{}, false
+	}
+	elapsed, ok := parseElapsed(strings.TrimSpace(string(out)))
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Now().Add(-elapsed), true
+}
+
+// maxElapsedDays is the longest elapsed time a time.Duration can hold.
+const maxElapsedDays = int(math.MaxInt64 / int64(24*time.Hour))
+
+// parseElapsed parses ps etime output: [[dd-]hh:]mm:ss. Day counts too large
+// for a duration are rejected: procps wraps around to one for a process that
+// started within the current clock tick.
+func parseElapsed(value string) (time.Duration, bool) {
+	var days int
+	if before, after, found := strings.Cut(value, "-"); found {
+		d, err := strconv.Atoi(before)
+		if err != nil || d < 0 || d >= maxElapsedDays {
+			return 0, false
+		}
+		days, value = d, after
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	seconds := 0
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, false
+		}
+		seconds = seconds*60 + n
+	}
+
+	return time.Duration(days)*24*time.Hour + time.Duration(seconds)*time.Second, true
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseElapsed(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{value: "00:07", want: 7 * time.Second, ok: true},
+		{value: "01:02:03", want: time.Hour + 2*time.Minute + 3*time.Second, ok: true},
+		{value: "2-00:00:01", want: 48*time.Hour + time.Second, ok: true},
+		// procps prints this for a process started within the current tick.
+		{value: "441077234-00:18:40", ok: false},
+		{value: "7", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseElapsed(tt.value)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseElapsed(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
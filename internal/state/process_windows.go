@@ -0,0 +1,40 @@
+//go:build windows
+
+package state
+
+import (
+	"os"
+	"time"
+)
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = proc.Release()
+
+	return true
+}
+
+// TerminateProcess asks pid to exit.
+func TerminateProcess(pid int) error {
+	if pid <= 0 {
+		return nil
+	}
+
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+	return proc.Kill()
+}
+
+// processStartTime is not implemented on Windows; PIDs are trusted as is.
+func processStartTime(pid int) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,129 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/errs"
+)
+
+// Recovery actions recorded on the transitions they make.
+const (
+	ActionRetry = "retry"
+	ActionReset = "reset"
+	ActionSkip  = "skip"
+)
+
+// RetryStage moves a failed or blocked stage back to the phase it stopped in
+// (implementing or ai-review) and returns that status. A stage whose agent
+// exited without reporting is retried in its current phase. Review rounds
+// start over so a retried review is not failed by the rounds already spent.
+func RetryStage(stacks *Stacks, stackName, stageID string) (string, error) {
+	stack, stage, err := findStackStage(stacks, stackName, stageID)
+	if err != nil {
+		return "", err
+	}
+
+	from := EffectiveStatus(stage)
+	var to string
+	switch lost, _ := AgentLost(stage); {
+	case StageStopped(stage):
+		to = stage.StoppedFrom
+		if to != StatusAIReview {
+			to = StatusImplementing
+		}
+	case lost:
+		to = from
+	default:
+		return "", errs.New(errs.InvalidTransition, "stage %q is %s; only failed or blocked stages, or stages whose agent exited, can be retried", stageID, from)
+	}
+
+	stage.ReviewRounds = 0
+	setStageStatus(stacks, stack, stage, from, to, map[string]string{"action": ActionRetry})
+	return to, nil
+}
+
+// resettableStatuses are the statuses ResetStage may move back to pending.
+// Done stages have been merged and stay done.
+var resettableStatuses = []string{
+	StatusImplementing,
+	StatusAIReview,
+	StatusHumanReview,
+	StatusFailed,
+	StatusBlocked,
+}
+
+// ResetStage moves a stage back to pending, clearing its run history so the
+// pipeline starts it from scratch. A running agent on this host is
+// terminated first, since its report would otherwise be taken for the next
+// attempt's. Agent reports are kept.
+func ResetStage(stacks *Stacks, stackName, stageID, to string) error {
+	if to != StatusPending {
+		return errs.New(errs.Usage, "stages can only be reset to %s, got %q", StatusPending, to)
+	}
+
+	stack, stage, err := findStackStage(stacks, stackName, stageID)
+	if err != nil {
+		return err
+	}
+
+	from := EffectiveStatus(stage)
+	if !slices.Contains(resettableStatuses, from) {
+		return errs.New(errs.InvalidTransition, "stage %q is %s; only %s stages can be reset", stageID, from, strings.Join(resettableStatuses, ", "))
+	}
+
+	if AgentRunning(stage.Agent) && AgentOnThisHost(stage.Agent) {
+		if err := TerminateAgent(stage.Agent); err != nil {
+			return fmt.Errorf("stop agent pid %d of stage %q: %w", stage.Agent.PID, stageID, err)
+		}
+	}
+
+	setStageStatus(stacks, stack, stage, from, StatusPending, map[string]string{"action": ActionReset})
+	stage.StartedAt = ""
+	stage.ReviewedAt = ""
+	stage.ReviewRounds = 0
+	stage.TimeoutRetries = 0
+	stage.Agent = nil
+	return nil
+}
+
+// skippableStatuses are the statuses SkipStage may mark done.
+var skippableStatuses = []string{StatusPending, StatusFailed, StatusBlocked}
+
+// SkipStage marks a pending, failed or blocked stage done without running it
+// so stages depending on it can start.
+func SkipStage(stacks *Stacks, stackName, stageID, reason string) error {
+	stack, stage, err := findStackStage(stacks, stackName, stageID)
+	if err != nil {
+		return err
+	}
+
+	from := EffectiveStatus(stage)
+	if !slices.Contains(skippableStatuses, from) {
+		return errs.New(errs.InvalidTransition, "stage %q is %s; only %s stages can be skipped", stageID, from, strings.Join(skippableStatuses, ", "))
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		reason = "skipped"
+	}
+
+	setStageStatus(stacks, stack, stage, from, StatusDone, map[string]string{"action": ActionSkip, "reason": reason})
+	stage.StatusReason = reason
+	return nil
+}
+
+func findStackStage(stacks *Stacks, stackName, stageID string) (*Stack, *Stage, error) {
+	stack, _ := FindStack(stacks, stackName)
+	if stack == nil {
+		return nil, nil, StackNotFoundError(stackName)
+	}
+
+	stage, _ := FindStage(stack, stageID)
+	if stage == nil {
+		return nil, nil, fmt.Errorf("stage %q not found in stack %q", stageID, stackName)
+	}
+
+	return stack, stage, nil
+}
@@ -0,0 +1,78 @@
+package state
+
+import "testing"
+
+func recoveryStacks(status, stoppedFrom string) *Stacks {
+	return &Stacks{Stacks: []Stack{{
+		Name: "s",
+		Stages: []Stage{{
+			ID:           "a",
+			Status:       status,
+			StoppedFrom:  stoppedFrom,
+			StatusReason: "boom",
+			ReviewRounds: 3,
+			StartedAt:    "2026-01-02T03:04:05Z",
+		}},
+	}}}
+}
+
+func TestRetryStage(t *testing.T) {
+	stacks := recoveryStacks(StatusFailed, StatusAIReview)
+	to, err := RetryStage(stacks, "s", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage := stacks.Stacks[0].Stages[0]
+	if to != StatusAIReview || stage.Status != StatusAIReview {
+		t.Fatalf("retry moved stage to %q (returned %q), want ai-review", stage.Status, to)
+	}
+	if stage.ReviewRounds != 0 || stage.StatusReason != "" || stage.StoppedFrom != "" {
+		t.Fatalf("retry did not clear stop details: %+v", stage)
+	}
+
+	stacks = recoveryStacks(StatusBlocked, StatusImplementing)
+	if to, err := RetryStage(stacks, "s", "a"); err != nil || to != StatusImplementing {
+		t.Fatalf("RetryStage(blocked) = %q, %v; want implementing", to, err)
+	}
+
+	stacks = recoveryStacks(StatusHumanReview, "")
+	if _, err := RetryStage(stacks, "s", "a"); err == nil {
+		t.Fatal("expected retry of a human-review stage to fail")
+	}
+}
+
+func TestResetStage(t *testing.T) {
+	stacks := recoveryStacks(StatusFailed, StatusImplementing)
+	if err := ResetStage(stacks, "s", "a", StatusPending); err != nil {
+		t.Fatal(err)
+	}
+	stage := stacks.Stacks[0].Stages[0]
+	if stage.Status != StatusPending || stage.StartedAt != "" || stage.ReviewRounds != 0 {
+		t.Fatalf("reset left stage as %+v", stage)
+	}
+
+	if err := ResetStage(recoveryStacks(StatusFailed, ""), "s", "a", StatusImplementing); err == nil {
+		t.Fatal("expected reset to a status other than pending to fail")
+	}
+	if err := ResetStage(recoveryStacks(StatusDone, ""), "s", "a", StatusPending); err == nil {
+		t.Fatal("expected reset of a done stage to fail")
+	}
+	if err := ResetStage(recoveryStacks(StatusPending, ""), "s", "a", StatusPending); err == nil {
+		t.Fatal("expected reset of a pending stage to fail")
+	}
+}
+
+func TestSkipStage(t *testing.T) {
+	stacks := recoveryStacks(StatusBlocked, StatusImplementing)
+	if err := SkipStage(stacks, "s", "a", ""); err != nil {
+		t.Fatal(err)
+	}
+	stage := stacks.Stacks[0].Stages[0]
+	if stage.Status != StatusDone || stage.StatusReason != "skipped" {
+		t.Fatalf("skip left stage as status %q reason %q", stage.Status, stage.StatusReason)
+	}
+
+	if err := SkipStage(recoveryStacks(StatusImplementing, ""), "s", "a", ""); err == nil {
+		t.Fatal("expected skip of an implementing stage to fail")
+	}
+}
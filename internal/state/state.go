@@ -5,24 +5,43 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/mlawd/m-cli/internal/errs"
 )
 
-const schemaVersion = 1
+// SchemaVersion is the stack index version this build reads and writes.
+// Older indexes are upgraded through the migrations in migrate.go.
+const SchemaVersion = 2
 
 type Stacks struct {
 	Version int     `json:"version"`
 	Stacks  []Stack `json:"stacks"`
+
+	journal []journalEntry
 }
 
 type Stack struct {
-	Name         string  `json:"name"`
-	Type         string  `json:"type,omitempty"`
-	PlanFile     string  `json:"plan_file"`
-	CreatedAt    string  `json:"created_at"`
-	CurrentStage string  `json:"current_stage,omitempty"`
-	Stages       []Stage `json:"stages"`
+	Name         string `json:"name"`
+	Type         string `json:"type,omitempty"`
+	PlanFile     string `json:"plan_file"`
+	CreatedAt    string `json:"created_at"`
+	CurrentStage string `json:"current_stage,omitempty"`
+	// DependencyGraph is set for stacks whose stages declare depends_on
+	// (plan version 4); see StageDependencies.
+	DependencyGraph bool `json:"dependency_graph,omitempty"`
+	// Parallel is the number of stages the automated pipeline may run at
+	// once; zero means one.
+	Parallel int `json:"parallel,omitempty"`
+	// Paused stops the automated pipeline from starting more stages;
+	// agents already running finish their phase.
+	Paused bool    `json:"paused,omitempty"`
+	Stages []Stage `json:"stages"`
+	// Sync is set while an interactive `m stack sync` is stopped at a
+	// rebase conflict.
+	Sync *SyncCheckpoint `json:"sync,omitempty"`
 }
 
 // Stage status constants.
@@ -32,22 +51,33 @@ const (
 	StatusAIReview     = "ai-review"
 	StatusHumanReview  = "human-review"
 	StatusDone         = "done"
+	StatusFailed       = "failed"
+	StatusBlocked      = "blocked"
 )
 
 type Stage struct {
-	ID             string      `json:"id"`
-	Title          string      `json:"title"`
-	Outcome        string      `json:"outcome,omitempty"`
-	Implementation []string    `json:"implementation,omitempty"`
-	Validation     []string    `json:"validation,omitempty"`
-	Risks          []StageRisk `json:"risks,omitempty"`
-	Context        string      `json:"context,omitempty"`
-	Branch         string      `json:"branch,omitempty"`
-	Worktree       string      `json:"worktree,omitempty"`
-	Parent         string      `json:"parent_branch,omitempty"`
-	Status         string      `json:"status,omitempty"`
-	StartedAt      string      `json:"started_at,omitempty"`
-	ReviewedAt     string      `json:"reviewed_at,omitempty"`
+	ID             string            `json:"id"`
+	Title          string            `json:"title"`
+	Outcome        string            `json:"outcome,omitempty"`
+	Implementation []string          `json:"implementation,omitempty"`
+	Validation     []string          `json:"validation,omitempty"`
+	Risks          []StageRisk       `json:"risks,omitempty"`
+	Context        string            `json:"context,omitempty"`
+	DependsOn      []string          `json:"depends_on,omitempty"`
+	Timeouts       map[string]string `json:"timeouts,omitempty"`
+	Model          string            `json:"model,omitempty"`
+	Branch         string            `json:"branch,omitempty"`
+	Worktree       string            `json:"worktree,omitempty"`
+	Parent         string            `json:"parent_branch,omitempty"`
+	Status         string            `json:"status,omitempty"`
+	StartedAt      string            `json:"started_at,omitempty"`
+	ReviewedAt     string            `json:"reviewed_at,omitempty"`
+	ReviewRounds   int               `json:"review_rounds,omitempty"`
+	TimeoutRetries int               `json:"timeout_retries,omitempty"`
+	StatusReason   string            `json:"status_reason,omitempty"`
+	StoppedFrom    string            `json:"stopped_from,omitempty"`
+	Agent          *AgentProcess     `json:"agent,omitempty"`
+	Reports        []StageReport     `json:"reports,omitempty"`
 }
 
 type StageRisk struct {
@@ -55,10 +85,46 @@ type StageRisk struct {
 	Mitigation string `json:"mitigation"`
 }
 
+// Agent phases reported through report_stage_done.
+const (
+	PhaseImplementing = "implementing"
+	PhaseAIReview     = "ai_review"
+)
+
+// Review outcomes reported for the ai_review phase.
+const (
+	OutcomeApproved         = "approved"
+	OutcomeChangesRequested = "changes_requested"
+)
+
+// StageReport is a phase completion report from an agent, kept in order.
+// Review reports carry the reviewer's outcome and, when changes were
+// requested, the findings handed back to the build agent.
+type StageReport struct {
+	Phase      string `json:"phase"`
+	Summary    string `json:"summary,omitempty"`
+	Outcome    string `json:"outcome,omitempty"`
+	Findings   string `json:"findings,omitempty"`
+	ReportedAt string `json:"reported_at"`
+	Commit     string `json:"commit,omitempty"`
+}
+
 func Dir(repoRoot string) string {
 	return filepath.Join(repoRoot, ".m")
 }
 
+// Initialized reports whether `m init` (or any command that writes state) has
+// created the state directory.
+func Initialized(repoRoot string) (bool, error) {
+	if _, err := os.Stat(Dir(repoRoot)); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 func StacksPath(repoRoot string) string {
 	return filepath.Join(Dir(repoRoot), "stacks", "index.json")
 }
@@ -87,7 +153,17 @@ func EnsureInitialized(repoRoot string) error {
 
 	stacksPath := StacksPath(repoRoot)
 	if _, err := os.Stat(stacksPath); os.IsNotExist(err) {
-		if err := writeJSONAtomic(stacksPath, defaultStacks()); err != nil {
+		unlock, err := Lock(repoRoot)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		// Re-check under the lock so a concurrent init cannot clobber an
+		// index another process has already written.
+		if _, err := os.Stat(stacksPath); os.IsNotExist(err) {
+			return writeJSONAtomic(stacksPath, defaultStacks())
+		} else if err != nil {
 			return err
 		}
 	} else if err != nil {
@@ -107,13 +183,15 @@ func LoadStacks(repoRoot string) (*Stacks, error) {
 		return nil, err
 	}
 
+	data, _, err = migrateDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
 	var s Stacks
 	if err := json.Unmarshal(data, &s); err != nil {
 		return nil, fmt.Errorf("parse stacks: %w", err)
 	}
-	if s.Version == 0 {
-		s.Version = schemaVersion
-	}
 	if s.Stacks == nil {
 		s.Stacks = []Stack{}
 	}
@@ -121,13 +199,19 @@ func LoadStacks(repoRoot string) (*Stacks, error) {
 	return &s, nil
 }
 
+// SaveStacks writes the index at SchemaVersion. It refuses to overwrite an
+// index written by a newer m, and backs up an older one before replacing it.
 func SaveStacks(repoRoot string, s *Stacks) error {
-	if s.Version == 0 {
-		s.Version = schemaVersion
+	if s.Version > SchemaVersion {
+		return fmt.Errorf("stack index is version %d but this m only supports up to version %d; upgrade m before changing stacks", s.Version, SchemaVersion)
 	}
+	s.Version = SchemaVersion
 	if s.Stacks == nil {
 		s.Stacks = []Stack{}
 	}
+	if err := writeMigrationBackup(repoRoot); err != nil {
+		return err
+	}
 	return writeJSONAtomic(StacksPath(repoRoot), s)
 }
 
@@ -255,7 +339,7 @@ func IsLinkedWorktree(worktreePath, repoRoot string) bool {
 }
 
 func defaultStacks() *Stacks {
-	return &Stacks{Version: schemaVersion, Stacks: []Stack{}}
+	return &Stacks{Version: SchemaVersion, Stacks: []Stack{}}
 }
 
 func writeJSONAtomic(path string, value any) error {
@@ -269,12 +353,33 @@ func writeJSONAtomic(path string, value any) error {
 	}
 	data = append(data, '\n')
 
-	tmpPath := path + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
 		return err
 	}
 
-	return os.Rename(tmpPath, path)
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
 }
 
 func pathEqual(a, b string) bool {
@@ -318,23 +423,38 @@ func EffectiveStatus(stage *Stage) string {
 	return s
 }
 
-var allowedTransitions = map[string]string{
-	StatusPending:      StatusImplementing,
-	StatusImplementing: StatusAIReview,
-	StatusAIReview:     StatusHumanReview,
-	StatusHumanReview:  StatusDone,
+// allowedTransitions lists the statuses each status may move to in the
+// pipeline. A review that requests changes sends the stage back to
+// implementing; agent phases can fail or be blocked. Recovery out of failed
+// and blocked goes through RetryStage, ResetStage and SkipStage.
+var allowedTransitions = map[string][]string{
+	StatusPending:      {StatusImplementing},
+	StatusImplementing: {StatusAIReview, StatusFailed, StatusBlocked},
+	StatusAIReview:     {StatusHumanReview, StatusImplementing, StatusFailed, StatusBlocked},
+	StatusHumanReview:  {StatusDone},
 }
 
 // ValidTransition returns true if from → to is an allowed status transition.
 func ValidTransition(from, to string) bool {
-	return allowedTransitions[from] == to
+	return slices.Contains(allowedTransitions[from], to)
+}
+
+// StackNotFoundError reports that no stack is named name.
+func StackNotFoundError(name string) error {
+	return errs.New(errs.StackNotFound, "stack %q not found", name)
 }
 
 // TransitionStage transitions a stage to the given status, enforcing valid transitions.
 func TransitionStage(stacks *Stacks, stackName, stageID, toStatus string) error {
+	return TransitionStageWith(stacks, stackName, stageID, toStatus, nil)
+}
+
+// TransitionStageWith is TransitionStage with extra details (such as an agent
+// summary) attached to the journaled transition event.
+func TransitionStageWith(stacks *Stacks, stackName, stageID, toStatus string, details map[string]string) error {
 	stack, _ := FindStack(stacks, stackName)
 	if stack == nil {
-		return fmt.Errorf("stack %q not found", stackName)
+		return StackNotFoundError(stackName)
 	}
 
 	stage, _ := FindStage(stack, stageID)
@@ -344,11 +464,30 @@ func TransitionStage(stacks *Stacks, stackName, stageID, toStatus string) error
 
 	from := EffectiveStatus(stage)
 	if !ValidTransition(from, toStatus) {
-		return fmt.Errorf("invalid transition: %s -> %s for stage %q", from, toStatus, stageID)
+		return errs.New(errs.InvalidTransition, "invalid transition: %s -> %s for stage %q", from, toStatus, stageID)
 	}
 
+	setStageStatus(stacks, stack, stage, from, toStatus, details)
+	return nil
+}
+
+// setStageStatus moves stage to toStatus without checking the transition,
+// journals it and maintains the status timestamps and stop reason.
+func setStageStatus(stacks *Stacks, stack *Stack, stage *Stage, from, toStatus string, details map[string]string) {
 	stage.Status = toStatus
 
+	payload := map[string]string{"from": from, "to": toStatus}
+	for key, value := range details {
+		if strings.TrimSpace(value) != "" {
+			payload[key] = value
+		}
+	}
+	stacks.Record(stack.Name, Event{
+		Type:    EventStageTransition,
+		Stage:   stage.ID,
+		Payload: payload,
+	})
+
 	now := time.Now().UTC().Format(time.RFC3339)
 	if toStatus == StatusImplementing {
 		stage.StartedAt = now
@@ -356,18 +495,80 @@ func TransitionStage(stacks *Stacks, stackName, stageID, toStatus string) error
 	if toStatus == StatusHumanReview {
 		stage.ReviewedAt = now
 	}
+	if StageStopped(stage) {
+		stage.StatusReason = strings.TrimSpace(details["reason"])
+		stage.StoppedFrom = from
+	} else {
+		stage.StatusReason = ""
+		stage.StoppedFrom = ""
+	}
+}
+
+// StageStopped reports whether the pipeline stopped on stage because it
+// failed or was blocked.
+func StageStopped(stage *Stage) bool {
+	status := EffectiveStatus(stage)
+	return status == StatusFailed || status == StatusBlocked
+}
+
+// AddStageReport appends a phase report to the stage's history and returns
+// it so callers can fill in review details.
+func AddStageReport(stage *Stage, phase, summary, commit string) *StageReport {
+	if stage == nil {
+		return nil
+	}
+
+	stage.Reports = append(stage.Reports, StageReport{
+		Phase:      phase,
+		Summary:    strings.TrimSpace(summary),
+		ReportedAt: time.Now().UTC().Format(time.RFC3339),
+		Commit:     strings.TrimSpace(commit),
+	})
+	return &stage.Reports[len(stage.Reports)-1]
+}
+
+// PendingReviewFindings returns the findings of the latest review when it
+// requested changes, or "" otherwise.
+func PendingReviewFindings(stage *Stage) string {
+	report := LatestReport(stage, PhaseAIReview)
+	if report == nil || report.Outcome != OutcomeChangesRequested {
+		return ""
+	}
+	return report.Findings
+}
+
+// LatestReport returns the most recent report for phase, or nil if none.
+func LatestReport(stage *Stage, phase string) *StageReport {
+	if stage == nil {
+		return nil
+	}
+
+	for i := len(stage.Reports) - 1; i >= 0; i-- {
+		if stage.Reports[i].Phase == phase {
+			return &stage.Reports[i]
+		}
+	}
 
 	return nil
 }
 
-// NextPendingStage returns the first stage with pending status, or nil if none.
+// NextPendingStage returns the first pending stage whose dependencies are
+// complete, or nil if none is ready.
 func NextPendingStage(stack *Stack) *Stage {
+	if ready := ReadyStages(stack); len(ready) > 0 {
+		return ready[0]
+	}
+	return nil
+}
+
+// HasPendingStages reports whether any stage is still pending, ready or not.
+func HasPendingStages(stack *Stack) bool {
 	for i := range stack.Stages {
 		if EffectiveStatus(&stack.Stages[i]) == StatusPending {
-			return &stack.Stages[i]
+			return true
 		}
 	}
-	return nil
+	return false
 }
 
 // AllStagesComplete returns true if all stages are in human-review or done status.
@@ -88,6 +88,9 @@ func TestValidTransition(t *testing.T) {
 		{StatusPending, StatusImplementing},
 		{StatusImplementing, StatusAIReview},
 		{StatusAIReview, StatusHumanReview},
+		{StatusAIReview, StatusImplementing},
+		{StatusAIReview, StatusFailed},
+		{StatusImplementing, StatusFailed},
 		{StatusHumanReview, StatusDone},
 	}
 	for _, tc := range valid {
@@ -101,6 +104,8 @@ func TestValidTransition(t *testing.T) {
 		{StatusPending, StatusDone},
 		{StatusImplementing, StatusDone},
 		{StatusDone, StatusPending},
+		{StatusHumanReview, StatusImplementing},
+		{StatusFailed, StatusHumanReview},
 	}
 	for _, tc := range invalid {
 		if ValidTransition(tc.from, tc.to) {
@@ -197,3 +202,58 @@ func TestAllStagesComplete(t *testing.T) {
 		t.Fatal("expected not all complete")
 	}
 }
+
+func TestLatestReport(t *testing.T) {
+	stage := &Stage{ID: "s1"}
+	if LatestReport(stage, PhaseImplementing) != nil {
+		t.Fatal("expected no report on a fresh stage")
+	}
+
+	AddStageReport(stage, PhaseImplementing, "  first pass  ", "abc123")
+	AddStageReport(stage, PhaseAIReview, "looks good", "")
+	AddStageReport(stage, PhaseImplementing, "second pass", "def456")
+
+	if len(stage.Reports) != 3 {
+		t.Fatalf("len(Reports) = %d, want 3", len(stage.Reports))
+	}
+	if stage.Reports[0].Summary != "first pass" {
+		t.Fatalf("summary = %q, want trimmed text", stage.Reports[0].Summary)
+	}
+	if stage.Reports[0].ReportedAt == "" {
+		t.Fatal("expected reported_at to be set")
+	}
+
+	latest := LatestReport(stage, PhaseImplementing)
+	if latest == nil || latest.Summary != "second pass" || latest.Commit != "def456" {
+		t.Fatalf("LatestReport(implementing) = %+v, want second pass at def456", latest)
+	}
+	if review := LatestReport(stage, PhaseAIReview); review == nil || review.Summary != "looks good" {
+		t.Fatalf("LatestReport(ai_review) = %+v, want looks good", review)
+	}
+}
+
+func TestPendingReviewFindings(t *testing.T) {
+	stage := &Stage{ID: "s1"}
+	AddStageReport(stage, PhaseAIReview, "approved", "").Outcome = OutcomeApproved
+	if got := PendingReviewFindings(stage); got != "" {
+		t.Fatalf("PendingReviewFindings() = %q, want none after approval", got)
+	}
+
+	report := AddStageReport(stage, PhaseAIReview, "needs rework", "")
+	report.Outcome = OutcomeChangesRequested
+	report.Findings = "validate input before saving"
+	if got := PendingReviewFindings(stage); got != "validate input before saving" {
+		t.Fatalf("PendingReviewFindings() = %q, want the latest findings", got)
+	}
+}
+
+func TestTransitionStageToFailedRecordsReason(t *testing.T) {
+	stacks := &Stacks{Stacks: []Stack{{Name: "s", Stages: []Stage{{ID: "a", Status: StatusAIReview}}}}}
+
+	if err := TransitionStageWith(stacks, "s", "a", StatusFailed, map[string]string{"reason": "too many review rounds"}); err != nil {
+		t.Fatal(err)
+	}
+	if stage := stacks.Stacks[0].Stages[0]; stage.StatusReason != "too many review rounds" {
+		t.Fatalf("StatusReason = %q", stage.StatusReason)
+	}
+}
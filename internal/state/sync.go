@@ -0,0 +1,48 @@
+package state
+
+// SyncCheckpoint records an interactive stack sync that stopped at a rebase
+// conflict, so that it can be continued or rolled back.
+type SyncCheckpoint struct {
+	StartedAt string `json:"started_at"`
+	// NoPrune and Merged carry the pruning decision of the interrupted run:
+	// Merged lists the stage branches whose PRs were merged.
+	NoPrune bool     `json:"no_prune,omitempty"`
+	Merged  []string `json:"merged,omitempty"`
+	// RebasedOnto maps each stage handled so far to the branch its
+	// dependents build on.
+	RebasedOnto map[string]string `json:"rebased_onto"`
+	// Stages are the stage branches the sync has rebased, in order; the last
+	// one is stopped at the conflict.
+	Stages []SyncStage `json:"stages"`
+	// Integrations are the integration branches the sync has rebuilt.
+	Integrations []SyncIntegration `json:"integrations,omitempty"`
+}
+
+// SyncStage is a stage branch rebased by an interactive sync. Head and Parent
+// are what an abort restores, and CreatedWorktree, if set, is the worktree
+// the sync added for the rebase, which an abort removes.
+type SyncStage struct {
+	Stage           string `json:"stage"`
+	Branch          string `json:"branch"`
+	Worktree        string `json:"worktree"`
+	Onto            string `json:"onto"`
+	Mode            string `json:"mode"`
+	Head            string `json:"head"`
+	Parent          string `json:"parent_branch,omitempty"`
+	CreatedWorktree string `json:"created_worktree,omitempty"`
+}
+
+// SyncIntegration is an integration branch rebuilt by an interactive sync.
+// Head is its commit from before the sync, or "" when the sync created it.
+type SyncIntegration struct {
+	Branch string `json:"branch"`
+	Head   string `json:"head,omitempty"`
+}
+
+// Conflict returns the stage the sync is stopped at, or nil.
+func (c *SyncCheckpoint) Conflict() *SyncStage {
+	if c == nil || len(c.Stages) == 0 {
+		return nil
+	}
+	return &c.Stages[len(c.Stages)-1]
+}
@@ -0,0 +1,303 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Change types reported by Diff and Watch.
+const (
+	ChangeStackAdded   = "stack_added"
+	ChangeStackRemoved = "stack_removed"
+	ChangeStackPaused  = "stack_paused"
+	ChangeStackResumed = "stack_resumed"
+	ChangeStageAdded   = "stage_added"
+	ChangeStageRemoved = "stage_removed"
+	ChangeStageStatus  = "stage_status"
+	ChangeAgentStarted = "agent_started"
+	ChangeAgentExited  = "agent_exited"
+)
+
+// DefaultPollInterval is how often Watch checks the index when file
+// notifications are unavailable.
+const DefaultPollInterval = time.Second
+
+// watchDebounce coalesces the burst of events an atomic save produces.
+const watchDebounce = 50 * time.Millisecond
+
+// Change is a typed difference between two snapshots of the stack index.
+// For ChangeStageStatus, From and To are the old and new status; for agent
+// changes, From is the agent phase and To its pid or exit code.
+type Change struct {
+	Type  string
+	Stack string
+	Stage string
+	From  string
+	To    string
+}
+
+func (c Change) String() string {
+	switch c.Type {
+	case ChangeStackAdded:
+		return fmt.Sprintf("stack %s added", c.Stack)
+	case ChangeStackRemoved:
+		return fmt.Sprintf("stack %s removed", c.Stack)
+	case ChangeStackPaused:
+		return fmt.Sprintf("stack %s paused", c.Stack)
+	case ChangeStackResumed:
+		return fmt.Sprintf("stack %s resumed", c.Stack)
+	case ChangeStageAdded:
+		return fmt.Sprintf("stage %s added to %s", c.Stage, c.Stack)
+	case ChangeStageRemoved:
+		return fmt.Sprintf("stage %s removed from %s", c.Stage, c.Stack)
+	case ChangeStageStatus:
+		return fmt.Sprintf("stage %s moved %s → %s", c.Stage, c.From, c.To)
+	case ChangeAgentStarted:
+		return fmt.Sprintf("stage %s %s agent started (pid %s)", c.Stage, c.From, c.To)
+	case ChangeAgentExited:
+		return fmt.Sprintf("stage %s %s agent exited with code %s", c.Stage, c.From, c.To)
+	default:
+		return c.Type
+	}
+}
+
+// Diff lists the changes from before to after, in stack and stage order.
+// A nil snapshot is treated as empty.
+func Diff(before, after *Stacks) []Change {
+	if before == nil {
+		before = &Stacks{}
+	}
+	if after == nil {
+		after = &Stacks{}
+	}
+
+	var changes []Change
+	for i := range after.Stacks {
+		next := &after.Stacks[i]
+		prev, _ := FindStack(before, next.Name)
+		if prev == nil {
+			changes = append(changes, Change{Type: ChangeStackAdded, Stack: next.Name})
+			prev = &Stack{Name: next.Name}
+		}
+		changes = append(changes, diffStack(prev, next)...)
+	}
+	for i := range before.Stacks {
+		if stack, _ := FindStack(after, before.Stacks[i].Name); stack == nil {
+			changes = append(changes, Change{Type: ChangeStackRemoved, Stack: before.Stacks[i].Name})
+		}
+	}
+
+	return changes
+}
+
+func diffStack(prev, next *Stack) []Change {
+	var changes []Change
+	if prev.Paused != next.Paused {
+		change := Change{Type: ChangeStackResumed, Stack: next.Name}
+		if next.Paused {
+			change.Type = ChangeStackPaused
+		}
+		changes = append(changes, change)
+	}
+
+	for i := range next.Stages {
+		stage := &next.Stages[i]
+		old, _ := FindStage(prev, stage.ID)
+		if old == nil {
+			changes = append(changes, Change{Type: ChangeStageAdded, Stack: next.Name, Stage: stage.ID, To: EffectiveStatus(stage)})
+			continue
+		}
+
+		if from, to := EffectiveStatus(old), EffectiveStatus(stage); from != to {
+			changes = append(changes, Change{Type: ChangeStageStatus, Stack: next.Name, Stage: stage.ID, From: from, To: to})
+		}
+		if agent := stage.Agent; agent != nil {
+			if old.Agent == nil || old.Agent.PID != agent.PID {
+				changes = append(changes, Change{Type: ChangeAgentStarted, Stack: next.Name, Stage: stage.ID, From: agent.Phase, To: fmt.Sprint(agent.PID)})
+			}
+			if agent.ExitCode != nil && (old.Agent == nil || old.Agent.PID != agent.PID || old.Agent.ExitCode == nil) {
+				changes = append(changes, Change{Type: ChangeAgentExited, Stack: next.Name, Stage: stage.ID, From: agent.Phase, To: fmt.Sprint(*agent.ExitCode)})
+			}
+		}
+	}
+	for i := range prev.Stages {
+		if stage, _ := FindStage(next, prev.Stages[i].ID); stage == nil {
+			changes = append(changes, Change{Type: ChangeStageRemoved, Stack: next.Name, Stage: prev.Stages[i].ID})
+		}
+	}
+
+	return changes
+}
+
+// WatchOptions tunes Watch. The zero value uses file notifications with a
+// polling fallback at DefaultPollInterval.
+type WatchOptions struct {
+	PollInterval time.Duration
+	// Poll skips file notifications, e.g. on network filesystems where
+	// they are unreliable.
+	Poll bool
+}
+
+// StateUpdate is a snapshot of the stack index delivered by Watch, with the
+// changes since the previous snapshot. The first update has no changes.
+type StateUpdate struct {
+	Stacks  *Stacks
+	Changes []Change
+	Err     error
+}
+
+// Watch delivers a StateUpdate each time the stack index is written, until
+// ctx is done. It uses fsnotify on the index directory and falls back to
+// polling the file when notifications are unavailable. Writes that change
+// nothing observable still produce an update with no changes, so subscribers
+// can refresh other details such as reports.
+func Watch(ctx context.Context, repoRoot string, opts WatchOptions) (<-chan StateUpdate, error) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+
+	// Start watching and stat before loading so a save in between is seen
+	// as a change.
+	var notify *fsnotify.Watcher
+	if !opts.Poll {
+		if watcher, err := watchIndexDir(repoRoot); err == nil {
+			notify = watcher
+		}
+	}
+
+	w := &indexWatcher{repoRoot: repoRoot}
+	w.stat()
+	stacks, err := LoadStacks(repoRoot)
+	if err != nil {
+		if notify != nil {
+			notify.Close()
+		}
+		return nil, err
+	}
+	w.last = stacks
+
+	updates := make(chan StateUpdate, 1)
+	updates <- StateUpdate{Stacks: stacks}
+	w.updates = updates
+
+	go func() {
+		defer close(updates)
+		if notify == nil {
+			w.runPoll(ctx, opts.PollInterval)
+			return
+		}
+		defer notify.Close()
+		w.runNotify(ctx, notify, opts.PollInterval)
+	}()
+
+	return updates, nil
+}
+
+func watchIndexDir(repoRoot string) (*fsnotify.Watcher, error) {
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// The index is replaced by rename on save, so watch its directory. A
+	// repo without stacks yet has no directory and is polled instead.
+	if err := notify.Add(StacksDir(repoRoot)); err != nil {
+		notify.Close()
+		return nil, err
+	}
+	return notify, nil
+}
+
+type indexWatcher struct {
+	repoRoot string
+	last     *Stacks
+	modTime  time.Time
+	size     int64
+	updates  chan<- StateUpdate
+}
+
+func (w *indexWatcher) runNotify(ctx context.Context, notify *fsnotify.Watcher, pollInterval time.Duration) {
+	indexName := filepath.Base(StacksPath(w.repoRoot))
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-notify.Events:
+			if !ok {
+				w.runPoll(ctx, pollInterval)
+				return
+			}
+			if filepath.Base(event.Name) == indexName {
+				debounce = time.After(watchDebounce)
+			}
+		case _, ok := <-notify.Errors:
+			if !ok {
+				w.runPoll(ctx, pollInterval)
+				return
+			}
+			// Events may have been dropped; reload to catch up.
+			debounce = time.After(watchDebounce)
+		case <-debounce:
+			debounce = nil
+			if !w.emit(ctx) {
+				return
+			}
+		}
+	}
+}
+
+func (w *indexWatcher) runPoll(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modTime, size := w.modTime, w.size
+			w.stat()
+			if w.modTime.Equal(modTime) && w.size == size {
+				continue
+			}
+			if !w.emit(ctx) {
+				return
+			}
+		}
+	}
+}
+
+func (w *indexWatcher) stat() {
+	if info, err := os.Stat(StacksPath(w.repoRoot)); err == nil {
+		w.modTime, w.size = info.ModTime(), info.Size()
+	}
+}
+
+// emit reloads the index and sends the update; it reports false once ctx
+// is done.
+func (w *indexWatcher) emit(ctx context.Context) bool {
+	w.stat()
+
+	update := StateUpdate{}
+	stacks, err := LoadStacks(w.repoRoot)
+	if err != nil {
+		update.Err = err
+	} else {
+		update.Stacks = stacks
+		update.Changes = Diff(w.last, stacks)
+		w.last = stacks
+	}
+
+	select {
+	case w.updates <- update:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
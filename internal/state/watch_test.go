@@ -0,0 +1,121 @@
+package state
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	exit := 0
+	before := &Stacks{Stacks: []Stack{
+		{Name: "checkout", Stages: []Stage{
+			{ID: "api", Status: StatusImplementing, Agent: &AgentProcess{PID: 10, Phase: PhaseImplementing}},
+			{ID: "ui", Status: StatusPending},
+			{ID: "docs", Status: StatusPending},
+		}},
+		{Name: "old"},
+	}}
+	after := &Stacks{Stacks: []Stack{
+		{Name: "checkout", Paused: true, Stages: []Stage{
+			{ID: "api", Status: StatusAIReview, Agent: &AgentProcess{PID: 10, Phase: PhaseImplementing, ExitCode: &exit}},
+			{ID: "ui", Status: StatusImplementing, Agent: &AgentProcess{PID: 11, Phase: PhaseImplementing}},
+			{ID: "e2e", Status: StatusPending},
+		}},
+		{Name: "billing"},
+	}}
+
+	got := Diff(before, after)
+	want := []Change{
+		{Type: ChangeStackPaused, Stack: "checkout"},
+		{Type: ChangeStageStatus, Stack: "checkout", Stage: "api", From: StatusImplementing, To: StatusAIReview},
+		{Type: ChangeAgentExited, Stack: "checkout", Stage: "api", From: PhaseImplementing, To: "0"},
+		{Type: ChangeStageStatus, Stack: "checkout", Stage: "ui", From: StatusPending, To: StatusImplementing},
+		{Type: ChangeAgentStarted, Stack: "checkout", Stage: "ui", From: PhaseImplementing, To: "11"},
+		{Type: ChangeStageAdded, Stack: "checkout", Stage: "e2e", To: StatusPending},
+		{Type: ChangeStageRemoved, Stack: "checkout", Stage: "docs"},
+		{Type: ChangeStackAdded, Stack: "billing"},
+		{Type: ChangeStackRemoved, Stack: "old"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if s := got[1].String(); s != "stage api moved implementing → ai-review" {
+		t.Fatalf("String() = %q", s)
+	}
+	if changes := Diff(after, after); len(changes) != 0 {
+		t.Fatalf("Diff of identical snapshots = %+v, want none", changes)
+	}
+}
+
+func TestWatchReportsStageTransitions(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts WatchOptions
+	}{
+		{name: "notify"},
+		{name: "poll", opts: WatchOptions{Poll: true, PollInterval: 20 * time.Millisecond}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repoRoot := t.TempDir()
+			if err := SaveStacks(repoRoot, &Stacks{Stacks: []Stack{{Name: "checkout", Stages: []Stage{{ID: "api"}}}}}); err != nil {
+				t.Fatalf("SaveStacks: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			updates, err := Watch(ctx, repoRoot, tc.opts)
+			if err != nil {
+				t.Fatalf("Watch: %v", err)
+			}
+
+			first := nextUpdate(t, updates)
+			if first.Stacks == nil || len(first.Changes) != 0 {
+				t.Fatalf("first update = %+v, want snapshot without changes", first)
+			}
+
+			err = Update(repoRoot, func(s *Stacks) error {
+				return TransitionStage(s, "checkout", "api", StatusImplementing)
+			})
+			if err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+
+			want := Change{Type: ChangeStageStatus, Stack: "checkout", Stage: "api", From: StatusPending, To: StatusImplementing}
+			for {
+				update := nextUpdate(t, updates)
+				if update.Err != nil {
+					t.Fatalf("update error: %v", update.Err)
+				}
+				if len(update.Changes) == 0 {
+					continue
+				}
+				if !reflect.DeepEqual(update.Changes, []Change{want}) {
+					t.Fatalf("changes = %+v, want %+v", update.Changes, want)
+				}
+				break
+			}
+
+			cancel()
+			for range updates {
+			}
+		})
+	}
+}
+
+func nextUpdate(t *testing.T, updates <-chan StateUpdate) StateUpdate {
+	t.Helper()
+	select {
+	case update, ok := <-updates:
+		if !ok {
+			t.Fatal("updates closed early")
+		}
+		return update
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a state update")
+	}
+	return StateUpdate{}
+}
@@ -0,0 +1,50 @@
+package workflow
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/config"
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/harness"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// LoadAgent loads and validates the global config and resolves the harness
+// that runs pipeline agents.
+func LoadAgent() (*config.Config, Agent, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, Agent{}, fmt.Errorf("load config: %w", err)
+	}
+
+	if err := config.ValidateConfig(cfg); err != nil {
+		return nil, Agent{}, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Verify harness binary is available
+	harnessName := strings.ToLower(cfg.AgentHarness)
+	if bin := harness.Executable(cfg); bin != "" {
+		if _, lookErr := exec.LookPath(bin); lookErr != nil {
+			return nil, Agent{}, errs.New(errs.AgentMissing, "%s not found in PATH", bin)
+		}
+	}
+
+	h, err := harness.ForConfig(cfg)
+	if err != nil {
+		return nil, Agent{}, err
+	}
+
+	return cfg, Agent{Harness: h, Name: harnessName}, nil
+}
+
+// markBlocked blocks a stage whose agent could not be started, unless it has
+// already stopped.
+func markBlocked(stacks *state.Stacks, stack *state.Stack, stage *state.Stage, cause error) {
+	if state.StageStopped(stage) {
+		return
+	}
+
+	_ = state.TransitionStageWith(stacks, stack.Name, stage.ID, state.StatusBlocked, map[string]string{"reason": cause.Error()})
+}
@@ -0,0 +1,206 @@
+package workflow
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// MaxStageLogs is how many agent logs are kept per stage and phase; older
+// logs are removed when a new agent is spawned.
+const MaxStageLogs = 5
+
+// StageLog is one captured agent log.
+type StageLog struct {
+	Phase string
+	Run   int
+	Path  string
+}
+
+// StageLogDir is where agent output for a stage is captured. It sits inside
+// the stage's managed worktree directory, so it carries its own .gitignore.
+func StageLogDir(repoRoot, stackName, stageID string) string {
+	return filepath.Join(StageWorktreePath(repoRoot, stackName, stageID), "logs")
+}
+
+// StageAgentFilesDir holds the private files handed to a stage's current
+// agent, such as its prompt file or MCP config. It lives under the log
+// directory, so it is ignored by git as well.
+func StageAgentFilesDir(repoRoot, stackName, stageID string) string {
+	return filepath.Join(StageLogDir(repoRoot, stackName, stageID), "agent")
+}
+
+// PrepareAgentFiles empties a stage's agent files directory for a new agent,
+// removing whatever was handed to the previous one, and returns it.
+func PrepareAgentFiles(repoRoot, stackName, stageID string) (string, error) {
+	if err := RemoveAgentFiles(repoRoot, stackName, stageID); err != nil {
+		return "", err
+	}
+	dir := StageAgentFilesDir(repoRoot, stackName, stageID)
+	return dir, os.MkdirAll(dir, 0o700)
+}
+
+// RemoveAgentFiles removes the files handed to a stage's agents.
+func RemoveAgentFiles(repoRoot, stackName, stageID string) error {
+	return os.RemoveAll(StageAgentFilesDir(repoRoot, stackName, stageID))
+}
+
+// StageLogs returns the captured logs for a stage, least recently written
+// first. An empty phase returns logs for every phase.
+func StageLogs(repoRoot, stackName, stageID, phase string) ([]StageLog, error) {
+	dir := StageLogDir(repoRoot, stackName, stageID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	type entryLog struct {
+		log     StageLog
+		modTime int64
+	}
+	var logs []entryLog
+	for _, entry := range entries {
+		logPhase, run, ok := parseLogName(entry.Name())
+		if !ok || (phase != "" && logPhase != phase) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		logs = append(logs, entryLog{
+			log:     StageLog{Phase: logPhase, Run: run, Path: filepath.Join(dir, entry.Name())},
+			modTime: info.ModTime().UnixNano(),
+		})
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].modTime != logs[j].modTime {
+			return logs[i].modTime < logs[j].modTime
+		}
+		return logs[i].log.Run < logs[j].log.Run
+	})
+
+	result := make([]StageLog, 0, len(logs))
+	for _, item := range logs {
+		result = append(result, item.log)
+	}
+	return result, nil
+}
+
+// LatestStageLog returns the most recent log for a stage and phase (any phase
+// when empty), or nil if none has been captured.
+func LatestStageLog(repoRoot, stackName, stageID, phase string) (*StageLog, error) {
+	logs, err := StageLogs(repoRoot, stackName, stageID, phase)
+	if err != nil || len(logs) == 0 {
+		return nil, err
+	}
+	return &logs[len(logs)-1], nil
+}
+
+// OpenStageLog creates the next log file for an agent phase, removing the
+// oldest logs beyond MaxStageLogs.
+func OpenStageLog(repoRoot, stackName, stageID, phase string) (*os.File, error) {
+	dir := StageLogDir(repoRoot, stackName, stageID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	// Keep the logs out of the stage worktree's git status.
+	ignorePath := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignorePath); os.IsNotExist(err) {
+		if err := os.WriteFile(ignorePath, []byte("*\n"), 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	logs, err := StageLogs(repoRoot, stackName, stageID, phase)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(logs, func(i, j int) bool { return logs[i].Run < logs[j].Run })
+	run := 1
+	if len(logs) > 0 {
+		run = logs[len(logs)-1].Run + 1
+	}
+	for len(logs) >= MaxStageLogs {
+		_ = os.Remove(logs[0].Path)
+		logs = logs[1:]
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%d.log", phase, run))
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// TailFile returns the last n lines of the file at path.
+func TailFile(path string, n int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	// Read backwards in chunks until enough lines are buffered.
+	const chunkSize = 32 * 1024
+	var buf []byte
+	offset := info.Size()
+	for offset > 0 && bytes.Count(buf, []byte("\n")) <= n {
+		size := int64(chunkSize)
+		if offset < size {
+			size = offset
+		}
+		offset -= size
+
+		chunk := make([]byte, size)
+		if _, err := f.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return "", err
+		}
+		buf = append(chunk, buf...)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(buf), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return "", nil
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+func parseLogName(name string) (string, int, bool) {
+	base, ok := strings.CutSuffix(name, ".log")
+	if !ok {
+		return "", 0, false
+	}
+	idx := strings.LastIndex(base, "-")
+	if idx <= 0 {
+		return "", 0, false
+	}
+
+	phase := base[:idx]
+	if phase != state.PhaseImplementing && phase != state.PhaseAIReview {
+		return "", 0, false
+	}
+	run, err := strconv.Atoi(base[idx+1:])
+	if err != nil || run < 1 {
+		return "", 0, false
+	}
+
+	return phase, run, true
+}
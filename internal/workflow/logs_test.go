@@ -0,0 +1,88 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+func TestOpenStageLogRotates(t *testing.T) {
+	repoRoot := t.TempDir()
+
+	for i := 0; i < MaxStageLogs+2; i++ {
+		f, err := OpenStageLog(repoRoot, "checkout", "api", state.PhaseImplementing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(f, "run %d\n", i+1)
+		f.Close()
+	}
+
+	logs, err := StageLogs(repoRoot, "checkout", "api", state.PhaseImplementing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != MaxStageLogs {
+		t.Fatalf("kept %d logs, want %d", len(logs), MaxStageLogs)
+	}
+	if logs[0].Run != 3 {
+		t.Fatalf("oldest kept run = %d, want 3", logs[0].Run)
+	}
+
+	latest, err := LatestStageLog(repoRoot, "checkout", "api", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(repoRoot, ".m", "stacks", "checkout", "api", "logs", fmt.Sprintf("implementing-%d.log", MaxStageLogs+2))
+	if latest == nil || latest.Path != want {
+		t.Fatalf("LatestStageLog() = %+v, want %s", latest, want)
+	}
+}
+
+func TestStageLogsStayOutOfWorktreeStatus(t *testing.T) {
+	repoRoot := initTestRepo(t)
+
+	stacks := &state.Stacks{Stacks: []state.Stack{{Name: "checkout", Stages: []state.Stage{{ID: "api"}}}}}
+	stack := &stacks.Stacks[0]
+	if err := EnsureStageWorktree(repoRoot, stacks, stack, &stack.Stages[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenStageLog(repoRoot, "checkout", "api", state.PhaseImplementing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	status, err := gitx.Run(stack.Stages[0].Worktree, "status", "--porcelain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "" {
+		t.Fatalf("worktree status = %q, want clean", status)
+	}
+}
+
+func TestTailFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	var content strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	if err := os.WriteFile(path, []byte(content.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tail, err := TailFile(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tail != "line 98\nline 99\nline 100\n" {
+		t.Fatalf("TailFile() = %q", tail)
+	}
+}
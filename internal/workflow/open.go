@@ -0,0 +1,91 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// OpenedStage describes the branch and worktree OpenStage prepared.
+type OpenedStage struct {
+	Stack           string `json:"stack"`
+	Stage           string `json:"stage"`
+	Branch          string `json:"branch"`
+	Parent          string `json:"parent"`
+	Worktree        string `json:"worktree"`
+	CreatedBranch   bool   `json:"created_branch"`
+	CreatedWorktree bool   `json:"created_worktree"`
+}
+
+// OpenStage creates the stage's branch and worktree if they are missing and
+// makes it the stack's current stage. Starting an agent in the worktree is
+// left to the caller.
+func (s *Service) OpenStage(stackName, stageID string) (*OpenedStage, error) {
+	repoRoot := s.RepoRoot
+	if err := state.EnsureInitialized(repoRoot); err != nil {
+		return nil, err
+	}
+
+	var opened *OpenedStage
+	err := state.UpdateAs(repoRoot, s.Actor, func(stacks *state.Stacks) error {
+		stack, _ := state.FindStack(stacks, stackName)
+		if stack == nil {
+			return state.StackNotFoundError(stackName)
+		}
+		stage, stageIndex := state.FindStage(stack, stageID)
+		if stage == nil {
+			return fmt.Errorf("stage %q not found in stack %q", stageID, stackName)
+		}
+
+		branch := StageBranchFor(stack, stageIndex)
+		parentBranch, err := ParentBranchForStage(repoRoot, stack, stageIndex)
+		if err != nil {
+			return err
+		}
+
+		worktree := strings.TrimSpace(stage.Worktree)
+		if worktree == "" {
+			worktree = StageWorktreePath(repoRoot, stack.Name, stage.ID)
+		}
+		_, statErr := os.Stat(worktree)
+		if statErr != nil && !os.IsNotExist(statErr) {
+			return statErr
+		}
+
+		opened = &OpenedStage{
+			Stack:           stack.Name,
+			Stage:           stage.ID,
+			CreatedBranch:   !gitx.BranchExists(repoRoot, branch),
+			CreatedWorktree: statErr != nil,
+		}
+
+		if err := StartStageWorktree(repoRoot, stacks, stack, stageIndex, branch, parentBranch); err != nil {
+			return err
+		}
+
+		stack.CurrentStage = stage.ID
+		opened.Branch = stage.Branch
+		opened.Parent = stage.Parent
+		opened.Worktree = stage.Worktree
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opened.CreatedBranch {
+		s.report(StepBranchCreated, opened.Stage, "Created branch %s from %s", opened.Branch, opened.Parent)
+	} else {
+		s.report(StepBranchReused, opened.Stage, "Reusing branch %s", opened.Branch)
+	}
+	if opened.CreatedWorktree {
+		s.report(StepWorktreeCreated, opened.Stage, "Created worktree: %s", opened.Worktree)
+	} else {
+		s.report(StepWorktreeReused, opened.Stage, "Reusing worktree: %s", opened.Worktree)
+	}
+
+	return opened, nil
+}
@@ -0,0 +1,273 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/harness"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// Agent is the harness used to run pipeline agents, with the name recorded in
+// the stack journal.
+type Agent struct {
+	Harness harness.Harness
+	Name    string
+}
+
+// startStageAgent starts the build or review agent for a stage in its
+// worktree and returns the process to record on the stage. It does not
+// change the state, so it can run without the state lock.
+func startStageAgent(ctx context.Context, repoRoot string, stack *state.Stack, stage *state.Stage, phase string, agent Agent) (*state.AgentProcess, error) {
+	worktreePath := stage.Worktree
+	if worktreePath == "" {
+		worktreePath = repoRoot
+	}
+
+	opts := harness.AgentOpts{
+		WorktreePath: worktreePath,
+		StageContext: stage.Context,
+		StackName:    stack.Name,
+		StageID:      stage.ID,
+		Phase:        phase,
+		Model:        stage.Model,
+	}
+	if phase == state.PhaseImplementing {
+		opts.ReviewFindings = state.PendingReviewFindings(stage)
+	}
+	// Agents share a running `m mcp serve --http` daemon when there is one.
+	if server, err := state.LoadMCPServer(repoRoot); err == nil && server != nil {
+		opts.MCP = &harness.MCPEndpoint{URL: server.URL, Token: server.Token}
+	}
+	opts.SystemPrompt = harness.BuildSystemPrompt(opts)
+	// The spawning command usually returns long before the agent does, so the
+	// agent runs under `m agent exec`, which calls RecordAgentExit.
+	opts.RecordExit = true
+
+	logFile, err := OpenStageLog(repoRoot, stack.Name, stage.ID, phase)
+	if err != nil {
+		return nil, fmt.Errorf("open agent log: %w", err)
+	}
+	// The agent keeps its own handle to the log.
+	defer logFile.Close()
+	opts.Output = logFile
+
+	filesDir, err := PrepareAgentFiles(repoRoot, stack.Name, stage.ID)
+	if err != nil {
+		return nil, fmt.Errorf("prepare agent files: %w", err)
+	}
+	opts.FilesDir = filesDir
+
+	var proc *harness.Process
+	switch phase {
+	case state.PhaseImplementing:
+		proc, err = agent.Harness.SpawnBuildAgent(ctx, opts)
+	case state.PhaseAIReview:
+		proc, err = agent.Harness.SpawnReviewAgent(ctx, opts)
+	default:
+		return nil, fmt.Errorf("unknown agent phase %q", phase)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	return &state.AgentProcess{
+		Phase:     phase,
+		PID:       proc.PID,
+		Hostname:  hostname,
+		Command:   proc.Command,
+		StartedAt: proc.StartedAt.Format(time.RFC3339),
+		Log:       logFile.Name(),
+	}, nil
+}
+
+// recordAgent stores a started agent on its stage and records the spawn in
+// the stack journal.
+func recordAgent(stacks *state.Stacks, stack *state.Stack, stage *state.Stage, proc *state.AgentProcess, harnessName string) {
+	stage.Agent = proc
+	stacks.Record(stack.Name, state.Event{
+		Type:    state.EventAgentSpawn,
+		Stage:   stage.ID,
+		Payload: map[string]string{"phase": proc.Phase, "harness": harnessName, "worktree": stage.Worktree, "pid": strconv.Itoa(proc.PID), "log": proc.Log},
+	})
+}
+
+// blockStage marks stage blocked because its phase agent could not be
+// started.
+func blockStage(stacks *state.Stacks, stack *state.Stack, stage *state.Stage, phase string, err error) error {
+	reason := fmt.Sprintf("spawn %s agent: %v", phase, err)
+	return state.TransitionStageWith(stacks, stack.Name, stage.ID, state.StatusBlocked, map[string]string{"reason": reason})
+}
+
+// RecordAgentExit stores the exit code of the agent pid on its stage. An
+// agent that exits while its stage is still in the agent's phase never called
+// report_stage_done, so the stage is marked failed.
+func RecordAgentExit(repoRoot, stackName, stageID string, pid, exitCode int) error {
+	return state.UpdateAs(repoRoot, "agent-supervisor", func(stacks *state.Stacks) error {
+		stack, _ := state.FindStack(stacks, stackName)
+		if stack == nil {
+			return nil
+		}
+		stage, _ := state.FindStage(stack, stageID)
+		if stage == nil || stage.Agent == nil || stage.Agent.PID != pid {
+			// The stage has moved on to a newer agent.
+			return nil
+		}
+
+		stage.Agent.ExitCode = &exitCode
+		stage.Agent.ExitedAt = time.Now().UTC().Format(time.RFC3339)
+		stacks.Record(stack.Name, state.Event{
+			Type:    state.EventAgentExit,
+			Stage:   stage.ID,
+			Payload: map[string]string{"phase": stage.Agent.Phase, "pid": strconv.Itoa(pid), "exit_code": strconv.Itoa(exitCode)},
+		})
+
+		if lost, reason := state.AgentLost(stage); lost {
+			return state.TransitionStageWith(stacks, stack.Name, stage.ID, state.StatusFailed, map[string]string{"reason": reason})
+		}
+		return nil
+	})
+}
+
+// Launch is a stage the pipeline moved into an agent phase whose worktree
+// and agent are still to be started. Stages are claimed inside a state
+// transaction and launched by LaunchStages once it is saved, so the state
+// lock is not held while git and agent processes run.
+type Launch struct {
+	Stack string
+	Stage string
+	Phase string
+}
+
+// ClaimReadyStages moves ready stages to implementing, in stack order, until
+// the stack's parallelism limit is reached, and returns their launches. On
+// error the stages claimed before it are returned; callers should still save
+// the state and launch them. Nothing is claimed while the stack is paused.
+func ClaimReadyStages(stacks *state.Stacks, stack *state.Stack) ([]Launch, error) {
+	launches := []Launch{}
+	if stack.Paused {
+		return launches, nil
+	}
+	for state.FreeSlots(stack) > 0 {
+		next := state.NextPendingStage(stack)
+		if next == nil {
+			break
+		}
+
+		if err := state.TransitionStage(stacks, stack.Name, next.ID, state.StatusImplementing); err != nil {
+			return launches, err
+		}
+		launches = append(launches, Launch{Stack: stack.Name, Stage: next.ID, Phase: state.PhaseImplementing})
+	}
+
+	return launches, nil
+}
+
+// LaunchStages gives each claimed stage its worktree and spawns its phase
+// agent. The git and process work runs against a snapshot of the state with
+// the lock released; each result is then saved in a short transaction. A
+// stage that cannot be launched is left blocked and the others are still
+// launched. It returns the IDs of the stages whose agents were started.
+func LaunchStages(ctx context.Context, repoRoot, actor string, launches []Launch, agent Agent) ([]string, error) {
+	started := []string{}
+	var launchErrs []error
+	for _, launch := range launches {
+		ok, err := launchStage(ctx, repoRoot, actor, launch, agent)
+		if err != nil {
+			launchErrs = append(launchErrs, err)
+			continue
+		}
+		if ok {
+			started = append(started, launch.Stage)
+		}
+	}
+
+	return started, errors.Join(launchErrs...)
+}
+
+// launchStage launches one claimed stage. It reports false without an error
+// when the stage moved on before its agent was recorded, e.g. because it was
+// reset; an agent started for it in the meantime is stopped.
+func launchStage(ctx context.Context, repoRoot, actor string, launch Launch, agent Agent) (bool, error) {
+	snapshot, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		return false, err
+	}
+	stack, stage := claimedStage(snapshot, launch)
+	if stage == nil {
+		return false, nil
+	}
+
+	if err := EnsureStageWorktree(repoRoot, snapshot, stack, stage); err != nil {
+		err = fmt.Errorf("prepare worktree for stage %q: %w", stage.ID, err)
+		_, saveErr := updateClaimed(repoRoot, actor, launch, func(stacks *state.Stacks, stack *state.Stack, current *state.Stage) error {
+			markBlocked(stacks, stack, current, err)
+			return nil
+		})
+		return false, errors.Join(err, saveErr)
+	}
+	claimed, err := updateClaimed(repoRoot, actor, launch, func(stacks *state.Stacks, _ *state.Stack, current *state.Stage) error {
+		current.Branch = stage.Branch
+		current.Worktree = stage.Worktree
+		current.Parent = stage.Parent
+		stacks.TakeEvents(snapshot)
+		return nil
+	})
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	proc, err := startStageAgent(ctx, repoRoot, stack, stage, launch.Phase, agent)
+	if err != nil {
+		spawnErr := fmt.Errorf("spawn %s agent for stage %q: %w", launch.Phase, stage.ID, err)
+		_, saveErr := updateClaimed(repoRoot, actor, launch, func(stacks *state.Stacks, stack *state.Stack, current *state.Stage) error {
+			return blockStage(stacks, stack, current, launch.Phase, err)
+		})
+		return false, errors.Join(spawnErr, saveErr)
+	}
+
+	claimed, err = updateClaimed(repoRoot, actor, launch, func(stacks *state.Stacks, stack *state.Stack, current *state.Stage) error {
+		recordAgent(stacks, stack, current, proc, agent.Name)
+		return nil
+	})
+	if err != nil || !claimed {
+		_ = state.TerminateProcess(proc.PID)
+	}
+	return claimed, err
+}
+
+// updateClaimed applies fn to a launched stage under the state lock, unless
+// the stage has left the phase it was claimed for. It reports whether fn ran.
+func updateClaimed(repoRoot, actor string, launch Launch, fn func(*state.Stacks, *state.Stack, *state.Stage) error) (bool, error) {
+	claimed := false
+	err := state.UpdateAs(repoRoot, actor, func(stacks *state.Stacks) error {
+		stack, stage := claimedStage(stacks, launch)
+		if stage == nil {
+			return nil
+		}
+		claimed = true
+		return fn(stacks, stack, stage)
+	})
+
+	return claimed, err
+}
+
+// claimedStage finds a launched stage, or returns nil if it is no longer in
+// the phase it was claimed for.
+func claimedStage(stacks *state.Stacks, launch Launch) (*state.Stack, *state.Stage) {
+	stack, _ := state.FindStack(stacks, launch.Stack)
+	if stack == nil {
+		return nil, nil
+	}
+	stage, _ := state.FindStage(stack, launch.Stage)
+	if stage == nil || state.EffectiveStatus(stage) != state.PhaseStatus(launch.Phase) {
+		return nil, nil
+	}
+
+	return stack, stage
+}
@@ -0,0 +1,328 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/harness"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+type fakeHarness struct {
+	builds  []string
+	reviews []string
+	err     error
+	// filesDirs are the agent files directories passed to each spawn.
+	filesDirs []string
+	// onSpawn, if set, runs as each build agent is spawned and makes the
+	// agent a real `sleep` process, whose PID is added to pids.
+	onSpawn func()
+	pids    []int
+}
+
+func (f *fakeHarness) SpawnBuildAgent(_ context.Context, opts harness.AgentOpts) (*harness.Process, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.builds = append(f.builds, opts.StageID)
+	f.filesDirs = append(f.filesDirs, opts.FilesDir)
+	if f.onSpawn != nil {
+		cmd := exec.Command("sleep", "60")
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		go func() { _ = cmd.Wait() }()
+		f.pids = append(f.pids, cmd.Process.Pid)
+		f.onSpawn()
+		return &harness.Process{PID: cmd.Process.Pid, Command: []string{"sleep"}, StartedAt: time.Now()}, nil
+	}
+	return f.process(), nil
+}
+
+func (f *fakeHarness) SpawnReviewAgent(_ context.Context, opts harness.AgentOpts) (*harness.Process, error) {
+	f.reviews = append(f.reviews, opts.StageID)
+	return f.process(), nil
+}
+
+// process reports the test binary itself, which is alive for the whole test.
+func (f *fakeHarness) process() *harness.Process {
+	return &harness.Process{PID: os.Getpid(), Command: []string{"fake"}, StartedAt: time.Now()}
+}
+
+func TestLaunchStagesRespectsParallelLimit(t *testing.T) {
+	repoRoot := initTestRepo(t)
+	saveTestStacks(t, repoRoot, state.Stack{
+		Name:            "checkout",
+		DependencyGraph: true,
+		Parallel:        2,
+		Stages: []state.Stage{
+			{ID: "api", Status: state.StatusPending},
+			{ID: "ui", Status: state.StatusPending},
+			{ID: "docs", Status: state.StatusPending},
+			{ID: "launch", Status: state.StatusPending, DependsOn: []string{"api", "ui"}},
+		},
+	})
+	fake := &fakeHarness{}
+	agent := Agent{Harness: fake, Name: "fake"}
+
+	started, err := claimAndLaunch(t, repoRoot, agent)
+	if err != nil {
+		t.Fatalf("LaunchStages() error = %v", err)
+	}
+	if !reflect.DeepEqual(started, []string{"api", "ui"}) {
+		t.Fatalf("started = %v, want [api ui]", started)
+	}
+	if !reflect.DeepEqual(fake.builds, []string{"api", "ui"}) {
+		t.Fatalf("build agents = %v, want [api ui]", fake.builds)
+	}
+
+	stack := loadTestStack(t, repoRoot)
+	for _, id := range []string{"api", "ui"} {
+		stage, _ := state.FindStage(stack, id)
+		if stage.Status != state.StatusImplementing {
+			t.Fatalf("stage %q status = %q, want implementing", id, stage.Status)
+		}
+		if stage.Worktree != StageWorktreePath(repoRoot, "checkout", id) {
+			t.Fatalf("stage %q worktree = %q", id, stage.Worktree)
+		}
+		if _, err := os.Stat(stage.Worktree); err != nil {
+			t.Fatalf("stage %q worktree missing: %v", id, err)
+		}
+		if stage.Agent == nil || stage.Agent.PID != os.Getpid() {
+			t.Fatalf("stage %q agent = %+v, want the spawned agent recorded", id, stage.Agent)
+		}
+	}
+
+	// A slot frees up once a stage reaches human review; launch still waits
+	// on ui, so docs is next.
+	if err := state.Update(repoRoot, func(stacks *state.Stacks) error {
+		stacks.Stacks[0].Stages[0].Status = state.StatusHumanReview
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	started, err = claimAndLaunch(t, repoRoot, agent)
+	if err != nil {
+		t.Fatalf("LaunchStages() error = %v", err)
+	}
+	if !reflect.DeepEqual(started, []string{"docs"}) {
+		t.Fatalf("started = %v, want [docs]", started)
+	}
+	if len(fake.reviews) != 0 {
+		t.Fatalf("unexpected review agents: %v", fake.reviews)
+	}
+}
+
+func TestClaimReadyStagesSkipsPausedStack(t *testing.T) {
+	stacks := &state.Stacks{Stacks: []state.Stack{{
+		Name:   "checkout",
+		Paused: true,
+		Stages: []state.Stage{{ID: "api", Status: state.StatusPending}},
+	}}}
+	stack := &stacks.Stacks[0]
+
+	launches, err := ClaimReadyStages(stacks, stack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(launches) != 0 || stack.Stages[0].Status != state.StatusPending {
+		t.Fatalf("launches = %v; want nothing claimed while paused", launches)
+	}
+}
+
+func TestLaunchStagesBlocksStageWhenSpawnFails(t *testing.T) {
+	repoRoot := initTestRepo(t)
+	saveTestStacks(t, repoRoot, state.Stack{
+		Name:   "checkout",
+		Stages: []state.Stage{{ID: "api", Status: state.StatusPending}},
+	})
+	agent := Agent{Harness: &fakeHarness{err: errors.New("harness missing")}, Name: "fake"}
+
+	started, err := claimAndLaunch(t, repoRoot, agent)
+	if err == nil {
+		t.Fatal("expected spawn error")
+	}
+	if len(started) != 0 {
+		t.Fatalf("started = %v, want none", started)
+	}
+
+	stage := loadTestStack(t, repoRoot).Stages[0]
+	if stage.Status != state.StatusBlocked {
+		t.Fatalf("status = %q, want blocked", stage.Status)
+	}
+	if stage.StatusReason != "spawn implementing agent: harness missing" {
+		t.Fatalf("reason = %q", stage.StatusReason)
+	}
+}
+
+func TestLaunchStagesStopsAgentOfStageResetMeanwhile(t *testing.T) {
+	repoRoot := initTestRepo(t)
+	saveTestStacks(t, repoRoot, state.Stack{
+		Name:   "checkout",
+		Stages: []state.Stage{{ID: "api", Status: state.StatusPending}},
+	})
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+	// The stage is reset while its agent starts, with the lock released.
+	fake := &fakeHarness{onSpawn: func() {
+		if err := state.Update(repoRoot, func(stacks *state.Stacks) error {
+			return state.ResetStage(stacks, "checkout", "api", state.StatusPending)
+		}); err != nil {
+			t.Error(err)
+		}
+	}}
+
+	started, err := claimAndLaunch(t, repoRoot, Agent{Harness: fake, Name: "fake"})
+	if err != nil {
+		t.Fatalf("LaunchStages() error = %v", err)
+	}
+	if len(started) != 0 {
+		t.Fatalf("started = %v, want none", started)
+	}
+	if stage := loadTestStack(t, repoRoot).Stages[0]; stage.Status != state.StatusPending || stage.Agent != nil {
+		t.Fatalf("stage = %s with agent %+v, want pending without an agent", stage.Status, stage.Agent)
+	}
+	if len(fake.pids) != 1 {
+		t.Fatalf("agent pids = %v, want one", fake.pids)
+	}
+	agentProc := &state.AgentProcess{PID: fake.pids[0]}
+	for deadline := time.Now().Add(5 * time.Second); state.AgentRunning(agentProc); time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("agent %d of the reset stage is still running", fake.pids[0])
+		}
+	}
+}
+
+func TestStartStageAgentClearsPreviousAgentFiles(t *testing.T) {
+	repoRoot := initTestRepo(t)
+	stack := &state.Stack{
+		Name:   "checkout",
+		Stages: []state.Stage{{ID: "api", Status: state.StatusImplementing}},
+	}
+	fake := &fakeHarness{}
+	agent := Agent{Harness: fake, Name: "fake"}
+
+	if _, err := startStageAgent(context.Background(), repoRoot, stack, &stack.Stages[0], state.PhaseImplementing, agent); err != nil {
+		t.Fatalf("startStageAgent() error = %v", err)
+	}
+	dir := StageAgentFilesDir(repoRoot, "checkout", "api")
+	if len(fake.filesDirs) != 1 || fake.filesDirs[0] != dir {
+		t.Fatalf("files dirs = %v, want [%s]", fake.filesDirs, dir)
+	}
+	if info, err := os.Stat(dir); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0o700) {
+		t.Fatalf("agent files dir = %v, %v; want a private directory", info, err)
+	}
+	stale := filepath.Join(dir, "mcp.json")
+	if err := os.WriteFile(stale, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := startStageAgent(context.Background(), repoRoot, stack, &stack.Stages[0], state.PhaseImplementing, agent); err != nil {
+		t.Fatalf("startStageAgent() error = %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("previous agent's file still present: %v", err)
+	}
+}
+
+func TestRecordAgentExitFailsStageThatDidNotReport(t *testing.T) {
+	repoRoot := t.TempDir()
+	stacks := &state.Stacks{Stacks: []state.Stack{{
+		Name: "checkout",
+		Stages: []state.Stage{
+			{ID: "api", Status: state.StatusImplementing, Agent: &state.AgentProcess{Phase: state.PhaseImplementing, PID: 41}},
+			{ID: "ui", Status: state.StatusAIReview, Agent: &state.AgentProcess{Phase: state.PhaseImplementing, PID: 42}},
+		},
+	}}}
+	if err := state.SaveStacks(repoRoot, stacks); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RecordAgentExit(repoRoot, "checkout", "api", 41, 1); err != nil {
+		t.Fatal(err)
+	}
+	// ui's build agent reported before exiting, so its exit is just recorded.
+	if err := RecordAgentExit(repoRoot, "checkout", "ui", 42, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := loaded.Stacks[0].Stages[0]
+	if api.Status != state.StatusFailed || !strings.Contains(api.StatusReason, "exited with code 1") {
+		t.Fatalf("api = %s (%q), want failed with exit reason", api.Status, api.StatusReason)
+	}
+	ui := loaded.Stacks[0].Stages[1]
+	if ui.Status != state.StatusAIReview || ui.Agent.ExitCode == nil || *ui.Agent.ExitCode != 0 {
+		t.Fatalf("ui = %s with agent %+v, want ai-review with exit code 0", ui.Status, ui.Agent)
+	}
+}
+
+// claimAndLaunch claims the ready stages of the saved checkout stack and
+// launches them, as `m stack run` does.
+func claimAndLaunch(t *testing.T, repoRoot string, agent Agent) ([]string, error) {
+	t.Helper()
+	var launches []Launch
+	if err := state.Update(repoRoot, func(stacks *state.Stacks) error {
+		stack, _ := state.FindStack(stacks, "checkout")
+		var err error
+		launches, err = ClaimReadyStages(stacks, stack)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return LaunchStages(context.Background(), repoRoot, "test", launches, agent)
+}
+
+func saveTestStacks(t *testing.T, repoRoot string, stacks ...state.Stack) {
+	t.Helper()
+	if err := state.SaveStacks(repoRoot, &state.Stacks{Stacks: stacks}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func loadTestStack(t *testing.T, repoRoot string) *state.Stack {
+	t.Helper()
+	stacks, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &stacks.Stacks[0]
+}
+
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := t.TempDir()
+	mustGit(t, dir, "init", "-q", "-b", "main")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("test"), 0o600); err != nil {
+		t.Fatalf("write README.md: %v", err)
+	}
+	mustGit(t, dir, "add", ".")
+	mustGit(t, dir, "commit", "-q", "-m", "init")
+	return dir
+}
+
+func mustGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := gitx.Run(dir, args...); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
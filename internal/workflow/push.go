@@ -0,0 +1,474 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// PushedStage describes a pushed stage branch and its PR.
+type PushedStage struct {
+	Stage          string `json:"stage"`
+	Branch         string `json:"branch"`
+	ForceWithLease bool   `json:"force_with_lease,omitempty"`
+	// PushedBase is the base branch, pushed because it was missing remotely.
+	PushedBase string `json:"pushed_base,omitempty"`
+	PRURL      string `json:"pr_url"`
+	PRCreated  bool   `json:"pr_created"`
+	// JournalError is set when the push could not be recorded in the stack
+	// journal. The branch is on the remote regardless.
+	JournalError string `json:"journal_error,omitempty"`
+}
+
+// SyncedPR is a PR whose description was refreshed with the stack's PR links.
+type SyncedPR struct {
+	Stage string `json:"stage"`
+	URL   string `json:"url"`
+}
+
+// PushResult is the outcome of PushStage or PushStack. On error it holds what
+// was pushed before the failure.
+type PushResult struct {
+	Pushed []PushedStage `json:"pushed"`
+	Synced []SyncedPR    `json:"synced"`
+}
+
+// PushStage pushes the stage at stageIndex, after any earlier stages whose
+// branches are not on origin yet, creating or updating a PR for each. The
+// pushed PRs' descriptions are then refreshed with links to each other.
+func (s *Service) PushStage(stack *state.Stack, stageIndex int) (*PushResult, error) {
+	repoRoot := s.RepoRoot
+	if _, err := exec.LookPath("gh"); err != nil {
+		return nil, errs.New(errs.GHMissing, "gh CLI is required for stage push")
+	}
+
+	stageIndexes, err := stageIndexesToPush(stack, stageIndex, func(branch string) bool {
+		return gitx.RemoteBranchExists(repoRoot, "origin", branch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	stageIndexes = append(stageIndexes, stageIndex)
+
+	return s.pushStages(stack, stageIndexes, false)
+}
+
+// PushStack force-pushes (with lease) every stage whose branch exists
+// locally, creating or updating a PR for each.
+func (s *Service) PushStack(stack *state.Stack) (*PushResult, error) {
+	repoRoot := s.RepoRoot
+	if _, err := exec.LookPath("gh"); err != nil {
+		return nil, errs.New(errs.GHMissing, "gh CLI is required for stack push")
+	}
+
+	stageIndexes, err := startedStageIndexes(stack, func(branch string) bool {
+		return gitx.BranchExists(repoRoot, branch)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.pushStages(stack, stageIndexes, true)
+}
+
+func (s *Service) pushStages(stack *state.Stack, stageIndexes []int, forceWithLease bool) (*PushResult, error) {
+	result := &PushResult{Pushed: []PushedStage{}, Synced: []SyncedPR{}}
+	if len(stageIndexes) == 0 {
+		return result, nil
+	}
+
+	for _, stageIndex := range stageIndexes {
+		s.report(StepPushing, stack.Stages[stageIndex].ID, "%s PR", stack.Stages[stageIndex].ID)
+		pushed, err := s.pushStageAndEnsurePR(stack, stageIndex, forceWithLease)
+		if pushed != nil {
+			result.Pushed = append(result.Pushed, *pushed)
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+
+	synced, err := s.syncStackPRDescriptions(stack, stageIndexes)
+	result.Synced = append(result.Synced, synced...)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func startedStageIndexes(stack *state.Stack, localBranchExists func(branch string) bool) ([]int, error) {
+	if stack == nil {
+		return nil, fmt.Errorf("stack is required")
+	}
+
+	indexes := make([]int, 0, len(stack.Stages))
+	for idx := range stack.Stages {
+		branch := StageBranchFor(stack, idx)
+		if !localBranchExists(branch) {
+			continue
+		}
+		indexes = append(indexes, idx)
+	}
+
+	return indexes, nil
+}
+
+func stageIndexesToPush(stack *state.Stack, currentStageIndex int, remoteBranchExists func(branch string) bool) ([]int, error) {
+	if stack == nil {
+		return nil, fmt.Errorf("stack is required")
+	}
+	if currentStageIndex < 0 || currentStageIndex >= len(stack.Stages) {
+		return nil, fmt.Errorf("current stage index %d out of range", currentStageIndex)
+	}
+
+	indexes := make([]int, 0, currentStageIndex)
+	for idx := 0; idx < currentStageIndex; idx++ {
+		branch := StageBranchFor(stack, idx)
+		if remoteBranchExists(branch) {
+			continue
+		}
+		indexes = append(indexes, idx)
+	}
+
+	return indexes, nil
+}
+
+// pushStageAndEnsurePR pushes one stage branch and creates its PR, or updates
+// the description of the open one. Once the branch is pushed the returned
+// PushedStage is non-nil, even alongside an error.
+func (s *Service) pushStageAndEnsurePR(stack *state.Stack, stageIndex int, forceWithLease bool) (*PushedStage, error) {
+	repoRoot := s.RepoRoot
+	stage := &stack.Stages[stageIndex]
+	branch := StageBranchFor(stack, stageIndex)
+	if !gitx.BranchExists(repoRoot, branch) {
+		return nil, fmt.Errorf("stage branch %q does not exist; run: m stage open --next", branch)
+	}
+
+	pushArgs := []string{"push", "-u", "origin", branch}
+	if forceWithLease {
+		pushArgs = append(pushArgs, "--force-with-lease")
+	}
+
+	if _, err := gitx.Run(repoRoot, pushArgs...); err != nil {
+		return nil, err
+	}
+	pushed := &PushedStage{Stage: stage.ID, Branch: branch, ForceWithLease: forceWithLease}
+	if forceWithLease {
+		s.report(StepPushed, stage.ID, "Force-pushed branch %s (--force-with-lease)", branch)
+	} else {
+		s.report(StepPushed, stage.ID, "Pushed branch %s", branch)
+	}
+
+	prURL, err := findOpenPRURL(repoRoot, branch)
+	if err != nil {
+		return pushed, err
+	}
+
+	baseBranch, err := ParentBranchForStage(repoRoot, stack, stageIndex)
+	if err != nil {
+		return pushed, err
+	}
+
+	if len(state.StageDependencies(stack, stageIndex)) > 0 && !gitx.RemoteBranchExists(repoRoot, "origin", baseBranch) {
+		if _, err := gitx.Run(repoRoot, "push", "-u", "origin", baseBranch); err != nil {
+			return pushed, err
+		}
+		pushed.PushedBase = baseBranch
+		s.report(StepBasePushed, stage.ID, "Base branch was missing remotely; pushed %s", baseBranch)
+	}
+
+	stackPRURLs, err := collectStackOpenPRURLs(repoRoot, stack)
+	if err != nil {
+		return pushed, err
+	}
+
+	title := fmt.Sprintf("%s: %s", stack.Name, stage.Title)
+	if strings.TrimSpace(stage.Title) == "" {
+		title = fmt.Sprintf("%s: %s", stack.Name, stage.ID)
+	}
+	body := stagePRBody(stack, stageIndex, stackPRURLs)
+
+	if strings.TrimSpace(prURL) != "" {
+		if _, err := runGH(repoRoot, "pr", "edit", prURL, "--body", body); err != nil {
+			return pushed, err
+		}
+		pushed.PRURL = prURL
+		s.report(StepPRFound, stage.ID, "Found existing PR for %s: %s", stage.ID, prURL)
+		s.report(StepPRUpdated, stage.ID, "Updated PR description for %s: %s", stage.ID, prURL)
+		s.recordPushEvent(stack.Name, pushed)
+		return pushed, nil
+	}
+
+	if _, err := runGH(repoRoot, "pr", "create", "--head", branch, "--base", baseBranch, "--title", title, "--body", body); err != nil {
+		return pushed, err
+	}
+
+	prURL, err = findOpenPRURL(repoRoot, branch)
+	if err != nil {
+		return pushed, err
+	}
+	if strings.TrimSpace(prURL) == "" {
+		return pushed, fmt.Errorf("failed to determine PR URL after creation")
+	}
+
+	pushed.PRURL = prURL
+	pushed.PRCreated = true
+	s.report(StepPRCreated, stage.ID, "Created PR for %s: %s", stage.ID, prURL)
+	s.recordPushEvent(stack.Name, pushed)
+	return pushed, nil
+}
+
+// recordPushEvent journals a completed push. The branch is already on the
+// remote at this point, so a journal failure is reported but not fatal.
+func (s *Service) recordPushEvent(stackName string, pushed *PushedStage) {
+	payload := map[string]string{"branch": pushed.Branch, "pr_url": pushed.PRURL}
+	if pushed.ForceWithLease {
+		payload["force_with_lease"] = "true"
+	}
+
+	err := state.AppendEvent(s.RepoRoot, stackName, state.Event{
+		Type:    state.EventPush,
+		Actor:   s.Actor,
+		Stage:   pushed.Stage,
+		Payload: payload,
+	})
+	if err != nil {
+		pushed.JournalError = err.Error()
+		s.report(StepJournalFailed, pushed.Stage, "Could not record push in stack journal: %v", err)
+	}
+}
+
+func collectStackOpenPRURLs(repoRoot string, stack *state.Stack) (map[int]string, error) {
+	urls := make(map[int]string, len(stack.Stages))
+	for idx := range stack.Stages {
+		branch := StageBranchFor(stack, idx)
+		prURL, err := findOpenPRURL(repoRoot, branch)
+		if err != nil {
+			return nil, err
+		}
+		urls[idx] = strings.TrimSpace(prURL)
+	}
+
+	return urls, nil
+}
+
+// stagePRBody renders the PR description for the stage at stageIndex, linking
+// the PRs of the stages it builds on and those building on it.
+func stagePRBody(stack *state.Stack, stageIndex int, stackPRURLs map[int]string) string {
+	stage := stack.Stages[stageIndex]
+	hasDetails := strings.TrimSpace(stage.Outcome) != "" || len(stage.Implementation) > 0 || len(stage.Validation) > 0 || len(stage.Risks) > 0 || strings.TrimSpace(stage.Context) != ""
+
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("Stage: %s", stage.ID))
+	if outcome := strings.TrimSpace(stage.Outcome); outcome != "" {
+		body.WriteString("\n\n## Outcome\n")
+		body.WriteString(outcome)
+	}
+
+	if len(stage.Implementation) > 0 {
+		body.WriteString("\n\n## Implementation\n")
+		body.WriteString(formatBulletList(stage.Implementation))
+	}
+
+	if len(stage.Validation) > 0 {
+		body.WriteString("\n\n## Validation\n")
+		body.WriteString(formatBulletList(stage.Validation))
+	}
+
+	if len(stage.Risks) > 0 {
+		body.WriteString("\n\n## Risks\n")
+		for i, risk := range stage.Risks {
+			body.WriteString(fmt.Sprintf("- Risk: %s\n  Mitigation: %s", strings.TrimSpace(risk.Risk), strings.TrimSpace(risk.Mitigation)))
+			if i < len(stage.Risks)-1 {
+				body.WriteString("\n")
+			}
+		}
+	}
+
+	if context := strings.TrimSpace(stage.Context); context != "" {
+		body.WriteString("\n\n## Context\n")
+		body.WriteString(context)
+	}
+
+	if !hasDetails {
+		body.WriteString("\n\n")
+		body.WriteString("No implementation details found for this stage.")
+	}
+
+	if summaries := agentSummaryLines(&stage); len(summaries) > 0 {
+		body.WriteString("\n\n## Agent summaries\n")
+		body.WriteString(strings.Join(summaries, "\n\n"))
+	}
+
+	body.WriteString("\n\n## Stack PRs\n\n### Earlier stages (base chain)\n")
+	upstream := stackPRListLines(stack, stageIndex, stackPRURLs, true)
+	if len(upstream) == 0 {
+		body.WriteString("- None\n")
+	} else {
+		for _, line := range upstream {
+			body.WriteString(line)
+			body.WriteString("\n")
+		}
+	}
+
+	body.WriteString("\n### Later stages (dependent chain)\n")
+	downstream := stackPRListLines(stack, stageIndex, stackPRURLs, false)
+	if len(downstream) == 0 {
+		body.WriteString("- None")
+	} else {
+		for i, line := range downstream {
+			body.WriteString(line)
+			if i < len(downstream)-1 {
+				body.WriteString("\n")
+			}
+		}
+	}
+
+	return body.String()
+}
+
+// agentSummaryLines renders the latest implementation and review reports.
+func agentSummaryLines(stage *state.Stage) []string {
+	var sections []string
+	for _, phase := range []struct {
+		key   string
+		label string
+	}{
+		{state.PhaseImplementing, "Implementation"},
+		{state.PhaseAIReview, "AI review"},
+	} {
+		report := state.LatestReport(stage, phase.key)
+		if report == nil || report.Summary == "" {
+			continue
+		}
+
+		heading := fmt.Sprintf("### %s", phase.label)
+		if report.Commit != "" {
+			heading = fmt.Sprintf("%s (at %s)", heading, ShortCommit(report.Commit))
+		}
+		sections = append(sections, heading+"\n"+report.Summary)
+	}
+
+	return sections
+}
+
+// ShortCommit abbreviates a commit hash for display.
+func ShortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+func formatBulletList(items []string) string {
+	var lines []string
+	for _, item := range items {
+		trimmed := strings.TrimSpace(item)
+		if trimmed == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- %s", trimmed))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func stackPRListLines(stack *state.Stack, stageIndex int, stackPRURLs map[int]string, upstream bool) []string {
+	related := state.StageDescendants(stack, stageIndex)
+	if upstream {
+		related = state.StageAncestors(stack, stageIndex)
+	}
+
+	lines := []string{}
+	for _, idx := range related {
+		stage := stack.Stages[idx]
+		if prURL := strings.TrimSpace(stackPRURLs[idx]); prURL != "" {
+			lines = append(lines, fmt.Sprintf("- %s: %s", stage.ID, prURL))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- %s: (not created)", stage.ID))
+	}
+
+	return lines
+}
+
+// syncStackPRDescriptions refreshes the descriptions of the open PRs for
+// stageIndexes now that every pushed stage has one, and returns those it
+// updated.
+func (s *Service) syncStackPRDescriptions(stack *state.Stack, stageIndexes []int) ([]SyncedPR, error) {
+	if len(stageIndexes) == 0 {
+		return nil, nil
+	}
+
+	stackPRURLs, err := collectStackOpenPRURLs(s.RepoRoot, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	var synced []SyncedPR
+	updated := map[int]struct{}{}
+	for _, stageIndex := range stageIndexes {
+		if stageIndex < 0 || stageIndex >= len(stack.Stages) {
+			continue
+		}
+		if _, seen := updated[stageIndex]; seen {
+			continue
+		}
+
+		prURL := strings.TrimSpace(stackPRURLs[stageIndex])
+		if prURL == "" {
+			continue
+		}
+
+		body := stagePRBody(stack, stageIndex, stackPRURLs)
+		if _, err := runGH(s.RepoRoot, "pr", "edit", prURL, "--body", body); err != nil {
+			return synced, err
+		}
+		stageID := stack.Stages[stageIndex].ID
+		synced = append(synced, SyncedPR{Stage: stageID, URL: prURL})
+		s.report(StepPRSynced, stageID, "Synced PR description for %s: %s", stageID, prURL)
+		updated[stageIndex] = struct{}{}
+	}
+
+	return synced, nil
+}
+
+func findOpenPRURL(repoRoot, headBranch string) (string, error) {
+	out, err := runGH(repoRoot, "pr", "list", "--state", "open", "--head", headBranch, "--json", "url", "--limit", "1")
+	if err != nil {
+		return "", err
+	}
+
+	var prs []struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal([]byte(out), &prs); err != nil {
+		return "", fmt.Errorf("parse gh pr list output: %w", err)
+	}
+	if len(prs) == 0 {
+		return "", nil
+	}
+
+	return strings.TrimSpace(prs[0].URL), nil
+}
+
+func runGH(dir string, args ...string) (string, error) {
+	cmd := exec.Command("gh", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	trimmed := strings.TrimSpace(string(out))
+	if err != nil {
+		if trimmed == "" {
+			trimmed = err.Error()
+		}
+		return "", fmt.Errorf("gh %s: %s", strings.Join(args, " "), trimmed)
+	}
+
+	return trimmed, nil
+}
@@ -0,0 +1,280 @@
+package workflow
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+func TestStartedStageIndexes(t *testing.T) {
+	stack := &state.Stack{
+		Name: "test-stack",
+		Stages: []state.Stage{
+			{ID: "stage-1", Branch: "test-stack/1/stage-1"},
+			{ID: "stage-2", Branch: "test-stack/2/stage-2"},
+			{ID: "stage-3", Branch: "test-stack/3/stage-3"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		exists      map[string]bool
+		wantIndexes []int
+	}{
+		{
+			name:        "none started",
+			exists:      map[string]bool{},
+			wantIndexes: []int{},
+		},
+		{
+			name: "subset started",
+			exists: map[string]bool{
+				"test-stack/1/stage-1": true,
+				"test-stack/3/stage-3": true,
+			},
+			wantIndexes: []int{0, 2},
+		},
+		{
+			name: "all started",
+			exists: map[string]bool{
+				"test-stack/1/stage-1": true,
+				"test-stack/2/stage-2": true,
+				"test-stack/3/stage-3": true,
+			},
+			wantIndexes: []int{0, 1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := startedStageIndexes(stack, func(branch string) bool {
+				return tt.exists[branch]
+			})
+			if err != nil {
+				t.Fatalf("startedStageIndexes returned error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.wantIndexes) {
+				t.Fatalf("startedStageIndexes() = %v, want %v", got, tt.wantIndexes)
+			}
+		})
+	}
+}
+
+func TestStartedStageIndexesNilStack(t *testing.T) {
+	if _, err := startedStageIndexes(nil, func(string) bool { return false }); err == nil {
+		t.Fatal("expected error for nil stack")
+	}
+}
+
+func TestStageIndexesToPush(t *testing.T) {
+	stack := &state.Stack{
+		Name: "test-stack",
+		Stages: []state.Stage{
+			{ID: "stage-1", Branch: "test-stack/1/stage-1"},
+			{ID: "stage-2", Branch: "test-stack/2/stage-2"},
+			{ID: "stage-3", Branch: "test-stack/3/stage-3"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		currentIndex int
+		remoteExists map[string]bool
+		want         []int
+	}{
+		{
+			name:         "pushes missing stage 1 before stage 2",
+			currentIndex: 1,
+			remoteExists: map[string]bool{},
+			want:         []int{0},
+		},
+		{
+			name:         "skips stage 1 when already on remote",
+			currentIndex: 1,
+			remoteExists: map[string]bool{"test-stack/1/stage-1": true},
+			want:         []int{},
+		},
+		{
+			name:         "pushes only missing earlier stages",
+			currentIndex: 2,
+			remoteExists: map[string]bool{"test-stack/2/stage-2": true},
+			want:         []int{0},
+		},
+		{
+			name:         "pushes multiple missing earlier stages",
+			currentIndex: 2,
+			remoteExists: map[string]bool{},
+			want:         []int{0, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stageIndexesToPush(stack, tt.currentIndex, func(branch string) bool {
+				return tt.remoteExists[branch]
+			})
+			if err != nil {
+				t.Fatalf("stageIndexesToPush returned error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("stageIndexesToPush() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStageIndexesToPushErrors(t *testing.T) {
+	t.Run("nil stack", func(t *testing.T) {
+		_, err := stageIndexesToPush(nil, 0, func(string) bool { return false })
+		if err == nil {
+			t.Fatal("expected error for nil stack")
+		}
+	})
+
+	t.Run("out of range index", func(t *testing.T) {
+		stack := &state.Stack{Name: "test-stack", Stages: []state.Stage{{ID: "stage-1"}}}
+		_, err := stageIndexesToPush(stack, 2, func(string) bool { return false })
+		if err == nil {
+			t.Fatal("expected error for out-of-range index")
+		}
+	})
+}
+
+func TestStagePRBodyIncludesStackLinks(t *testing.T) {
+	stack := &state.Stack{
+		Name: "test-stack",
+		Stages: []state.Stage{
+			{ID: "stage-1", Outcome: "First outcome"},
+			{
+				ID:             "stage-2",
+				Outcome:        "Second outcome",
+				Implementation: []string{"Create service interface", "Wire checkout handler"},
+				Validation:     []string{"go test ./...", "exercise checkout quote endpoint"},
+				Risks: []state.StageRisk{
+					{Risk: "Payment gateway latency spikes", Mitigation: "Add retry with backoff and timeout metrics"},
+				},
+			},
+			{ID: "stage-3", Outcome: "Third outcome"},
+		},
+	}
+
+	prURLs := map[int]string{
+		0: "https://github.com/org/repo/pull/10",
+		2: "https://github.com/org/repo/pull/12",
+	}
+
+	body := stagePRBody(stack, 1, prURLs)
+	checks := []string{
+		"Stage: stage-2",
+		"## Outcome",
+		"Second outcome",
+		"## Implementation",
+		"- Create service interface",
+		"## Validation",
+		"- go test ./...",
+		"## Risks",
+		"Risk: Payment gateway latency spikes",
+		"Mitigation: Add retry with backoff and timeout metrics",
+		"## Stack PRs",
+		"### Earlier stages (base chain)",
+		"- stage-1: https://github.com/org/repo/pull/10",
+		"### Later stages (dependent chain)",
+		"- stage-3: https://github.com/org/repo/pull/12",
+	}
+
+	for _, check := range checks {
+		if !strings.Contains(body, check) {
+			t.Fatalf("expected body to contain %q; got:\n%s", check, body)
+		}
+	}
+}
+
+func TestStagePRBodyUsesNotCreatedPlaceholder(t *testing.T) {
+	stack := &state.Stack{
+		Name: "test-stack",
+		Stages: []state.Stage{
+			{ID: "stage-1"},
+			{ID: "stage-2"},
+		},
+	}
+
+	body := stagePRBody(stack, 1, map[int]string{})
+	if !strings.Contains(body, "- stage-1: (not created)") {
+		t.Fatalf("expected placeholder for missing upstream PR; got:\n%s", body)
+	}
+	if !strings.Contains(body, "No implementation details found for this stage.") {
+		t.Fatalf("expected fallback details message; got:\n%s", body)
+	}
+	if !strings.Contains(body, "### Later stages (dependent chain)\n- None") {
+		t.Fatalf("expected downstream none section; got:\n%s", body)
+	}
+}
+
+func TestStagePRBodyIncludesContext(t *testing.T) {
+	stack := &state.Stack{
+		Name: "test-stack",
+		Stages: []state.Stage{
+			{ID: "stage-1", Context: "Keep existing defaults for tax calculation and honor existing flags."},
+		},
+	}
+
+	body := stagePRBody(stack, 0, map[int]string{})
+	if !strings.Contains(body, "## Context") {
+		t.Fatalf("expected context section; got:\n%s", body)
+	}
+	if !strings.Contains(body, "tax calculation") {
+		t.Fatalf("expected context text; got:\n%s", body)
+	}
+}
+
+func TestStagePRBodyListsDependencyGraphStages(t *testing.T) {
+	stack := &state.Stack{
+		Name:            "test-stack",
+		DependencyGraph: true,
+		Stages: []state.Stage{
+			{ID: "base"},
+			{ID: "api", DependsOn: []string{"base"}},
+			{ID: "docs"},
+			{ID: "launch", DependsOn: []string{"api"}},
+		},
+	}
+
+	body := stagePRBody(stack, 1, map[int]string{0: "https://example.com/pr/1"})
+	if !strings.Contains(body, "### Earlier stages (base chain)\n- base: https://example.com/pr/1\n") {
+		t.Fatalf("expected only base upstream; got:\n%s", body)
+	}
+	if !strings.Contains(body, "### Later stages (dependent chain)\n- launch: (not created)") {
+		t.Fatalf("expected only launch downstream; got:\n%s", body)
+	}
+	if strings.Contains(body, "docs") {
+		t.Fatalf("expected unrelated stage to be omitted; got:\n%s", body)
+	}
+}
+
+func TestStagePRBodyIncludesAgentSummaries(t *testing.T) {
+	stage := state.Stage{ID: "stage-1", Outcome: "Ship it"}
+	state.AddStageReport(&stage, state.PhaseImplementing, "Added the retry loop.", "0123456789abcdef")
+	state.AddStageReport(&stage, state.PhaseAIReview, "No blocking issues.", "")
+	stack := &state.Stack{Name: "test-stack", Stages: []state.Stage{stage}}
+
+	body := stagePRBody(stack, 0, map[int]string{})
+	if !strings.Contains(body, "## Agent summaries\n### Implementation (at 0123456789ab)\nAdded the retry loop.") {
+		t.Fatalf("expected implementation summary; got:\n%s", body)
+	}
+	if !strings.Contains(body, "### AI review\nNo blocking issues.") {
+		t.Fatalf("expected review summary; got:\n%s", body)
+	}
+}
+
+func TestStagePRBodyOmitsAgentSummariesWithoutReports(t *testing.T) {
+	stack := &state.Stack{Name: "test-stack", Stages: []state.Stage{{ID: "stage-1", Outcome: "Ship it"}}}
+
+	body := stagePRBody(stack, 0, map[int]string{})
+	if strings.Contains(body, "## Agent summaries") {
+		t.Fatalf("expected no agent summaries section; got:\n%s", body)
+	}
+}
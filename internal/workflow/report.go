@@ -0,0 +1,330 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/config"
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// PhaseReport is an agent's report that its phase of a stage is complete.
+type PhaseReport struct {
+	StackName string
+	StageID   string
+	Phase     string
+	Summary   string
+	Outcome   string
+	Findings  string
+}
+
+// ReportStageDone records a completed agent phase and advances the pipeline:
+// a finished build starts the review agent, and an approved review moves the
+// stage to human-review and starts the stages that are now ready. It backs
+// the report_stage_done tool and `m stage report`, and returns a message for
+// the reporting agent.
+func (s *Service) ReportStageDone(ctx context.Context, report PhaseReport) (string, error) {
+	repoRoot := s.RepoRoot
+	stackName := strings.TrimSpace(report.StackName)
+	stageID := strings.TrimSpace(report.StageID)
+	phase := strings.TrimSpace(report.Phase)
+	summary := strings.TrimSpace(report.Summary)
+	outcome := strings.TrimSpace(report.Outcome)
+	findings := strings.TrimSpace(report.Findings)
+	details := map[string]string{"summary": summary}
+
+	if phase != state.PhaseImplementing && phase != state.PhaseAIReview {
+		return "", fmt.Errorf("phase must be \"implementing\" or \"ai_review\", got %q", phase)
+	}
+	if phase == state.PhaseAIReview && outcome == "" {
+		outcome = state.OutcomeApproved
+	}
+	if err := validateReviewOutcome(phase, outcome, findings); err != nil {
+		return "", err
+	}
+
+	var message string
+	var next *followUp
+	err := state.UpdateAs(repoRoot, s.Actor, func(stacks *state.Stacks) error {
+		report, err := recordStageReport(stacks, repoRoot, stackName, stageID, phase, summary)
+		if err != nil {
+			return err
+		}
+		report.Outcome = outcome
+		report.Findings = findings
+		details["commit"] = report.Commit
+		details["outcome"] = outcome
+
+		switch phase {
+		case state.PhaseImplementing:
+			if err := state.TransitionStageWith(stacks, stackName, stageID, state.StatusAIReview, details); err != nil {
+				return err
+			}
+
+			// Spawn review agent
+			next, err = phaseFollowUp(stacks, stackName, stageID, state.PhaseAIReview)
+			if err != nil {
+				message = fmt.Sprintf("Stage %q is blocked: failed to spawn review agent: %v", stageID, err)
+				return nil
+			}
+			next.describe = func(_ []string, err error) string {
+				if err != nil {
+					return fmt.Sprintf("Stage %q is blocked: failed to spawn review agent: %v", stageID, err)
+				}
+				return fmt.Sprintf("Stage %q transitioned to ai-review. Review agent spawned.", stageID)
+			}
+			return nil
+
+		case state.PhaseAIReview:
+			if outcome == state.OutcomeChangesRequested {
+				message, next, err = requestStageChanges(stacks, stackName, stageID, details)
+				return err
+			}
+
+			if err := state.TransitionStageWith(stacks, stackName, stageID, state.StatusHumanReview, details); err != nil {
+				return err
+			}
+
+			stack, _ := state.FindStack(stacks, stackName)
+			if stack == nil {
+				message = fmt.Sprintf("Stage %q transitioned to human-review.", stageID)
+				return nil
+			}
+
+			// Start stages whose dependencies are now complete, up to the
+			// stack's parallelism limit
+			if state.NextPendingStage(stack) == nil {
+				switch {
+				case state.AllStagesComplete(stack):
+					message = fmt.Sprintf("Stage %q transitioned to human-review. All stages complete.", stageID)
+				case state.HasPendingStages(stack):
+					message = fmt.Sprintf("Stage %q transitioned to human-review. Remaining stages are waiting on dependencies.", stageID)
+				default:
+					message = fmt.Sprintf("Stage %q transitioned to human-review. No more pending stages.", stageID)
+				}
+				return nil
+			}
+			if state.FreeSlots(stack) == 0 {
+				message = fmt.Sprintf("Stage %q transitioned to human-review. All %d slot(s) are busy.", stageID, len(state.ActiveStages(stack)))
+				return nil
+			}
+			if stack.Paused {
+				message = fmt.Sprintf("Stage %q transitioned to human-review. The pipeline is paused; no new stages started.", stageID)
+				return nil
+			}
+
+			_, agent, err := LoadAgent()
+			if err != nil {
+				message = fmt.Sprintf("Stage %q transitioned to human-review but failed to start next stages: %v", stageID, err)
+				return nil
+			}
+
+			launches, claimErr := ClaimReadyStages(stacks, stack)
+			next = &followUp{launches: launches, agent: agent}
+			next.describe = func(started []string, err error) string {
+				err = errors.Join(claimErr, err)
+				switch {
+				case err != nil && len(started) > 0:
+					return fmt.Sprintf("Stage %q -> human-review. Started %s; failed to start more: %v", stageID, strings.Join(started, ", "), err)
+				case err != nil:
+					return fmt.Sprintf("Stage %q transitioned to human-review but failed to start next stage: %v", stageID, err)
+				default:
+					return fmt.Sprintf("Stage %q -> human-review. Next stage(s) %s -> implementing. Build agent(s) spawned.", stageID, strings.Join(started, ", "))
+				}
+			}
+			return nil
+		}
+
+		return fmt.Errorf("unexpected phase: %s", phase)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Agents are started once the report is saved, without the state lock.
+	if next != nil {
+		message = next.run(ctx, repoRoot, s.Actor)
+	}
+
+	return message, nil
+}
+
+// followUp holds the agents a report leads to, which are launched after the
+// report is saved, and how to describe the outcome to the reporting agent.
+type followUp struct {
+	launches []Launch
+	agent    Agent
+	describe func(started []string, err error) string
+}
+
+func (f *followUp) run(ctx context.Context, repoRoot, actor string) string {
+	started, err := LaunchStages(ctx, repoRoot, actor, f.launches, f.agent)
+	return f.describe(started, err)
+}
+
+// recordStageReport stores the agent's phase report on the stage, along with
+// the worktree HEAD at report time, and returns the stored report.
+func recordStageReport(stacks *state.Stacks, repoRoot, stackName, stageID, phase, summary string) (*state.StageReport, error) {
+	stack, _ := state.FindStack(stacks, stackName)
+	if stack == nil {
+		return nil, state.StackNotFoundError(stackName)
+	}
+
+	stage, _ := state.FindStage(stack, stageID)
+	if stage == nil {
+		return nil, fmt.Errorf("stage %q not found in stack %q", stageID, stackName)
+	}
+	// A report for a phase the stage is not in comes from an agent of an
+	// earlier attempt, e.g. one still running when the stage was reset.
+	if status := state.EffectiveStatus(stage); status != state.PhaseStatus(phase) {
+		return nil, errs.New(errs.InvalidTransition, "stage %q is %s; it is not expecting a %s report", stageID, status, phase)
+	}
+
+	worktreePath := stage.Worktree
+	if worktreePath == "" {
+		worktreePath = repoRoot
+	}
+
+	// The commit is best-effort; a report without one is still useful.
+	commit, _ := gitx.Run(worktreePath, "rev-parse", "HEAD")
+	return state.AddStageReport(stage, phase, summary, commit), nil
+}
+
+// validateReviewOutcome checks the outcome and findings reported for phase.
+func validateReviewOutcome(phase, outcome, findings string) error {
+	if phase != state.PhaseAIReview {
+		if outcome != "" {
+			return fmt.Errorf("outcome is only valid for the ai_review phase")
+		}
+		return nil
+	}
+
+	switch outcome {
+	case state.OutcomeApproved:
+		return nil
+	case state.OutcomeChangesRequested:
+		if findings == "" {
+			return fmt.Errorf("findings are required when outcome is %q", state.OutcomeChangesRequested)
+		}
+		return nil
+	default:
+		return fmt.Errorf("outcome must be %q or %q, got %q", state.OutcomeApproved, state.OutcomeChangesRequested, outcome)
+	}
+}
+
+// requestStageChanges handles a review that requested changes: the stage goes
+// back to implementing with the findings, or fails once it has used up its
+// review rounds. It returns the message for the reviewer, or the follow-up
+// that launches the next agents and describes them.
+func requestStageChanges(stacks *state.Stacks, stackName, stageID string, details map[string]string) (string, *followUp, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", nil, fmt.Errorf("load config: %w", err)
+	}
+
+	stack, _ := state.FindStack(stacks, stackName)
+	if stack == nil {
+		return "", nil, state.StackNotFoundError(stackName)
+	}
+	stage, _ := state.FindStage(stack, stageID)
+	if stage == nil {
+		return "", nil, fmt.Errorf("stage %q not found in stack %q", stageID, stackName)
+	}
+
+	stage.ReviewRounds++
+	details["review_round"] = fmt.Sprintf("%d", stage.ReviewRounds)
+
+	if stage.ReviewRounds > cfg.MaxReviewRounds {
+		details["reason"] = fmt.Sprintf("review requested changes %d time(s); max_review_rounds is %d", stage.ReviewRounds, cfg.MaxReviewRounds)
+		if err := state.TransitionStageWith(stacks, stackName, stageID, state.StatusFailed, details); err != nil {
+			return "", nil, err
+		}
+
+		message := fmt.Sprintf("Stage %q failed after %d review round(s).", stageID, stage.ReviewRounds)
+		note, next := claimNextStages(stacks, stack)
+		if next == nil {
+			if note != "" {
+				message += " " + note
+			}
+			return message, nil, nil
+		}
+		describeStarted := next.describe
+		next.describe = func(started []string, err error) string {
+			return message + " " + describeStarted(started, err)
+		}
+		return "", next, nil
+	}
+
+	if err := state.TransitionStageWith(stacks, stackName, stageID, state.StatusImplementing, details); err != nil {
+		return "", nil, err
+	}
+
+	next, err := phaseFollowUp(stacks, stackName, stageID, state.PhaseImplementing)
+	if err != nil {
+		return fmt.Sprintf("Stage %q sent back to implementing but is blocked: failed to spawn build agent: %v", stageID, err), nil, nil
+	}
+	rounds := stage.ReviewRounds
+	next.describe = func(_ []string, err error) string {
+		if err != nil {
+			return fmt.Sprintf("Stage %q sent back to implementing but is blocked: failed to spawn build agent: %v", stageID, err)
+		}
+		return fmt.Sprintf("Stage %q sent back to implementing (review round %d of %d). Build agent spawned with review findings.", stageID, rounds, cfg.MaxReviewRounds)
+	}
+	return "", next, nil
+}
+
+// claimNextStages claims the stages that can use slots freed by a finished
+// stage. It returns the follow-up that launches them, or a note on why none
+// were claimed ("" when there is nothing to start).
+func claimNextStages(stacks *state.Stacks, stack *state.Stack) (string, *followUp) {
+	if state.NextPendingStage(stack) == nil || state.FreeSlots(stack) == 0 {
+		return "", nil
+	}
+	if stack.Paused {
+		return "The pipeline is paused; no new stages started.", nil
+	}
+
+	_, agent, err := LoadAgent()
+	if err != nil {
+		return fmt.Sprintf("Failed to start next stages: %v", err), nil
+	}
+
+	launches, claimErr := ClaimReadyStages(stacks, stack)
+	return "", &followUp{launches: launches, agent: agent, describe: func(started []string, err error) string {
+		err = errors.Join(claimErr, err)
+		switch {
+		case err != nil && len(started) > 0:
+			return fmt.Sprintf("Started %s; failed to start more: %v", strings.Join(started, ", "), err)
+		case err != nil:
+			return fmt.Sprintf("Failed to start next stage: %v", err)
+		default:
+			return fmt.Sprintf("Next stage(s) %s -> implementing.", strings.Join(started, ", "))
+		}
+	}}
+}
+
+// phaseFollowUp prepares the launch of the phase agent for a stage the
+// pipeline just moved on. A stage whose agent cannot be started is left
+// blocked.
+func phaseFollowUp(stacks *state.Stacks, stackName, stageID, phase string) (*followUp, error) {
+	stack, _ := state.FindStack(stacks, stackName)
+	if stack == nil {
+		return nil, state.StackNotFoundError(stackName)
+	}
+	stage, _ := state.FindStage(stack, stageID)
+	if stage == nil {
+		return nil, fmt.Errorf("stage %q not found", stageID)
+	}
+
+	_, agent, err := LoadAgent()
+	if err != nil {
+		markBlocked(stacks, stack, stage, err)
+		return nil, err
+	}
+
+	return &followUp{launches: []Launch{{Stack: stackName, Stage: stageID, Phase: phase}}, agent: agent}, nil
+}
@@ -0,0 +1,135 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+func TestValidateReviewOutcome(t *testing.T) {
+	if err := validateReviewOutcome(state.PhaseAIReview, state.OutcomeApproved, ""); err != nil {
+		t.Fatalf("approved review: %v", err)
+	}
+	if err := validateReviewOutcome(state.PhaseAIReview, state.OutcomeChangesRequested, "fix it"); err != nil {
+		t.Fatalf("changes requested with findings: %v", err)
+	}
+	if err := validateReviewOutcome(state.PhaseAIReview, state.OutcomeChangesRequested, ""); err == nil {
+		t.Fatal("expected findings to be required")
+	}
+	if err := validateReviewOutcome(state.PhaseAIReview, "rejected", ""); err == nil {
+		t.Fatal("expected unknown outcome to be rejected")
+	}
+	if err := validateReviewOutcome(state.PhaseImplementing, state.OutcomeApproved, ""); err == nil {
+		t.Fatal("expected outcome to be rejected for the implementing phase")
+	}
+}
+
+func TestRequestStageChangesFailsAfterMaxRounds(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	if err := os.MkdirAll(filepath.Join(configDir, "m"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "m", "config.json"), []byte(`{"max_review_rounds":2}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second round is the last one allowed: the stage goes back to
+	// implementing (blocked here, as no agent harness is installed).
+	stacks := &state.Stacks{Stacks: []state.Stack{{
+		Name:   "checkout",
+		Stages: []state.Stage{{ID: "api", Status: state.StatusAIReview, ReviewRounds: 1}},
+	}}}
+	if _, _, err := requestStageChanges(stacks, "checkout", "api", map[string]string{}); err != nil {
+		t.Fatalf("requestStageChanges() error = %v", err)
+	}
+	if stage := stacks.Stacks[0].Stages[0]; stage.Status == state.StatusFailed || stage.ReviewRounds != 2 {
+		t.Fatalf("stage = %s after %d rounds, want sent back at the limit", stage.Status, stage.ReviewRounds)
+	}
+
+	stacks = &state.Stacks{Stacks: []state.Stack{{
+		Name:   "checkout",
+		Stages: []state.Stage{{ID: "api", Status: state.StatusAIReview, ReviewRounds: 2}},
+	}}}
+	message, _, err := requestStageChanges(stacks, "checkout", "api", map[string]string{})
+	if err != nil {
+		t.Fatalf("requestStageChanges() error = %v", err)
+	}
+
+	stage := stacks.Stacks[0].Stages[0]
+	if stage.Status != state.StatusFailed {
+		t.Fatalf("status = %q, want failed", stage.Status)
+	}
+	if stage.ReviewRounds != 3 {
+		t.Fatalf("review rounds = %d, want 3", stage.ReviewRounds)
+	}
+	if !strings.Contains(stage.StatusReason, "max_review_rounds is 2") {
+		t.Fatalf("failure reason = %q", stage.StatusReason)
+	}
+	if !strings.Contains(message, "failed after 3 review round(s)") {
+		t.Fatalf("message = %q", message)
+	}
+}
+
+func TestReportFromResetAttemptIsRefused(t *testing.T) {
+	repoRoot := initTestRepo(t)
+
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+
+	stacks := &state.Stacks{Stacks: []state.Stack{{
+		Name: "checkout",
+		Stages: []state.Stage{{
+			ID:     "api",
+			Status: state.StatusImplementing,
+			Agent: &state.AgentProcess{
+				Phase:     state.PhaseImplementing,
+				PID:       cmd.Process.Pid,
+				StartedAt: time.Now().UTC().Format(time.RFC3339),
+			},
+		}},
+	}}}
+	if err := state.SaveStacks(repoRoot, stacks); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := state.Update(repoRoot, func(stacks *state.Stacks) error {
+		return state.ResetStage(stacks, "checkout", "api", state.StatusPending)
+	}); err != nil {
+		t.Fatalf("ResetStage() error = %v", err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent of the reset attempt was not terminated")
+	}
+
+	service := &Service{RepoRoot: repoRoot, Actor: "test"}
+	_, err := service.ReportStageDone(context.Background(), PhaseReport{StackName: "checkout", StageID: "api", Phase: state.PhaseImplementing, Summary: "late"})
+	if !errs.Is(err, errs.InvalidTransition) {
+		t.Fatalf("ReportStageDone() error = %v, want the stale report refused", err)
+	}
+
+	loaded, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stage := loaded.Stacks[0].Stages[0]; stage.Status != state.StatusPending || len(stage.Reports) != 0 {
+		t.Fatalf("stage = %s with reports %+v, want pending with none", stage.Status, stage.Reports)
+	}
+}
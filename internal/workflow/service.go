@@ -0,0 +1,72 @@
+package workflow
+
+import "fmt"
+
+// Service runs stack and stage operations for one repository. The CLI, the
+// MCP server and other Go programs drive the same operations through it;
+// each returns a structured result and streams progress to Reporter while it
+// runs.
+type Service struct {
+	// RepoRoot is the main worktree, where m keeps its state.
+	RepoRoot string
+	// Actor is recorded on the journal events the operations write, e.g. the
+	// CLI command path or "mcp:<tool>".
+	Actor string
+	// Reporter receives progress as operations run. Nil drops it.
+	Reporter Reporter
+}
+
+// Reporter receives progress from Service operations.
+type Reporter interface {
+	Report(Progress)
+}
+
+// ReporterFunc adapts a function to Reporter.
+type ReporterFunc func(Progress)
+
+func (f ReporterFunc) Report(p Progress) {
+	f(p)
+}
+
+// Progress is one step of a running operation.
+type Progress struct {
+	// Step is one of the Step constants.
+	Step    string `json:"step"`
+	Stage   string `json:"stage,omitempty"`
+	Message string `json:"message"`
+}
+
+// Steps reported by Service operations.
+const (
+	StepBranchCreated   = "branch_created"
+	StepBranchReused    = "branch_reused"
+	StepWorktreeCreated = "worktree_created"
+	StepWorktreeReused  = "worktree_reused"
+	StepRebasing        = "rebasing"
+	// StepRebaseFallback warns that a transplant rebase fell back to a
+	// plain one.
+	StepRebaseFallback = "rebase_fallback"
+	// StepSyncConflict warns that an interactive sync stopped at a rebase
+	// conflict.
+	StepSyncConflict = "sync_conflict"
+	StepSyncRestored = "sync_restored"
+	StepPushing      = "pushing"
+	StepPushed       = "pushed"
+	// StepBasePushed warns that a stage's base branch was missing remotely
+	// and was pushed too.
+	StepBasePushed = "base_pushed"
+	StepPRFound    = "pr_found"
+	StepPRCreated  = "pr_created"
+	StepPRUpdated  = "pr_updated"
+	StepPRSynced   = "pr_synced"
+	// StepJournalFailed warns that a completed step could not be recorded
+	// in the stack journal.
+	StepJournalFailed = "journal_failed"
+)
+
+func (s *Service) report(step, stage, format string, args ...any) {
+	if s.Reporter == nil {
+		return
+	}
+	s.Reporter.Report(Progress{Step: step, Stage: stage, Message: fmt.Sprintf(format, args...)})
+}
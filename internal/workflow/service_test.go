@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+func TestServiceOpenStageReportsProgress(t *testing.T) {
+	repoRoot := initTestRepo(t)
+
+	var steps []string
+	service := &Service{
+		RepoRoot: repoRoot,
+		Actor:    "test",
+		Reporter: ReporterFunc(func(p Progress) {
+			if p.Stage != "api" || p.Message == "" {
+				t.Errorf("progress = %+v", p)
+			}
+			steps = append(steps, p.Step)
+		}),
+	}
+
+	if _, err := service.CreateStack(NewStackOptions{Name: "checkout"}); err != nil {
+		t.Fatalf("CreateStack() error = %v", err)
+	}
+	if err := state.UpdateAs(repoRoot, "test", func(stacks *state.Stacks) error {
+		stack, _ := state.FindStack(stacks, "checkout")
+		stack.Stages = []state.Stage{{ID: "api", Status: state.StatusPending}}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := service.OpenStage("checkout", "api")
+	if err != nil {
+		t.Fatalf("OpenStage() error = %v", err)
+	}
+	if !opened.CreatedBranch || !opened.CreatedWorktree {
+		t.Fatalf("first open = %+v, want branch and worktree created", opened)
+	}
+	if _, err := service.OpenStage("checkout", "api"); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+
+	want := []string{StepBranchCreated, StepWorktreeCreated, StepBranchReused, StepWorktreeReused}
+	if !reflect.DeepEqual(steps, want) {
+		t.Fatalf("steps = %v, want %v", steps, want)
+	}
+}
@@ -0,0 +1,153 @@
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/paths"
+	"github.com/mlawd/m-cli/internal/plan"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// NewStackOptions describes a stack for CreateStack.
+type NewStackOptions struct {
+	Name string
+	// Type is feat, fix, chore, or empty.
+	Type string
+	// PlanFile, if set, is parsed for the stack's stages.
+	PlanFile string
+}
+
+// CreateStack adds a stack to the repo's state, with stages from the plan
+// file when one is given, and returns it.
+func (s *Service) CreateStack(opts NewStackOptions) (*state.Stack, error) {
+	stackName := strings.TrimSpace(opts.Name)
+	if err := paths.EnsureValidStackName(stackName); err != nil {
+		return nil, err
+	}
+
+	stackType := state.NormalizeStackType(opts.Type)
+	if stackType != "" && !state.IsValidStackType(stackType) {
+		return nil, fmt.Errorf("invalid stack type %q; valid values: feat, fix, chore", opts.Type)
+	}
+
+	planFile := ""
+	stages := []state.Stage{}
+	dependencyGraph := false
+	if strings.TrimSpace(opts.PlanFile) != "" {
+		var err error
+		planFile, stages, dependencyGraph, err = ParsePlanFile(opts.PlanFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := state.EnsureInitialized(s.RepoRoot); err != nil {
+		return nil, err
+	}
+
+	var created state.Stack
+	err := state.UpdateAs(s.RepoRoot, s.Actor, func(stacks *state.Stacks) error {
+		if existing, _ := state.FindStack(stacks, stackName); existing != nil {
+			return fmt.Errorf("stack %q already exists", stackName)
+		}
+
+		created = state.NewStack(stackName, stackType, planFile, stages)
+		created.DependencyGraph = dependencyGraph
+		stacks.Stacks = append(stacks.Stacks, created)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(state.StacksDir(s.RepoRoot), filepath.FromSlash(stackName)), 0o755); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// AttachPlan sets the stages of a stack that has no plan yet from planFile
+// and returns the updated stack.
+func (s *Service) AttachPlan(stackName, planFile string) (*state.Stack, error) {
+	planFile, stages, dependencyGraph, err := ParsePlanFile(planFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := state.EnsureInitialized(s.RepoRoot); err != nil {
+		return nil, err
+	}
+
+	var attached state.Stack
+	err = state.UpdateAs(s.RepoRoot, s.Actor, func(stacks *state.Stacks) error {
+		stack, _ := state.FindStack(stacks, stackName)
+		if stack == nil {
+			return state.StackNotFoundError(stackName)
+		}
+
+		if strings.TrimSpace(stack.PlanFile) != "" {
+			return fmt.Errorf("stack %q already has an attached plan: %s", stack.Name, stack.PlanFile)
+		}
+
+		stack.PlanFile = planFile
+		stack.Stages = stages
+		stack.DependencyGraph = dependencyGraph
+		stack.CurrentStage = ""
+		attached = *stack
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &attached, nil
+}
+
+// ParsePlanFile returns the absolute plan path, its stages, and whether the
+// plan declares an explicit dependency graph (version 4).
+func ParsePlanFile(planFile string) (string, []state.Stage, bool, error) {
+	absolutePlanFile, err := filepath.Abs(strings.TrimSpace(planFile))
+	if err != nil {
+		return "", nil, false, err
+	}
+	if ext := strings.ToLower(filepath.Ext(absolutePlanFile)); ext != ".md" {
+		return "", nil, false, fmt.Errorf("plan file must use .md extension (markdown with YAML frontmatter)")
+	}
+
+	parsedPlan, err := plan.ParseFile(absolutePlanFile)
+	if err != nil {
+		return "", nil, false, err
+	}
+
+	stages := make([]state.Stage, 0, len(parsedPlan.Stages))
+	for _, stage := range parsedPlan.Stages {
+		risks := make([]state.StageRisk, 0, len(stage.Risks))
+		for _, risk := range stage.Risks {
+			risks = append(risks, state.StageRisk{
+				Risk:       risk.Risk,
+				Mitigation: risk.Mitigation,
+			})
+		}
+
+		stages = append(stages, state.Stage{
+			ID:             stage.ID,
+			Title:          stage.Title,
+			Outcome:        stage.Outcome,
+			Implementation: append([]string(nil), stage.Implementation...),
+			Validation:     append([]string(nil), stage.Validation...),
+			Risks:          risks,
+			Context:        stage.Context,
+			DependsOn:      append([]string(nil), stage.DependsOn...),
+			Timeouts:       maps.Clone(stage.Timeouts),
+			Model:          stage.Model,
+			Status:         state.StatusPending,
+		})
+	}
+
+	return absolutePlanFile, stages, parsedPlan.Version >= 4, nil
+}
@@ -0,0 +1,72 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseStagesFromPlanFileRequiresMarkdownExtension(t *testing.T) {
+	tempDir := t.TempDir()
+	planPath := filepath.Join(tempDir, "plan.yaml")
+
+	content := `---
+version: 2
+stages:
+  - id: foundation
+    title: Foundation
+    outcome: Done
+    implementation:
+      - build it
+    validation:
+      - test it
+    risks:
+      - risk: drift
+        mitigation: review
+---
+`
+	if err := os.WriteFile(planPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+
+	_, _, _, err := ParsePlanFile(planPath)
+	if err == nil {
+		t.Fatal("expected error for non-markdown plan file")
+	}
+	if !strings.Contains(err.Error(), "must use .md extension") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseStagesFromPlanFileV3Context(t *testing.T) {
+	tempDir := t.TempDir()
+	planPath := filepath.Join(tempDir, "plan.md")
+
+	content := `---
+version: 3
+title: Feature rollout
+stages:
+  - id: foundation
+    title: Foundation
+---
+
+## Stage: foundation
+Preserve existing default values and keep behavior compatible with legacy checkout.
+`
+
+	if err := os.WriteFile(planPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+
+	_, stages, _, err := ParsePlanFile(planPath)
+	if err != nil {
+		t.Fatalf("ParsePlanFile returned error: %v", err)
+	}
+	if len(stages) != 1 {
+		t.Fatalf("len(stages) = %d, want 1", len(stages))
+	}
+	if got := strings.TrimSpace(stages[0].Context); got == "" {
+		t.Fatal("expected stage context to be populated")
+	}
+}
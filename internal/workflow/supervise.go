@@ -0,0 +1,218 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/config"
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// Limits bounds an agent phase. Zero durations are not enforced.
+type Limits struct {
+	Implementing time.Duration
+	AIReview     time.Duration
+	Idle         time.Duration
+	Retry        bool
+	Retries      int
+}
+
+// LimitsFromConfig builds the default limits from global config.
+func LimitsFromConfig(cfg *config.Config) (Limits, error) {
+	var limits Limits
+	var err error
+	if limits.Implementing, err = config.ParseTimeout(cfg.Timeouts.Implementing); err != nil {
+		return Limits{}, fmt.Errorf("timeouts.implementing: %w", err)
+	}
+	if limits.AIReview, err = config.ParseTimeout(cfg.Timeouts.AIReview); err != nil {
+		return Limits{}, fmt.Errorf("timeouts.ai_review: %w", err)
+	}
+	if limits.Idle, err = config.ParseTimeout(cfg.Timeouts.Idle); err != nil {
+		return Limits{}, fmt.Errorf("timeouts.idle: %w", err)
+	}
+	limits.Retry = cfg.Timeouts.OnTimeout == config.OnTimeoutRetry
+	limits.Retries = cfg.Timeouts.Retries
+
+	return limits, nil
+}
+
+// ForStage applies the stage's plan overrides to the limits.
+func (l Limits) ForStage(stage *state.Stage) Limits {
+	override := func(key string, current time.Duration) time.Duration {
+		value, ok := stage.Timeouts[key]
+		if !ok {
+			return current
+		}
+		d, err := config.ParseTimeout(value)
+		if err != nil {
+			return current
+		}
+		return d
+	}
+
+	l.Implementing = override(state.PhaseImplementing, l.Implementing)
+	l.AIReview = override(state.PhaseAIReview, l.AIReview)
+	l.Idle = override("idle", l.Idle)
+	return l
+}
+
+// Phase returns the time budget for an agent phase.
+func (l Limits) Phase(phase string) time.Duration {
+	if phase == state.PhaseAIReview {
+		return l.AIReview
+	}
+	return l.Implementing
+}
+
+// Intervention is an action the supervisor took on a stage.
+type Intervention struct {
+	StageID string
+	Reason  string
+	Retried bool
+	// Skipped is set when the agent is over a limit but runs on another host,
+	// so it was left running and its stage untouched.
+	Skipped bool
+	// Err is set when a retry was due but could not be started.
+	Err error
+}
+
+// Supervise checks the active stages of a stack once. Agents that exceeded
+// their phase budget or went idle are terminated and their stage is failed,
+// or retried when the limits allow it and agent is set. Agents on another
+// host are only reported, since they cannot be stopped from here. Stages
+// whose agent exited without reporting are failed and left for a manual retry.
+func Supervise(ctx context.Context, repoRoot, stackName string, limits Limits, agent *Agent, now time.Time) ([]Intervention, error) {
+	var interventions []Intervention
+	// retries maps interventions to the launches that retry their stage.
+	retries := map[int]Launch{}
+	err := state.UpdateAs(repoRoot, "supervisor", func(stacks *state.Stacks) error {
+		interventions = nil
+		clear(retries)
+
+		stack, _ := state.FindStack(stacks, stackName)
+		if stack == nil {
+			return state.StackNotFoundError(stackName)
+		}
+
+		for _, stage := range state.ActiveStages(stack) {
+			reason := ""
+			timedOut := false
+			if lost, lostReason := state.AgentLost(stage); lost {
+				reason = lostReason
+			} else if stage.Agent == nil || !state.AgentRunning(stage.Agent) {
+				continue
+			} else {
+				reason = limitExceeded(repoRoot, stack, stage, limits.ForStage(stage), now)
+				if reason == "" {
+					continue
+				}
+				if !state.AgentOnThisHost(stage.Agent) {
+					interventions = append(interventions, Intervention{StageID: stage.ID, Reason: reason, Skipped: true})
+					continue
+				}
+				_ = state.TerminateAgent(stage.Agent)
+				timedOut = true
+			}
+
+			if err := state.TransitionStageWith(stacks, stack.Name, stage.ID, state.StatusFailed, map[string]string{"reason": reason}); err != nil {
+				return err
+			}
+			item := Intervention{StageID: stage.ID, Reason: reason}
+
+			stageLimits := limits.ForStage(stage)
+			if timedOut && agent != nil && stageLimits.Retry && stage.TimeoutRetries < stageLimits.Retries {
+				launch, err := retryStoppedStage(stacks, stack, stage)
+				if err != nil {
+					item.Err = err
+				} else {
+					retries[len(interventions)] = launch
+				}
+			}
+			interventions = append(interventions, item)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return interventions, err
+	}
+
+	// Retried agents are started once the state is saved.
+	for i, launch := range retries {
+		started, err := LaunchStages(ctx, repoRoot, "supervisor", []Launch{launch}, *agent)
+		interventions[i].Err = err
+		interventions[i].Retried = len(started) == 1
+	}
+
+	return interventions, nil
+}
+
+// limitExceeded describes which limit a running agent is over, or "" if none.
+func limitExceeded(repoRoot string, stack *state.Stack, stage *state.Stage, limits Limits, now time.Time) string {
+	agent := stage.Agent
+	started, err := time.Parse(time.RFC3339, agent.StartedAt)
+	if err != nil {
+		return ""
+	}
+
+	if budget := limits.Phase(agent.Phase); budget > 0 && now.Sub(started) > budget {
+		return fmt.Sprintf("%s agent exceeded its %s timeout", agent.Phase, budget)
+	}
+
+	if limits.Idle > 0 {
+		last := lastActivity(repoRoot, stage, started)
+		if idle := now.Sub(last); idle > limits.Idle {
+			return fmt.Sprintf("%s agent made no commits or log output for %s (idle limit %s)", agent.Phase, idle.Truncate(time.Second), limits.Idle)
+		}
+	}
+
+	return ""
+}
+
+// lastActivity is the latest of the agent's start, its last log write and the
+// last commit in the stage worktree.
+func lastActivity(repoRoot string, stage *state.Stage, started time.Time) time.Time {
+	last := started
+
+	if stage.Agent.Log != "" {
+		if info, err := os.Stat(stage.Agent.Log); err == nil && info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+
+	worktree := stage.Worktree
+	if worktree == "" {
+		worktree = repoRoot
+	}
+	if out, err := gitx.Run(worktree, "log", "-1", "--format=%ct"); err == nil {
+		if seconds, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64); err == nil {
+			if committed := time.Unix(seconds, 0); committed.After(last) {
+				last = committed
+			}
+		}
+	}
+
+	return last
+}
+
+// retryStoppedStage moves a stage the supervisor just failed back to the
+// phase it stopped in and returns the launch that restarts its agent.
+func retryStoppedStage(stacks *state.Stacks, stack *state.Stack, stage *state.Stage) (Launch, error) {
+	retries := stage.TimeoutRetries + 1
+	status, err := state.RetryStage(stacks, stack.Name, stage.ID)
+	if err != nil {
+		return Launch{}, err
+	}
+	stage.TimeoutRetries = retries
+
+	phase := state.PhaseImplementing
+	if status == state.StatusAIReview {
+		phase = state.PhaseAIReview
+	}
+	return Launch{Stack: stack.Name, Stage: stage.ID, Phase: phase}, nil
+}
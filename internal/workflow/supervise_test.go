@@ -0,0 +1,186 @@
+package workflow
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+func TestSuperviseFailsAgentOverBudget(t *testing.T) {
+	repoRoot := initTestRepo(t)
+
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+
+	started := time.Now()
+	stacks := &state.Stacks{Stacks: []state.Stack{{
+		Name: "checkout",
+		Stages: []state.Stage{{
+			ID:     "api",
+			Status: state.StatusImplementing,
+			Agent: &state.AgentProcess{
+				Phase:     state.PhaseImplementing,
+				PID:       cmd.Process.Pid,
+				StartedAt: started.UTC().Format(time.RFC3339),
+			},
+		}},
+	}}}
+	if err := state.SaveStacks(repoRoot, stacks); err != nil {
+		t.Fatal(err)
+	}
+
+	limits := Limits{Implementing: 2 * time.Hour, Retry: true, Retries: 1}
+	interventions, err := Supervise(context.Background(), repoRoot, "checkout", limits, nil, started.Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("Supervise() error = %v", err)
+	}
+	if len(interventions) != 1 || interventions[0].Retried {
+		t.Fatalf("interventions = %+v, want one failure", interventions)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent process was not terminated")
+	}
+
+	loaded, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage := loaded.Stacks[0].Stages[0]
+	if stage.Status != state.StatusFailed || !strings.Contains(stage.StatusReason, "exceeded its 2h0m0s timeout") {
+		t.Fatalf("stage = %s (%q), want failed on timeout", stage.Status, stage.StatusReason)
+	}
+}
+
+func TestSuperviseLeavesAgentOnAnotherHost(t *testing.T) {
+	repoRoot := initTestRepo(t)
+
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+
+	// The local sleep shares the PID recorded for an agent on another host.
+	started := time.Now()
+	stacks := &state.Stacks{Stacks: []state.Stack{{
+		Name: "checkout",
+		Stages: []state.Stage{{
+			ID:     "api",
+			Status: state.StatusImplementing,
+			Agent: &state.AgentProcess{
+				Phase:     state.PhaseImplementing,
+				PID:       cmd.Process.Pid,
+				Hostname:  "some-other-host",
+				StartedAt: started.UTC().Format(time.RFC3339),
+			},
+		}},
+	}}}
+	if err := state.SaveStacks(repoRoot, stacks); err != nil {
+		t.Fatal(err)
+	}
+
+	limits := Limits{Implementing: 2 * time.Hour}
+	interventions, err := Supervise(context.Background(), repoRoot, "checkout", limits, nil, started.Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("Supervise() error = %v", err)
+	}
+	if len(interventions) != 1 || !interventions[0].Skipped {
+		t.Fatalf("interventions = %+v, want one skipped", interventions)
+	}
+
+	select {
+	case <-exited:
+		t.Fatal("local process with the foreign agent's PID was terminated")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	loaded, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stage := loaded.Stacks[0].Stages[0]; stage.Status != state.StatusImplementing {
+		t.Fatalf("stage = %s, want implementing", stage.Status)
+	}
+}
+
+func TestSuperviseRetriesIdleAgent(t *testing.T) {
+	repoRoot := initTestRepo(t)
+
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	go func() { _ = cmd.Wait() }()
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+
+	// The stage overrides the idle window; its last activity is the initial
+	// commit, made just now.
+	stacks := &state.Stacks{Stacks: []state.Stack{{
+		Name: "checkout",
+		Stages: []state.Stage{{
+			ID:       "api",
+			Status:   state.StatusImplementing,
+			Timeouts: map[string]string{"idle": "1m"},
+			Agent: &state.AgentProcess{
+				Phase:     state.PhaseImplementing,
+				PID:       cmd.Process.Pid,
+				StartedAt: time.Now().UTC().Format(time.RFC3339),
+			},
+		}},
+	}}}
+	if err := state.SaveStacks(repoRoot, stacks); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeHarness{}
+	agent := Agent{Harness: fake, Name: "fake"}
+	limits := Limits{Idle: time.Hour, Retry: true, Retries: 1}
+
+	interventions, err := Supervise(context.Background(), repoRoot, "checkout", limits, &agent, time.Now().Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(interventions) != 0 {
+		t.Fatalf("interventions = %+v, want none within the idle window", interventions)
+	}
+
+	interventions, err = Supervise(context.Background(), repoRoot, "checkout", limits, &agent, time.Now().Add(2*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(interventions) != 1 || !interventions[0].Retried {
+		t.Fatalf("interventions = %+v, want one retry", interventions)
+	}
+	if len(fake.builds) != 1 {
+		t.Fatalf("build agents = %v, want one restart", fake.builds)
+	}
+
+	loaded, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage := loaded.Stacks[0].Stages[0]
+	if stage.Status != state.StatusImplementing || stage.TimeoutRetries != 1 {
+		t.Fatalf("stage = %s with %d retries, want implementing after one retry", stage.Status, stage.TimeoutRetries)
+	}
+}
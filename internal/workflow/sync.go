@@ -0,0 +1,959 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// SyncOptions configures SyncStack.
+type SyncOptions struct {
+	// NoPrune keeps merged stages in state and only rebases started
+	// branches. Pruning asks gh which stage PRs were merged.
+	NoPrune bool
+	// InteractiveConflicts stops at a rebase conflict instead of aborting
+	// it: the stage worktree is left mid-rebase and a checkpoint is saved
+	// for ContinueSync or AbortSync.
+	InteractiveConflicts bool
+}
+
+// SyncedStage describes a stage branch rebased by SyncStack.
+type SyncedStage struct {
+	Stage    string `json:"stage"`
+	Branch   string `json:"branch"`
+	Worktree string `json:"worktree"`
+	Onto     string `json:"onto"`
+	// Mode is "plain", or "transplant" when the stage was moved off a merged
+	// parent, or off its parent's commits from before an interactive sync,
+	// onto its new base.
+	Mode string `json:"mode"`
+	// Upstream is the old base a transplant rebase started from.
+	Upstream string `json:"upstream,omitempty"`
+	// UnresolvedParent is set when a transplant fell back to a plain rebase
+	// because the merged parent could not be found.
+	UnresolvedParent string `json:"unresolved_parent,omitempty"`
+	// CreatedWorktree is the worktree recreated for the rebase, if any.
+	CreatedWorktree string `json:"created_worktree,omitempty"`
+}
+
+// SyncResult is the outcome of SyncStack and ContinueSync. On error it holds
+// the stages rebased before the failure.
+type SyncResult struct {
+	Rebased []SyncedStage `json:"rebased"`
+	Pruned  int           `json:"pruned"`
+	// Conflict is the stage an interactive sync stopped at, left mid-rebase
+	// in its worktree.
+	Conflict *SyncedStage `json:"conflict,omitempty"`
+}
+
+// SyncAbortResult is the outcome of AbortSync.
+type SyncAbortResult struct {
+	// Restored lists the stages whose branches were reset to their
+	// pre-sync commits.
+	Restored []string `json:"restored"`
+}
+
+// SyncStack rebases the stack's started stage branches onto their parents in
+// dependency order and, unless opts.NoPrune is set, first removes stages
+// whose PRs were merged along with their worktrees and local branches.
+//
+// The git work runs on a snapshot of the state without holding the state
+// lock, so agents can report meanwhile; the outcome is applied afterwards.
+func (s *Service) SyncStack(stackName string, opts SyncOptions) (*SyncResult, error) {
+	repoRoot := s.RepoRoot
+	stacks, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	stack, _ := state.FindStack(stacks, stackName)
+	if stack == nil {
+		return nil, state.StackNotFoundError(stackName)
+	}
+	if stack.Sync != nil {
+		return nil, syncInProgressError(stack)
+	}
+
+	repoInfo, err := gitx.DiscoverRepo(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	pruneMerged := !opts.NoPrune
+	if pruneMerged {
+		if _, err := exec.LookPath("gh"); err != nil {
+			return nil, errs.Wrap(errs.GHMissing, fmt.Errorf("gh CLI is required for stack sync prune mode"),
+				"Install the GitHub CLI (https://cli.github.com), or rerun with --no-prune to skip merged-stage pruning.")
+		}
+	}
+
+	mergedByBranch := map[string]bool{}
+	if pruneMerged {
+		for _, info := range buildStageSyncInfos(stack, repoInfo.DefaultBranch) {
+			merged, err := stagePRMerged(repoRoot, info.Branch)
+			if err != nil {
+				return nil, err
+			}
+			mergedByBranch[info.Branch] = merged
+		}
+	}
+
+	run := &stackSync{
+		service:       s,
+		defaultBranch: repoInfo.DefaultBranch,
+		startedAt:     time.Now().UTC().Format(time.RFC3339),
+		pruneMerged:   pruneMerged,
+		interactive:   opts.InteractiveConflicts,
+		merged:        mergedByBranch,
+		rebasedOnto:   map[string]string{},
+		result:        &SyncResult{Rebased: []SyncedStage{}},
+	}
+	if err := run.rebaseStages(stacks, stack); err != nil {
+		return run.result, err
+	}
+	if err := run.apply(stacks, stackName, nil); err != nil {
+		return run.result, err
+	}
+
+	return run.result, run.stopped
+}
+
+// ContinueSync finishes the rebase an interactive sync stopped at, once its
+// conflicts are resolved, and goes on with the remaining stages as the
+// interrupted sync would have. It may stop at another conflict.
+func (s *Service) ContinueSync(stackName string) (*SyncResult, error) {
+	repoRoot := s.RepoRoot
+	repoInfo, err := gitx.DiscoverRepo(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	stacks, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	stack, _ := state.FindStack(stacks, stackName)
+	if stack == nil {
+		return nil, state.StackNotFoundError(stackName)
+	}
+	checkpoint := stack.Sync
+	conflict := checkpoint.Conflict()
+	if conflict == nil {
+		return nil, noSyncInProgressError(stack.Name)
+	}
+	stage, _ := state.FindStage(stack, conflict.Stage)
+	if stage == nil {
+		return nil, errs.Wrap(errs.InvalidTransition,
+			fmt.Errorf("stage %q of the interrupted sync is no longer in stack %q", conflict.Stage, stack.Name),
+			"Run `m stack sync --abort` to put the stage branches back.")
+	}
+
+	run := &stackSync{
+		service:       s,
+		defaultBranch: repoInfo.DefaultBranch,
+		startedAt:     checkpoint.StartedAt,
+		pruneMerged:   !checkpoint.NoPrune,
+		interactive:   true,
+		merged:        map[string]bool{},
+		rebasedOnto:   maps.Clone(checkpoint.RebasedOnto),
+		touched:       slices.Clone(checkpoint.Stages),
+		integrations:  slices.Clone(checkpoint.Integrations),
+		result:        &SyncResult{Rebased: []SyncedStage{}},
+	}
+	for _, branch := range checkpoint.Merged {
+		run.merged[branch] = true
+	}
+	if run.rebasedOnto == nil {
+		run.rebasedOnto = map[string]string{}
+	}
+
+	synced := SyncedStage{Stage: stage.ID, Branch: conflict.Branch, Worktree: conflict.Worktree, Onto: conflict.Onto, Mode: conflict.Mode}
+	s.report(StepRebasing, stage.ID, "Continuing rebase of %s onto %s", synced.Branch, synced.Onto)
+	if err := run.continueRebase(synced); err != nil {
+		return run.result, err
+	}
+	if run.stopped != nil {
+		// Still at the same conflict, so the checkpoint stands as saved.
+		return run.result, run.stopped
+	}
+	run.finishRebase(stacks, stack, stage, synced)
+
+	if err := run.rebaseStages(stacks, stack); err != nil {
+		return run.result, err
+	}
+	if err := run.apply(stacks, stackName, checkpoint); err != nil {
+		return run.result, err
+	}
+
+	return run.result, run.stopped
+}
+
+// AbortSync rolls back an interactive sync stopped at a conflict: it aborts
+// the rebase in progress, resets every branch the sync rebased or rebuilt to
+// its commit from before the sync, and removes the worktrees and integration
+// branches the sync created.
+func (s *Service) AbortSync(stackName string) (*SyncAbortResult, error) {
+	stacks, err := state.LoadStacks(s.RepoRoot)
+	if err != nil {
+		return nil, err
+	}
+	stack, _ := state.FindStack(stacks, stackName)
+	if stack == nil {
+		return nil, state.StackNotFoundError(stackName)
+	}
+	checkpoint := stack.Sync
+	conflict := checkpoint.Conflict()
+	if conflict == nil {
+		return nil, noSyncInProgressError(stack.Name)
+	}
+
+	if inProgress, err := rebaseInProgress(conflict.Worktree); err != nil {
+		return nil, err
+	} else if inProgress {
+		if _, err := gitx.Run(conflict.Worktree, "rebase", "--abort"); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &SyncAbortResult{Restored: []string{}}
+	for i := len(checkpoint.Stages) - 1; i >= 0; i-- {
+		touched := checkpoint.Stages[i]
+		head, err := gitx.Run(touched.Worktree, "symbolic-ref", "--quiet", "--short", "HEAD")
+		if err != nil || head != touched.Branch {
+			return nil, errs.Wrap(errs.GitFailure,
+				fmt.Errorf("worktree %s is no longer on %s; cannot restore it", touched.Worktree, touched.Branch),
+				fmt.Sprintf("Check out %s in %s, then rerun `m stack sync --abort`.", touched.Branch, touched.Worktree))
+		}
+		if _, err := gitx.Run(touched.Worktree, "reset", "--keep", touched.Head); err != nil {
+			return nil, err
+		}
+		if touched.CreatedWorktree != "" {
+			if err := removeStageWorktree(s.RepoRoot, touched.CreatedWorktree); err != nil {
+				return nil, err
+			}
+		}
+		s.report(StepSyncRestored, touched.Stage, "Restored %s to %s", touched.Branch, ShortCommit(touched.Head))
+		result.Restored = append(result.Restored, touched.Stage)
+	}
+	slices.Reverse(result.Restored)
+
+	for i := len(checkpoint.Integrations) - 1; i >= 0; i-- {
+		integration := checkpoint.Integrations[i]
+		if integration.Head == "" {
+			if err := removeLocalStageBranch(s.RepoRoot, integration.Branch); err != nil {
+				return nil, err
+			}
+			s.report(StepSyncRestored, "", "Removed %s", integration.Branch)
+			continue
+		}
+		if _, err := gitx.Run(s.RepoRoot, "branch", "-f", integration.Branch, integration.Head); err != nil {
+			return nil, err
+		}
+		s.report(StepSyncRestored, "", "Restored %s to %s", integration.Branch, ShortCommit(integration.Head))
+	}
+
+	err = state.UpdateAs(s.RepoRoot, s.Actor, func(stacksFile *state.Stacks) error {
+		stack, _ := state.FindStack(stacksFile, stackName)
+		if stack == nil {
+			return state.StackNotFoundError(stackName)
+		}
+		if err := checkSyncUnchanged(stack, checkpoint); err != nil {
+			return err
+		}
+
+		for _, touched := range checkpoint.Stages {
+			if stage, _ := state.FindStage(stack, touched.Stage); stage != nil {
+				stage.Parent = touched.Parent
+			}
+		}
+		stack.Sync = nil
+		stacksFile.Record(stack.Name, state.Event{
+			Type:    state.EventSyncAbort,
+			Stage:   conflict.Stage,
+			Payload: map[string]string{"restored": fmt.Sprintf("%d", len(result.Restored))},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// stackSync is one pass of SyncStack over a stack, which an interactive
+// sync can checkpoint at a conflict and ContinueSync resume.
+type stackSync struct {
+	service       *Service
+	defaultBranch string
+	startedAt     string
+	pruneMerged   bool
+	interactive   bool
+	merged        map[string]bool
+	// rebasedOnto maps each stage id to the branch its dependents should
+	// now build on: its own branch, or its parent's once merged.
+	rebasedOnto map[string]string
+	// touched are the stage branches rebased in interactive mode, with
+	// what an abort restores.
+	touched []state.SyncStage
+	// integrations are the integration branches rebuilt in interactive
+	// mode, with their pre-sync commits.
+	integrations []state.SyncIntegration
+	result       *SyncResult
+	// stopped is the conflict an interactive sync stopped at.
+	stopped error
+}
+
+// rebaseStages rebases the stages not yet handled in dependency order and
+// prunes merged stages. When an interactive rebase stops at a conflict it
+// leaves the checkpoint on the stack and returns nil so it can be applied.
+func (y *stackSync) rebaseStages(stacksFile *state.Stacks, stack *state.Stack) error {
+	s := y.service
+	repoRoot := s.RepoRoot
+	stageInfos := buildStageSyncInfos(stack, y.defaultBranch)
+
+	for _, idx := range state.TopologicalOrder(stack) {
+		info := stageInfos[idx]
+		stage := &stack.Stages[info.Index]
+		branch := info.Branch
+		if _, handled := y.rebasedOnto[stage.ID]; handled {
+			continue
+		}
+
+		parentBranch, err := y.parentBranch(stack, info.Index)
+		if err != nil {
+			return err
+		}
+		y.rebasedOnto[stage.ID] = parentBranch
+
+		if y.pruneMerged && y.merged[branch] {
+			continue
+		}
+
+		if !gitx.BranchExists(repoRoot, branch) {
+			continue
+		}
+
+		worktree := strings.TrimSpace(stage.Worktree)
+		if worktree == "" {
+			worktree = StageWorktreePath(repoRoot, stack.Name, stage.ID)
+		}
+		synced := SyncedStage{Stage: stage.ID, Branch: branch, Worktree: worktree, Onto: parentBranch, Mode: "plain"}
+
+		if _, err := os.Stat(worktree); os.IsNotExist(err) {
+			if err := os.MkdirAll(filepath.Dir(worktree), 0o755); err != nil {
+				return err
+			}
+			if err := gitx.AddWorktree(repoRoot, worktree, branch); err != nil {
+				return err
+			}
+			stacksFile.Record(stack.Name, state.Event{
+				Type:    state.EventWorktreeCreated,
+				Stage:   stage.ID,
+				Payload: map[string]string{"branch": branch, "worktree": worktree},
+			})
+			synced.CreatedWorktree = worktree
+			s.report(StepWorktreeCreated, stage.ID, "Created worktree: %s", worktree)
+		} else if err != nil {
+			return err
+		}
+
+		rebaseArgs := []string{"rebase", parentBranch}
+		if shouldTransplantRebase(info, y.pruneMerged, y.merged, parentBranch) {
+			upstream, err := resolveTransplantUpstream(repoRoot, branch, info.OldParent)
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(upstream) == "" {
+				synced.UnresolvedParent = info.OldParent
+				s.report(StepRebaseFallback, stage.ID, "Could not resolve upstream %s for %s; falling back to plain rebase onto %s", info.OldParent, branch, parentBranch)
+			} else {
+				rebaseArgs = []string{"rebase", "--onto", parentBranch, upstream}
+				synced.Mode = "transplant"
+				synced.Upstream = upstream
+			}
+		}
+		if y.interactive && synced.Mode == "plain" {
+			// A parent rebased earlier in this sync may have had conflicts
+			// resolved, so its old commits would not apply cleanly again.
+			if upstream := y.touchedHead(parentBranch); upstream != "" {
+				if onOld, _ := gitIsAncestor(repoRoot, upstream, branch); onOld {
+					rebaseArgs = []string{"rebase", "--onto", parentBranch, upstream}
+					synced.Mode = "transplant"
+					synced.Upstream = upstream
+				}
+			}
+		}
+		if synced.Mode == "transplant" {
+			s.report(StepRebasing, stage.ID, "Transplant rebasing %s onto %s (from %s)", branch, parentBranch, synced.Upstream)
+		} else {
+			s.report(StepRebasing, stage.ID, "Rebasing %s onto %s", branch, parentBranch)
+		}
+
+		if y.interactive {
+			if err := y.rebaseInteractive(stacksFile, stack, stage, synced, rebaseArgs); err != nil || y.stopped != nil {
+				return err
+			}
+		} else if err := runRebaseWithAbort(func(dir string, args ...string) (string, error) {
+			return gitx.Run(dir, args...)
+		}, worktree, rebaseArgs, stage.ID, branch, synced.Mode); err != nil {
+			return err
+		}
+		y.finishRebase(stacksFile, stack, stage, synced)
+	}
+
+	if y.pruneMerged {
+		removed, _, err := pruneMergedStages(stack,
+			func(branch string) (bool, error) {
+				return y.merged[branch], nil
+			},
+			func(stage state.Stage, branch string) error {
+				if err := removeStageWorktree(repoRoot, stage.Worktree); err != nil {
+					return err
+				}
+				if err := removeLocalStageBranch(repoRoot, branch); err != nil {
+					return err
+				}
+				return nil
+			},
+		)
+		if err != nil {
+			return err
+		}
+		y.result.Pruned = removed
+	}
+
+	stack.Sync = nil
+	return nil
+}
+
+// apply saves the outcome of the sync, run on the snapshot of the state it
+// was loaded from, to the stack as it is now. from is the checkpoint the sync
+// resumed, or nil for a new sync; the save is refused if the stack's sync
+// changed meanwhile.
+func (y *stackSync) apply(snapshot *state.Stacks, stackName string, from *state.SyncCheckpoint) error {
+	synced, _ := state.FindStack(snapshot, stackName)
+	return state.UpdateAs(y.service.RepoRoot, y.service.Actor, func(stacksFile *state.Stacks) error {
+		stack, _ := state.FindStack(stacksFile, stackName)
+		if stack == nil {
+			return state.StackNotFoundError(stackName)
+		}
+		if err := checkSyncUnchanged(stack, from); err != nil {
+			return err
+		}
+
+		for _, rebased := range y.result.Rebased {
+			if stage, _ := state.FindStage(stack, rebased.Stage); stage != nil {
+				stage.Branch = rebased.Branch
+				stage.Worktree = rebased.Worktree
+				stage.Parent = rebased.Onto
+			}
+		}
+		if y.result.Pruned > 0 {
+			// The merged stages' worktrees and branches are already gone.
+			_, _, err := pruneMergedStages(stack,
+				func(branch string) (bool, error) {
+					return y.merged[branch], nil
+				},
+				func(state.Stage, string) error {
+					return nil
+				},
+			)
+			if err != nil {
+				return err
+			}
+		}
+		stack.Sync = synced.Sync
+		stacksFile.TakeEvents(snapshot)
+		return nil
+	})
+}
+
+// checkSyncUnchanged refuses to save a sync when the stack's checkpoint is no
+// longer the one the sync started from.
+func checkSyncUnchanged(stack *state.Stack, from *state.SyncCheckpoint) error {
+	current := stack.Sync
+	switch {
+	case current == nil && from == nil:
+		return nil
+	case current == nil:
+		return noSyncInProgressError(stack.Name)
+	case from != nil && current.StartedAt == from.StartedAt && len(current.Stages) == len(from.Stages):
+		return nil
+	default:
+		return syncInProgressError(stack)
+	}
+}
+
+// rebaseInteractive rebases a stage branch, leaving its worktree mid-rebase
+// and checkpointing the sync when the rebase stops at a conflict.
+func (y *stackSync) rebaseInteractive(stacksFile *state.Stacks, stack *state.Stack, stage *state.Stage, synced SyncedStage, rebaseArgs []string) error {
+	head, err := gitx.Run(y.service.RepoRoot, "rev-parse", "--verify", synced.Branch+"^{commit}")
+	if err != nil {
+		return err
+	}
+	y.touched = append(y.touched, state.SyncStage{
+		Stage:           stage.ID,
+		Branch:          synced.Branch,
+		Worktree:        synced.Worktree,
+		Onto:            synced.Onto,
+		Mode:            synced.Mode,
+		Head:            head,
+		Parent:          stage.Parent,
+		CreatedWorktree: synced.CreatedWorktree,
+	})
+
+	_, rebaseErr := gitx.Run(synced.Worktree, rebaseArgs...)
+	if rebaseErr == nil {
+		return nil
+	}
+	inProgress, err := rebaseInProgress(synced.Worktree)
+	if err != nil {
+		return err
+	}
+	if !inProgress {
+		return fmt.Errorf("rebase failed for stage %q (%s) [%s]: %w", stage.ID, synced.Branch, synced.Mode, rebaseErr)
+	}
+
+	merged := []string{}
+	for branch, isMerged := range y.merged {
+		if isMerged {
+			merged = append(merged, branch)
+		}
+	}
+	slices.Sort(merged)
+	stack.Sync = &state.SyncCheckpoint{
+		StartedAt:    y.startedAt,
+		NoPrune:      !y.pruneMerged,
+		Merged:       merged,
+		RebasedOnto:  y.rebasedOnto,
+		Stages:       y.touched,
+		Integrations: y.integrations,
+	}
+	stacksFile.Record(stack.Name, state.Event{
+		Type:    state.EventSyncConflict,
+		Stage:   stage.ID,
+		Payload: map[string]string{"branch": synced.Branch, "onto": synced.Onto, "worktree": synced.Worktree},
+	})
+	y.stop(synced, rebaseErr)
+	return nil
+}
+
+// continueRebase completes the rebase a checkpointed sync stopped at. It
+// stops again if the worktree still has unresolved conflicts.
+func (y *stackSync) continueRebase(synced SyncedStage) error {
+	inProgress, err := rebaseInProgress(synced.Worktree)
+	if err != nil {
+		return err
+	}
+	if inProgress {
+		if _, err := gitx.Run(synced.Worktree, "-c", "core.editor=true", "rebase", "--continue"); err != nil {
+			if stillInProgress, checkErr := rebaseInProgress(synced.Worktree); checkErr != nil {
+				return checkErr
+			} else if stillInProgress {
+				y.stop(synced, err)
+				return nil
+			}
+			return err
+		}
+	}
+
+	if onto, _ := gitIsAncestor(synced.Worktree, synced.Onto, synced.Branch); !onto {
+		return errs.Wrap(errs.RebaseConflict,
+			fmt.Errorf("%s is not on top of %s; the rebase of stage %q was not completed", synced.Branch, synced.Onto, synced.Stage),
+			fmt.Sprintf("Rebase %s onto %s in %s, then rerun `m stack sync --continue`.", synced.Branch, synced.Onto, synced.Worktree),
+			"Run `m stack sync --abort` to put the stage branches back as they were before the sync.")
+	}
+	return nil
+}
+
+func (y *stackSync) finishRebase(stacksFile *state.Stacks, stack *state.Stack, stage *state.Stage, synced SyncedStage) {
+	stacksFile.Record(stack.Name, state.Event{
+		Type:    state.EventSyncRebase,
+		Stage:   stage.ID,
+		Payload: map[string]string{"branch": synced.Branch, "onto": synced.Onto, "mode": synced.Mode},
+	})
+
+	stage.Branch = synced.Branch
+	stage.Worktree = synced.Worktree
+	stage.Parent = synced.Onto
+	y.rebasedOnto[stage.ID] = synced.Branch
+	y.result.Rebased = append(y.result.Rebased, synced)
+}
+
+// parentBranch resolves the branch a stage is rebased onto. In interactive
+// mode it records the pre-sync commit of an integration branch it rebuilds,
+// so dependents can be moved off the old merge and an abort can restore it.
+func (y *stackSync) parentBranch(stack *state.Stack, stageIndex int) (string, error) {
+	repoRoot := y.service.RepoRoot
+	integration := StageIntegrationBranchName(stack.Name, stageIndex, stack.Stages[stageIndex].ID)
+	before := branchHead(repoRoot, integration)
+
+	parent, err := syncParentBranch(repoRoot, stack, stageIndex, y.rebasedOnto, y.defaultBranch)
+	if err != nil || !y.interactive || parent != integration {
+		return parent, err
+	}
+	if branchHead(repoRoot, integration) != before {
+		y.integrations = append(y.integrations, state.SyncIntegration{Branch: integration, Head: before})
+	}
+	return parent, nil
+}
+
+// touchedHead returns the pre-sync commit of a stage or integration branch
+// the sync has moved, or "".
+func (y *stackSync) touchedHead(branch string) string {
+	for _, touched := range y.touched {
+		if touched.Branch == branch {
+			return touched.Head
+		}
+	}
+	for _, integration := range y.integrations {
+		if integration.Branch == branch {
+			return integration.Head
+		}
+	}
+	return ""
+}
+
+func (y *stackSync) stop(synced SyncedStage, rebaseErr error) {
+	y.result.Conflict = &synced
+	y.service.report(StepSyncConflict, synced.Stage, "Stopped at a conflict rebasing %s onto %s in %s", synced.Branch, synced.Onto, synced.Worktree)
+	y.stopped = errs.Wrap(errs.RebaseConflict,
+		fmt.Errorf("rebase of stage %q (%s) onto %s stopped at a conflict: %w", synced.Stage, synced.Branch, synced.Onto, rebaseErr),
+		fmt.Sprintf("Resolve the conflicts in %s and `git add` the files, then run `m stack sync --continue`.", synced.Worktree),
+		"Run `m stack sync --abort` to put the stage branches back as they were before the sync.")
+}
+
+func syncInProgressError(stack *state.Stack) error {
+	conflict := stack.Sync.Conflict()
+	stage := ""
+	if conflict != nil {
+		stage = conflict.Stage
+	}
+	return errs.Wrap(errs.RebaseConflict,
+		fmt.Errorf("stack %q has a sync stopped at a conflict in stage %q", stack.Name, stage),
+		"Run `m stack sync --continue` once the conflicts are resolved, or `m stack sync --abort` to roll the sync back.")
+}
+
+func noSyncInProgressError(stackName string) error {
+	return errs.Wrap(errs.InvalidTransition,
+		fmt.Errorf("stack %q has no sync stopped at a conflict", stackName),
+		"Run `m stack sync --interactive-conflicts` to sync and stop at conflicts.")
+}
+
+// rebaseInProgress reports whether the worktree is stopped in the middle of
+// a rebase.
+func rebaseInProgress(worktree string) (bool, error) {
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		path, err := gitx.Run(worktree, "rev-parse", "--git-path", name)
+		if err != nil {
+			return false, err
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(worktree, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true, nil
+		} else if !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+type stackSyncStageInfo struct {
+	Index        int
+	Branch       string
+	OldParent    string
+	ParentMerged bool
+}
+
+func buildStageSyncInfos(stack *state.Stack, defaultBranch string) []stackSyncStageInfo {
+	infos := make([]stackSyncStageInfo, 0, len(stack.Stages))
+	for idx := range stack.Stages {
+		branch := StageBranchFor(stack, idx)
+		oldParent := strings.TrimSpace(defaultBranch)
+		parentMerged := false
+		if deps := state.StageDependencies(stack, idx); len(deps) == 1 {
+			if _, depIndex := state.FindStage(stack, deps[0]); depIndex >= 0 {
+				oldParent = StageBranchFor(stack, depIndex)
+				parentMerged = true
+			}
+		} else if len(deps) > 1 {
+			oldParent = StageIntegrationBranchName(stack.Name, idx, stack.Stages[idx].ID)
+			parentMerged = true
+		}
+
+		infos = append(infos, stackSyncStageInfo{
+			Index:        idx,
+			Branch:       branch,
+			OldParent:    oldParent,
+			ParentMerged: parentMerged,
+		})
+	}
+
+	return infos
+}
+
+// syncParentBranch resolves the branch a stage should be rebased onto during
+// sync from where its dependencies ended up.
+func syncParentBranch(repoRoot string, stack *state.Stack, stageIndex int, rebasedOnto map[string]string, defaultBranch string) (string, error) {
+	parents := []string{}
+	seen := map[string]bool{}
+	for _, dep := range state.StageDependencies(stack, stageIndex) {
+		parent, ok := rebasedOnto[dep]
+		if !ok || seen[parent] {
+			continue
+		}
+		seen[parent] = true
+		parents = append(parents, parent)
+	}
+
+	if len(parents) == 0 {
+		return defaultBranch, nil
+	}
+
+	return ResolveParentBranch(repoRoot, stack, stageIndex, parents)
+}
+
+func shouldTransplantRebase(info stackSyncStageInfo, pruneMerged bool, mergedByBranch map[string]bool, currentParent string) bool {
+	if !pruneMerged || !info.ParentMerged {
+		return false
+	}
+	if strings.TrimSpace(info.OldParent) == "" {
+		return false
+	}
+	if strings.TrimSpace(currentParent) == strings.TrimSpace(info.OldParent) {
+		return false
+	}
+
+	return mergedByBranch[info.OldParent]
+}
+
+func runRebaseWithAbort(
+	runGit func(dir string, args ...string) (string, error),
+	worktree string,
+	rebaseArgs []string,
+	stageID string,
+	branch string,
+	mode string,
+) error {
+	if _, err := runGit(worktree, rebaseArgs...); err != nil {
+		if _, abortErr := runGit(worktree, "rebase", "--abort"); abortErr != nil {
+			return errs.Wrap(errs.RebaseConflict,
+				fmt.Errorf("rebase failed for stage %q (%s) [%s]: %w\nRebase abort also failed in %s: %v", stageID, branch, mode, err, worktree, abortErr),
+				fmt.Sprintf("Resolve manually in %s (`git rebase --abort`), then rerun `m stack sync`.", worktree))
+		}
+		return errs.Wrap(errs.RebaseConflict,
+			fmt.Errorf("rebase failed for stage %q (%s) [%s]: %w\nAborted rebase in %s", stageID, branch, mode, err, worktree),
+			fmt.Sprintf("Rebase %s by hand in %s and resolve the conflicts, then rerun `m stack sync`.", branch, worktree),
+			"Or rerun `m stack sync --interactive-conflicts` to stop at the conflict and resolve it in place.")
+	}
+
+	return nil
+}
+
+func resolveTransplantUpstream(repoRoot, stageBranch, oldParent string) (string, error) {
+	parent := strings.TrimSpace(oldParent)
+	if parent == "" {
+		return "", nil
+	}
+
+	candidates := []string{parent, "origin/" + parent}
+	for _, candidate := range candidates {
+		exists, err := gitCommitRefExists(repoRoot, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			continue
+		}
+
+		isAncestor, err := gitIsAncestor(repoRoot, candidate, stageBranch)
+		if err != nil {
+			return "", err
+		}
+		if isAncestor {
+			return candidate, nil
+		}
+
+		mergeBase, err := gitMergeBase(repoRoot, candidate, stageBranch)
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(mergeBase) != "" {
+			return mergeBase, nil
+		}
+	}
+
+	return "", nil
+}
+
+func gitCommitRefExists(repoRoot, ref string) (bool, error) {
+	if strings.TrimSpace(ref) == "" {
+		return false, nil
+	}
+	_, err := gitx.Run(repoRoot, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// branchHead returns the commit a local branch points at, or "" when it does
+// not exist.
+func branchHead(repoRoot, branch string) string {
+	if !gitx.BranchExists(repoRoot, branch) {
+		return ""
+	}
+	head, err := gitx.Run(repoRoot, "rev-parse", "--verify", branch+"^{commit}")
+	if err != nil {
+		return ""
+	}
+	return head
+}
+
+func gitIsAncestor(repoRoot, ancestor, descendant string) (bool, error) {
+	if strings.TrimSpace(ancestor) == "" || strings.TrimSpace(descendant) == "" {
+		return false, nil
+	}
+	_, err := gitx.Run(repoRoot, "merge-base", "--is-ancestor", ancestor, descendant)
+	if err != nil {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func gitMergeBase(repoRoot, a, b string) (string, error) {
+	if strings.TrimSpace(a) == "" || strings.TrimSpace(b) == "" {
+		return "", nil
+	}
+
+	return gitx.Run(repoRoot, "merge-base", a, b)
+}
+
+func pruneMergedStages(
+	stack *state.Stack,
+	isMerged func(branch string) (bool, error),
+	cleanup func(stage state.Stage, branch string) error,
+) (int, bool, error) {
+	if stack == nil {
+		return 0, false, fmt.Errorf("stack is required")
+	}
+
+	remaining := make([]state.Stage, 0, len(stack.Stages))
+	prunedCount := 0
+	currentStage := strings.TrimSpace(stack.CurrentStage)
+	preferredCurrentIndex := -1
+	currentStillExists := false
+
+	for i := range stack.Stages {
+		stage := stack.Stages[i]
+		branch := strings.TrimSpace(stage.Branch)
+		if branch == "" {
+			branch = StageBranchName(stack.Name, i, stage.ID)
+		}
+
+		merged, err := isMerged(branch)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if merged {
+			if err := cleanup(stage, branch); err != nil {
+				return 0, false, err
+			}
+			if currentStage != "" && stage.ID == currentStage {
+				preferredCurrentIndex = len(remaining)
+			}
+			prunedCount++
+			continue
+		}
+
+		if currentStage != "" && stage.ID == currentStage {
+			currentStillExists = true
+		}
+
+		remaining = append(remaining, stage)
+	}
+
+	if prunedCount == 0 {
+		return 0, false, nil
+	}
+
+	stack.Stages = remaining
+	if currentStillExists {
+		return prunedCount, true, nil
+	}
+
+	if len(remaining) == 0 {
+		stack.CurrentStage = ""
+		return prunedCount, true, nil
+	}
+
+	if preferredCurrentIndex >= 0 && preferredCurrentIndex < len(remaining) {
+		stack.CurrentStage = remaining[preferredCurrentIndex].ID
+		return prunedCount, true, nil
+	}
+
+	stack.CurrentStage = remaining[len(remaining)-1].ID
+	return prunedCount, true, nil
+}
+
+func stagePRMerged(repoRoot, headBranch string) (bool, error) {
+	out, err := runGH(repoRoot, "pr", "list", "--state", "merged", "--head", headBranch, "--json", "number", "--limit", "1")
+	if err != nil {
+		return false, err
+	}
+
+	var prs []struct {
+		Number int `json:"number"`
+	}
+	if err := json.Unmarshal([]byte(out), &prs); err != nil {
+		return false, fmt.Errorf("parse gh pr list output: %w", err)
+	}
+
+	return len(prs) > 0, nil
+}
+
+func removeStageWorktree(repoRoot, worktree string) error {
+	trimmed := strings.TrimSpace(worktree)
+	if trimmed == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(trimmed); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	_, err := gitx.Run(repoRoot, "worktree", "remove", "--force", trimmed)
+	return err
+}
+
+func removeLocalStageBranch(repoRoot, branch string) error {
+	trimmed := strings.TrimSpace(branch)
+	if trimmed == "" {
+		return nil
+	}
+
+	if !gitx.BranchExists(repoRoot, trimmed) {
+		return nil
+	}
+
+	_, err := gitx.Run(repoRoot, "branch", "-D", trimmed)
+	return err
+}
@@ -0,0 +1,536 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/errs"
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+func TestPruneMergedStagesRemovesMergedAndAdvancesCurrent(t *testing.T) {
+	stack := &state.Stack{
+		Name:         "test-stack",
+		CurrentStage: "stage-2",
+		Stages: []state.Stage{
+			{ID: "stage-1", Branch: "test-stack/1/stage-1", Worktree: "/tmp/wt1"},
+			{ID: "stage-2", Branch: "test-stack/2/stage-2", Worktree: "/tmp/wt2"},
+			{ID: "stage-3", Branch: "test-stack/3/stage-3", Worktree: "/tmp/wt3"},
+		},
+	}
+
+	merged := map[string]bool{
+		"test-stack/2/stage-2": true,
+	}
+
+	cleaned := []string{}
+	pruned, mutated, err := pruneMergedStages(
+		stack,
+		func(branch string) (bool, error) {
+			return merged[branch], nil
+		},
+		func(stage state.Stage, branch string) error {
+			cleaned = append(cleaned, fmt.Sprintf("%s:%s", stage.ID, branch))
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("pruneMergedStages returned error: %v", err)
+	}
+	if !mutated {
+		t.Fatal("expected mutated=true")
+	}
+	if pruned != 1 {
+		t.Fatalf("pruned = %d, want 1", pruned)
+	}
+
+	if got := []string{stack.Stages[0].ID, stack.Stages[1].ID}; !reflect.DeepEqual(got, []string{"stage-1", "stage-3"}) {
+		t.Fatalf("remaining stage IDs = %v, want [stage-1 stage-3]", got)
+	}
+	if stack.CurrentStage != "stage-3" {
+		t.Fatalf("CurrentStage = %q, want %q", stack.CurrentStage, "stage-3")
+	}
+	if !reflect.DeepEqual(cleaned, []string{"stage-2:test-stack/2/stage-2"}) {
+		t.Fatalf("cleanup calls = %v, want one stage-2 call", cleaned)
+	}
+}
+
+func TestPruneMergedStagesNoMerged(t *testing.T) {
+	stack := &state.Stack{
+		Name:         "test-stack",
+		CurrentStage: "stage-1",
+		Stages: []state.Stage{
+			{ID: "stage-1", Branch: "test-stack/1/stage-1"},
+			{ID: "stage-2", Branch: "test-stack/2/stage-2"},
+		},
+	}
+
+	pruned, mutated, err := pruneMergedStages(
+		stack,
+		func(string) (bool, error) { return false, nil },
+		func(state.Stage, string) error { return nil },
+	)
+	if err != nil {
+		t.Fatalf("pruneMergedStages returned error: %v", err)
+	}
+	if mutated {
+		t.Fatal("expected mutated=false")
+	}
+	if pruned != 0 {
+		t.Fatalf("pruned = %d, want 0", pruned)
+	}
+	if stack.CurrentStage != "stage-1" {
+		t.Fatalf("CurrentStage = %q, want stage-1", stack.CurrentStage)
+	}
+}
+
+func TestPruneMergedStagesErrors(t *testing.T) {
+	t.Run("nil stack", func(t *testing.T) {
+		_, _, err := pruneMergedStages(nil, func(string) (bool, error) { return false, nil }, func(state.Stage, string) error { return nil })
+		if err == nil {
+			t.Fatal("expected error for nil stack")
+		}
+	})
+
+	t.Run("merge checker error", func(t *testing.T) {
+		stack := &state.Stack{Name: "test-stack", Stages: []state.Stage{{ID: "stage-1", Branch: "test-stack/1/stage-1"}}}
+		_, _, err := pruneMergedStages(
+			stack,
+			func(string) (bool, error) { return false, fmt.Errorf("gh failed") },
+			func(state.Stage, string) error { return nil },
+		)
+		if err == nil || !strings.Contains(err.Error(), "gh failed") {
+			t.Fatalf("expected checker error, got: %v", err)
+		}
+	})
+}
+
+func TestBuildStageSyncInfos(t *testing.T) {
+	stack := &state.Stack{
+		Name: "test-stack",
+		Stages: []state.Stage{
+			{ID: "stage-1", Branch: "test-stack/1/stage-1"},
+			{ID: "stage-2"},
+			{ID: "stage-3", Branch: "custom/branch-3"},
+		},
+	}
+
+	infos := buildStageSyncInfos(stack, "main")
+	if len(infos) != 3 {
+		t.Fatalf("len(infos) = %d, want 3", len(infos))
+	}
+
+	if infos[0].OldParent != "main" {
+		t.Fatalf("infos[0].OldParent = %q, want main", infos[0].OldParent)
+	}
+	if infos[1].Branch != "test-stack/2/stage-2" {
+		t.Fatalf("infos[1].Branch = %q, want generated stage branch", infos[1].Branch)
+	}
+	if infos[1].OldParent != "test-stack/1/stage-1" {
+		t.Fatalf("infos[1].OldParent = %q, want previous stage branch", infos[1].OldParent)
+	}
+	if infos[2].OldParent != "test-stack/2/stage-2" {
+		t.Fatalf("infos[2].OldParent = %q, want generated stage-2 branch", infos[2].OldParent)
+	}
+}
+
+func TestBuildStageSyncInfosDependencyGraph(t *testing.T) {
+	stack := &state.Stack{
+		Name:            "test-stack",
+		DependencyGraph: true,
+		Stages: []state.Stage{
+			{ID: "base"},
+			{ID: "api", DependsOn: []string{"base"}},
+			{ID: "docs"},
+			{ID: "launch", DependsOn: []string{"api", "docs"}},
+		},
+	}
+
+	infos := buildStageSyncInfos(stack, "main")
+	if infos[1].OldParent != "test-stack/1/base" || !infos[1].ParentMerged {
+		t.Fatalf("infos[1] = %+v, want dependency branch parent", infos[1])
+	}
+	if infos[2].OldParent != "main" || infos[2].ParentMerged {
+		t.Fatalf("infos[2] = %+v, want default branch parent", infos[2])
+	}
+	if infos[3].OldParent != "test-stack/4/launch-integration" {
+		t.Fatalf("infos[3].OldParent = %q, want integration branch", infos[3].OldParent)
+	}
+}
+
+func TestShouldTransplantRebase(t *testing.T) {
+	info := stackSyncStageInfo{
+		Branch:       "test-stack/2/stage-2",
+		OldParent:    "test-stack/1/stage-1",
+		ParentMerged: true,
+	}
+
+	if !shouldTransplantRebase(info, true, map[string]bool{"test-stack/1/stage-1": true}, "main") {
+		t.Fatal("expected transplant rebase when parent stage is merged")
+	}
+
+	if shouldTransplantRebase(info, true, map[string]bool{"test-stack/1/stage-1": false}, "main") {
+		t.Fatal("did not expect transplant rebase when parent stage is not merged")
+	}
+
+	if shouldTransplantRebase(info, false, map[string]bool{"test-stack/1/stage-1": true}, "main") {
+		t.Fatal("did not expect transplant rebase when prune mode is disabled")
+	}
+
+	if shouldTransplantRebase(info, true, map[string]bool{"test-stack/1/stage-1": true}, "test-stack/1/stage-1") {
+		t.Fatal("did not expect transplant rebase when current parent equals old parent")
+	}
+}
+
+func TestRunRebaseWithAbort(t *testing.T) {
+	t.Run("aborts after rebase failure", func(t *testing.T) {
+		calls := []string{}
+		err := runRebaseWithAbort(
+			func(_ string, args ...string) (string, error) {
+				calls = append(calls, strings.Join(args, " "))
+				if len(args) >= 1 && args[0] == "rebase" && (len(args) < 2 || args[1] != "--abort") {
+					return "", fmt.Errorf("conflict")
+				}
+				return "", nil
+			},
+			"/tmp/wt",
+			[]string{"rebase", "main"},
+			"stage-2",
+			"test-stack/2/stage-2",
+			"plain",
+		)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "Aborted rebase") {
+			t.Fatalf("expected aborted message, got: %v", err)
+		}
+		if !reflect.DeepEqual(calls, []string{"rebase main", "rebase --abort"}) {
+			t.Fatalf("unexpected call sequence: %v", calls)
+		}
+	})
+
+	t.Run("reports abort failure", func(t *testing.T) {
+		err := runRebaseWithAbort(
+			func(_ string, args ...string) (string, error) {
+				if len(args) >= 2 && args[0] == "rebase" && args[1] == "--abort" {
+					return "", fmt.Errorf("abort failed")
+				}
+				return "", fmt.Errorf("conflict")
+			},
+			"/tmp/wt",
+			[]string{"rebase", "main"},
+			"stage-2",
+			"test-stack/2/stage-2",
+			"plain",
+		)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "abort also failed") {
+			t.Fatalf("expected abort failure in message, got: %v", err)
+		}
+	})
+}
+
+func TestInteractiveSyncContinue(t *testing.T) {
+	service, api, ui := setupConflictingStack(t)
+
+	result, err := service.SyncStack("checkout", SyncOptions{NoPrune: true, InteractiveConflicts: true})
+	if !errs.Is(err, errs.RebaseConflict) {
+		t.Fatalf("SyncStack() error = %v, want rebase conflict", err)
+	}
+	if result.Conflict == nil || result.Conflict.Stage != "api" {
+		t.Fatalf("conflict = %+v, want stage api", result.Conflict)
+	}
+	if inProgress, err := rebaseInProgress(api.Worktree); err != nil || !inProgress {
+		t.Fatalf("rebaseInProgress() = %v, %v; want the api worktree left mid-rebase", inProgress, err)
+	}
+	checkpoint := loadSyncCheckpoint(t, service.RepoRoot)
+	if checkpoint == nil || checkpoint.Conflict().Stage != "api" || !checkpoint.NoPrune {
+		t.Fatalf("checkpoint = %+v, want one stopped at api", checkpoint)
+	}
+	if _, err := service.SyncStack("checkout", SyncOptions{NoPrune: true}); !errs.Is(err, errs.RebaseConflict) {
+		t.Fatalf("SyncStack() during a stopped sync error = %v, want rebase conflict", err)
+	}
+
+	if _, err := service.ContinueSync("checkout"); !errs.Is(err, errs.RebaseConflict) {
+		t.Fatalf("ContinueSync() with unresolved conflicts error = %v, want rebase conflict", err)
+	}
+
+	writeTestFile(t, filepath.Join(api.Worktree, "shared.txt"), "resolved\n")
+	mustGit(t, api.Worktree, "add", "shared.txt")
+	result, err = service.ContinueSync("checkout")
+	if err != nil {
+		t.Fatalf("ContinueSync() error = %v", err)
+	}
+	var rebased []string
+	for _, synced := range result.Rebased {
+		rebased = append(rebased, synced.Stage)
+	}
+	if !reflect.DeepEqual(rebased, []string{"api", "ui"}) {
+		t.Fatalf("rebased = %v, want [api ui]", rebased)
+	}
+	if checkpoint := loadSyncCheckpoint(t, service.RepoRoot); checkpoint != nil {
+		t.Fatalf("checkpoint = %+v, want cleared", checkpoint)
+	}
+	if onto, _ := gitIsAncestor(service.RepoRoot, "main", ui.Branch); !onto {
+		t.Fatalf("%s is not on top of main", ui.Branch)
+	}
+	if onto, _ := gitIsAncestor(service.RepoRoot, api.Branch, ui.Branch); !onto {
+		t.Fatalf("%s is not on top of %s", ui.Branch, api.Branch)
+	}
+}
+
+func TestInteractiveSyncAbort(t *testing.T) {
+	service, api, ui := setupConflictingStack(t)
+	apiHead, _ := gitx.Run(service.RepoRoot, "rev-parse", api.Branch)
+	uiHead, _ := gitx.Run(service.RepoRoot, "rev-parse", ui.Branch)
+
+	if _, err := service.SyncStack("checkout", SyncOptions{NoPrune: true, InteractiveConflicts: true}); !errs.Is(err, errs.RebaseConflict) {
+		t.Fatalf("SyncStack() error = %v, want rebase conflict", err)
+	}
+
+	result, err := service.AbortSync("checkout")
+	if err != nil {
+		t.Fatalf("AbortSync() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Restored, []string{"api"}) {
+		t.Fatalf("restored = %v, want [api]", result.Restored)
+	}
+	if inProgress, _ := rebaseInProgress(api.Worktree); inProgress {
+		t.Fatal("api worktree is still mid-rebase")
+	}
+	if head, _ := gitx.Run(service.RepoRoot, "rev-parse", api.Branch); head != apiHead {
+		t.Fatalf("%s = %s, want %s", api.Branch, head, apiHead)
+	}
+	if head, _ := gitx.Run(service.RepoRoot, "rev-parse", ui.Branch); head != uiHead {
+		t.Fatalf("%s = %s, want %s", ui.Branch, head, uiHead)
+	}
+	if checkpoint := loadSyncCheckpoint(t, service.RepoRoot); checkpoint != nil {
+		t.Fatalf("checkpoint = %+v, want cleared", checkpoint)
+	}
+	if _, err := service.AbortSync("checkout"); !errs.Is(err, errs.InvalidTransition) {
+		t.Fatalf("second AbortSync() error = %v, want invalid transition", err)
+	}
+}
+
+func TestInteractiveSyncContinueThroughIntegrationBranch(t *testing.T) {
+	service, stages := setupConflictingGraph(t, "a")
+
+	if _, err := service.SyncStack("checkout", SyncOptions{NoPrune: true, InteractiveConflicts: true}); !errs.Is(err, errs.RebaseConflict) {
+		t.Fatalf("SyncStack() error = %v, want rebase conflict", err)
+	}
+
+	a := stages["a"]
+	writeTestFile(t, filepath.Join(a.Worktree, "shared.txt"), "resolved\n")
+	mustGit(t, a.Worktree, "add", "shared.txt")
+	// d builds on the integration of b and c, which still holds a's
+	// conflicting commit; only d's own commit may be replayed.
+	result, err := service.ContinueSync("checkout")
+	if err != nil {
+		t.Fatalf("ContinueSync() error = %v", err)
+	}
+	var rebased []string
+	for _, synced := range result.Rebased {
+		rebased = append(rebased, synced.Stage)
+	}
+	if !reflect.DeepEqual(rebased, []string{"a", "b", "c", "d"}) {
+		t.Fatalf("rebased = %v, want [a b c d]", rebased)
+	}
+	d := stages["d"]
+	for _, parent := range []string{a.Branch, stages["b"].Branch, stages["c"].Branch} {
+		if onto, _ := gitIsAncestor(service.RepoRoot, parent, d.Branch); !onto {
+			t.Fatalf("%s is not on top of %s", d.Branch, parent)
+		}
+	}
+}
+
+func TestInteractiveSyncAbortRestoresIntegrationBranches(t *testing.T) {
+	service, stages := setupConflictingGraph(t, "d")
+	integration := StageIntegrationBranchName("checkout", 3, "d")
+	heads := map[string]string{integration: branchHead(service.RepoRoot, integration)}
+	for _, stage := range stages {
+		heads[stage.Branch] = branchHead(service.RepoRoot, stage.Branch)
+	}
+	// The sync recreates c's missing worktree to rebase it.
+	c := stages["c"]
+	mustGit(t, service.RepoRoot, "worktree", "remove", "--force", c.Worktree)
+
+	if _, err := service.SyncStack("checkout", SyncOptions{NoPrune: true, InteractiveConflicts: true}); !errs.Is(err, errs.RebaseConflict) {
+		t.Fatalf("SyncStack() error = %v, want rebase conflict", err)
+	}
+	checkpoint := loadSyncCheckpoint(t, service.RepoRoot)
+	if checkpoint == nil || len(checkpoint.Integrations) != 1 || checkpoint.Integrations[0].Head != heads[integration] {
+		t.Fatalf("checkpoint = %+v, want the rebuilt integration branch recorded", checkpoint)
+	}
+	if branchHead(service.RepoRoot, integration) == heads[integration] {
+		t.Fatalf("%s was not rebuilt by the sync", integration)
+	}
+
+	result, err := service.AbortSync("checkout")
+	if err != nil {
+		t.Fatalf("AbortSync() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Restored, []string{"a", "b", "c", "d"}) {
+		t.Fatalf("restored = %v, want [a b c d]", result.Restored)
+	}
+	for branch, want := range heads {
+		if head := branchHead(service.RepoRoot, branch); head != want {
+			t.Fatalf("%s = %s, want %s", branch, head, want)
+		}
+	}
+	if _, err := os.Stat(c.Worktree); !os.IsNotExist(err) {
+		t.Fatalf("worktree %s created by the sync was not removed: %v", c.Worktree, err)
+	}
+}
+
+func TestSyncStackLeavesStateUnlockedWhileRebasing(t *testing.T) {
+	service, _, _ := setupConflictingStack(t)
+	previous := state.LockTimeout
+	state.LockTimeout = time.Second
+	t.Cleanup(func() { state.LockTimeout = previous })
+
+	// Stands in for an agent reporting while the sync rebases.
+	var updateErr error
+	service.Reporter = ReporterFunc(func(p Progress) {
+		if p.Step == StepRebasing && p.Stage == "api" {
+			updateErr = state.UpdateAs(service.RepoRoot, "agent", func(stacks *state.Stacks) error {
+				stack, _ := state.FindStack(stacks, "checkout")
+				stack.Stages[1].Title = "Checkout UI"
+				return nil
+			})
+		}
+	})
+
+	if _, err := service.SyncStack("checkout", SyncOptions{NoPrune: true, InteractiveConflicts: true}); !errs.Is(err, errs.RebaseConflict) {
+		t.Fatalf("SyncStack() error = %v, want rebase conflict", err)
+	}
+	if updateErr != nil {
+		t.Fatalf("update during the sync error = %v", updateErr)
+	}
+
+	stacks, err := state.LoadStacks(service.RepoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stack, _ := state.FindStack(stacks, "checkout")
+	if stack.Stages[1].Title != "Checkout UI" {
+		t.Fatalf("ui title = %q, want the update made during the sync kept", stack.Stages[1].Title)
+	}
+	if stack.Sync.Conflict() == nil {
+		t.Fatal("expected the sync checkpoint to be saved")
+	}
+}
+
+// setupConflictingStack builds a stack whose api stage conflicts with a
+// later commit on main and whose ui stage builds on api.
+func setupConflictingStack(t *testing.T) (*Service, *OpenedStage, *OpenedStage) {
+	t.Helper()
+	repoRoot := initTestRepo(t)
+	service := &Service{RepoRoot: repoRoot, Actor: "test"}
+
+	if _, err := service.CreateStack(NewStackOptions{Name: "checkout"}); err != nil {
+		t.Fatalf("CreateStack() error = %v", err)
+	}
+	if err := state.UpdateAs(repoRoot, "test", func(stacks *state.Stacks) error {
+		stack, _ := state.FindStack(stacks, "checkout")
+		stack.Stages = []state.Stage{{ID: "api", Status: state.StatusPending}, {ID: "ui", Status: state.StatusPending}}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := service.OpenStage("checkout", "api")
+	if err != nil {
+		t.Fatalf("OpenStage(api) error = %v", err)
+	}
+	writeTestFile(t, filepath.Join(api.Worktree, "shared.txt"), "api\n")
+	mustGit(t, api.Worktree, "add", "shared.txt")
+	mustGit(t, api.Worktree, "commit", "-q", "-m", "api")
+
+	ui, err := service.OpenStage("checkout", "ui")
+	if err != nil {
+		t.Fatalf("OpenStage(ui) error = %v", err)
+	}
+	writeTestFile(t, filepath.Join(ui.Worktree, "ui.txt"), "ui\n")
+	mustGit(t, ui.Worktree, "add", "ui.txt")
+	mustGit(t, ui.Worktree, "commit", "-q", "-m", "ui")
+
+	writeTestFile(t, filepath.Join(repoRoot, "shared.txt"), "main\n")
+	mustGit(t, repoRoot, "add", "shared.txt")
+	mustGit(t, repoRoot, "commit", "-q", "-m", "main")
+
+	return service, api, ui
+}
+
+// setupConflictingGraph builds a stack where b and c depend on a and d
+// depends on both through an integration branch. The conflicting stage's
+// commit conflicts with a later commit on main.
+func setupConflictingGraph(t *testing.T, conflicting string) (*Service, map[string]*OpenedStage) {
+	t.Helper()
+	repoRoot := initTestRepo(t)
+	service := &Service{RepoRoot: repoRoot, Actor: "test"}
+
+	if _, err := service.CreateStack(NewStackOptions{Name: "checkout"}); err != nil {
+		t.Fatalf("CreateStack() error = %v", err)
+	}
+	if err := state.UpdateAs(repoRoot, "test", func(stacks *state.Stacks) error {
+		stack, _ := state.FindStack(stacks, "checkout")
+		stack.DependencyGraph = true
+		stack.Stages = []state.Stage{
+			{ID: "a", Status: state.StatusPending},
+			{ID: "b", Status: state.StatusPending, DependsOn: []string{"a"}},
+			{ID: "c", Status: state.StatusPending, DependsOn: []string{"a"}},
+			{ID: "d", Status: state.StatusPending, DependsOn: []string{"b", "c"}},
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stages := map[string]*OpenedStage{}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		opened, err := service.OpenStage("checkout", id)
+		if err != nil {
+			t.Fatalf("OpenStage(%s) error = %v", id, err)
+		}
+		file := id + ".txt"
+		if id == conflicting {
+			file = "shared.txt"
+		}
+		writeTestFile(t, filepath.Join(opened.Worktree, file), id+"\n")
+		mustGit(t, opened.Worktree, "add", file)
+		mustGit(t, opened.Worktree, "commit", "-q", "-m", id)
+		stages[id] = opened
+	}
+
+	writeTestFile(t, filepath.Join(repoRoot, "shared.txt"), "main\n")
+	mustGit(t, repoRoot, "add", "shared.txt")
+	mustGit(t, repoRoot, "commit", "-q", "-m", "main")
+
+	return service, stages
+}
+
+func loadSyncCheckpoint(t *testing.T, repoRoot string) *state.SyncCheckpoint {
+	t.Helper()
+	stacks, err := state.LoadStacks(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stack, _ := state.FindStack(stacks, "checkout")
+	return stack.Sync
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,182 @@
+// Package workflow holds stack and stage operations shared by the CLI and the
+// MCP server.
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// StageBranchName is the default branch for the stage at stageIndex.
+func StageBranchName(stackName string, stageIndex int, stageID string) string {
+	return fmt.Sprintf("%s/%d/%s", strings.Trim(stackName, "/"), stageIndex+1, stageID)
+}
+
+// StageBranchFor returns the stage's recorded branch, or its default name.
+func StageBranchFor(stack *state.Stack, stageIndex int) string {
+	stage := stack.Stages[stageIndex]
+	branch := strings.TrimSpace(stage.Branch)
+	if branch == "" {
+		branch = StageBranchName(stack.Name, stageIndex, stage.ID)
+	}
+
+	return branch
+}
+
+// StageIntegrationBranchName is the branch merging the dependencies of a stage
+// that has more than one.
+func StageIntegrationBranchName(stackName string, stageIndex int, stageID string) string {
+	return StageBranchName(stackName, stageIndex, stageID) + "-integration"
+}
+
+// StageWorktreePath is the managed worktree location for a stage.
+func StageWorktreePath(repoRoot, stackName, stageID string) string {
+	return filepath.Join(state.StacksDir(repoRoot), filepath.FromSlash(stackName), filepath.FromSlash(stageID))
+}
+
+// ParentBranchForStage returns the branch a stage is built on: the default
+// branch for stages without dependencies, the dependency's branch for a single
+// dependency, or an integration branch merging every dependency otherwise.
+func ParentBranchForStage(repoRoot string, stack *state.Stack, stageIndex int) (string, error) {
+	parents := []string{}
+	for _, dep := range state.StageDependencies(stack, stageIndex) {
+		depStage, depIndex := state.FindStage(stack, dep)
+		if depStage == nil {
+			// Pruned after merging; its work is on the default branch.
+			continue
+		}
+
+		branch := StageBranchFor(stack, depIndex)
+		if !gitx.BranchExists(repoRoot, branch) {
+			return "", fmt.Errorf("dependency stage branch %q does not exist; start stage %q first", branch, depStage.ID)
+		}
+		parents = append(parents, branch)
+	}
+
+	return ResolveParentBranch(repoRoot, stack, stageIndex, parents)
+}
+
+// ResolveParentBranch picks the parent for a stage built on parents: the
+// default branch when there are none, the parent itself when there is one,
+// and the stage's integration branch otherwise.
+func ResolveParentBranch(repoRoot string, stack *state.Stack, stageIndex int, parents []string) (string, error) {
+	switch len(parents) {
+	case 0:
+		repo, err := gitx.DiscoverRepo(repoRoot)
+		if err != nil {
+			return "", err
+		}
+		return repo.DefaultBranch, nil
+	case 1:
+		return parents[0], nil
+	}
+
+	stage := stack.Stages[stageIndex]
+	branch := StageIntegrationBranchName(stack.Name, stageIndex, stage.ID)
+	if err := EnsureIntegrationBranch(repoRoot, branch, parents); err != nil {
+		return "", fmt.Errorf("stage %q: %w", stage.ID, err)
+	}
+
+	return branch, nil
+}
+
+// EnsureIntegrationBranch points branch at a merge of parents, rebuilding it
+// only when one of the parents has moved on.
+func EnsureIntegrationBranch(repoRoot, branch string, parents []string) error {
+	if gitx.BranchExists(repoRoot, branch) {
+		current := true
+		for _, parent := range parents {
+			if !gitx.IsAncestor(repoRoot, parent, branch) {
+				current = false
+				break
+			}
+		}
+		if current {
+			return nil
+		}
+	}
+
+	commit, err := gitx.MergeCommit(repoRoot, fmt.Sprintf("Integrate %s", strings.Join(parents, ", ")), parents...)
+	if err != nil {
+		return fmt.Errorf("build integration branch %q: %w", branch, err)
+	}
+
+	if _, err := gitx.Run(repoRoot, "branch", "-f", branch, commit); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EnsureStageWorktree creates the stage's branch and worktree unless its
+// recorded worktree already exists.
+func EnsureStageWorktree(repoRoot string, stacks *state.Stacks, stack *state.Stack, stage *state.Stage) error {
+	if strings.TrimSpace(stage.Worktree) != "" {
+		if _, err := os.Stat(stage.Worktree); err == nil {
+			return nil
+		}
+	}
+
+	_, stageIndex := state.FindStage(stack, stage.ID)
+	if stageIndex < 0 {
+		return fmt.Errorf("stage %q not found", stage.ID)
+	}
+
+	branch := strings.TrimSpace(stage.Branch)
+	if branch == "" {
+		branch = StageBranchName(stack.Name, stageIndex, stage.ID)
+	}
+
+	parentBranch, err := ParentBranchForStage(repoRoot, stack, stageIndex)
+	if err != nil {
+		return err
+	}
+
+	return StartStageWorktree(repoRoot, stacks, stack, stageIndex, branch, parentBranch)
+}
+
+// StartStageWorktree creates branch from parentBranch if needed, adds the
+// stage worktree if it is missing, and records both on the stage.
+func StartStageWorktree(repoRoot string, stacks *state.Stacks, stack *state.Stack, stageIndex int, branch, parentBranch string) error {
+	if stack == nil || stageIndex < 0 || stageIndex >= len(stack.Stages) {
+		return fmt.Errorf("invalid stage index")
+	}
+
+	target := &stack.Stages[stageIndex]
+
+	if !gitx.BranchExists(repoRoot, branch) {
+		if err := gitx.CreateBranch(repoRoot, branch, parentBranch); err != nil {
+			return err
+		}
+	}
+
+	worktree := strings.TrimSpace(target.Worktree)
+	if worktree == "" {
+		worktree = StageWorktreePath(repoRoot, stack.Name, target.ID)
+	}
+
+	if _, err := os.Stat(worktree); err != nil {
+		if err := os.MkdirAll(filepath.Dir(worktree), 0o755); err != nil {
+			return err
+		}
+		if err := gitx.AddWorktree(repoRoot, worktree, branch); err != nil {
+			return err
+		}
+		stacks.Record(stack.Name, state.Event{
+			Type:    state.EventWorktreeCreated,
+			Stage:   target.ID,
+			Payload: map[string]string{"branch": branch, "worktree": worktree, "parent_branch": parentBranch},
+		})
+	}
+
+	target.Branch = branch
+	target.Worktree = worktree
+	target.Parent = parentBranch
+
+	return nil
+}
@@ -0,0 +1,26 @@
+// Package m is the Go API for m's repo-local stack state. It reads and
+// changes the same .m/ index as the m CLI and MCP server, under the same
+// lock and journal, so tools built on it see exactly what the CLI sees.
+//
+// Open a repository, then list or create stacks, transition stages, read a
+// stack's journal, or subscribe to changes as they happen:
+//
+//	repo, err := m.Open(".")
+//	if err != nil {
+//		return err
+//	}
+//	stacks, err := repo.Stacks()
+//
+// Plans can be parsed and validated without a repository with ParsePlan and
+// ValidatePlan.
+//
+// # Compatibility
+//
+// Package m follows semantic versioning with the github.com/mlawd/m-cli
+// module. Within a major version, exported identifiers keep their names,
+// signatures and meaning, and the values of the Status, Event and Change
+// constants do not change. Minor versions may add functions, methods,
+// struct fields and constants, so construct structs with field names and
+// handle unknown constant values. Everything under internal/ is outside
+// this guarantee and may change in any release.
+package m
@@ -0,0 +1,90 @@
+package m_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/mlawd/m-cli/pkg/m"
+)
+
+func ExampleOpen() {
+	repo, err := m.Open(".")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stacks, err := repo.Stacks()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, stack := range stacks {
+		fmt.Printf("%s (%d stages)\n", stack.Name, len(stack.Stages))
+		for _, stage := range stack.Stages {
+			fmt.Printf("  %s: %s\n", stage.ID, stage.Status)
+		}
+	}
+}
+
+func ExampleRepo_Subscribe() {
+	repo, err := m.Open(".")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := repo.Subscribe(ctx, m.SubscribeOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for update := range updates {
+		for _, change := range update.Changes {
+			if change.Type == m.ChangeStageStatus && change.To == m.StatusHumanReview {
+				fmt.Printf("%s/%s is ready for review\n", change.Stack, change.Stage)
+			}
+		}
+	}
+}
+
+func ExampleRepo_TransitionStage() {
+	repo, err := m.Open(".")
+	if err != nil {
+		log.Fatal(err)
+	}
+	repo.Actor = "review-bot"
+
+	// Mark a human-reviewed stage done, like the `d` key in `m stack watch`.
+	if err := repo.TransitionStage("checkout", "api", m.StatusDone, map[string]string{"summary": "merged"}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleParsePlan() {
+	dir, err := os.MkdirTemp("", "plan")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "plan.md")
+	plan := "---\nversion: 3\ntitle: Checkout\nstages:\n  - id: api\n    title: API\n---\n\n## Stage: api\nAdd the checkout endpoint.\n"
+	if err := os.WriteFile(path, []byte(plan), 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	parsed, err := m.ParsePlan(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(parsed.Title, parsed.Stages[0].ID, parsed.Stages[0].Context)
+
+	parsed.Stages = append(parsed.Stages, m.PlanStage{ID: "UI", Title: "UI"})
+	fmt.Println(m.ValidatePlan(parsed))
+	// Output:
+	// Checkout api Add the checkout endpoint.
+	// stage "UI" has invalid id; use kebab-case letters/numbers
+}
@@ -0,0 +1,97 @@
+package m
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/mlawd/m-cli/internal/plan"
+)
+
+// Plan is a parsed markdown plan: YAML frontmatter listing the stages and,
+// from version 3, a "## Stage: <id>" section of context for each one.
+type Plan struct {
+	Version int         `json:"version"`
+	Title   string      `json:"title"`
+	Stages  []PlanStage `json:"stages"`
+}
+
+// PlanStage is a stage as the plan declares it.
+type PlanStage struct {
+	ID             string            `json:"id"`
+	Title          string            `json:"title"`
+	Outcome        string            `json:"outcome,omitempty"`
+	Implementation []string          `json:"implementation,omitempty"`
+	Validation     []string          `json:"validation,omitempty"`
+	Risks          []Risk            `json:"risks,omitempty"`
+	DependsOn      []string          `json:"depends_on,omitempty"`
+	Timeouts       map[string]string `json:"timeouts,omitempty"`
+	Model          string            `json:"model,omitempty"`
+	Context        string            `json:"context,omitempty"`
+}
+
+// ParsePlan reads and validates the markdown plan at path.
+func ParsePlan(path string) (*Plan, error) {
+	parsed, err := plan.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Plan{
+		Version: parsed.Version,
+		Title:   parsed.Title,
+		Stages:  make([]PlanStage, 0, len(parsed.Stages)),
+	}
+	for _, stage := range parsed.Stages {
+		planStage := PlanStage{
+			ID:             stage.ID,
+			Title:          stage.Title,
+			Outcome:        stage.Outcome,
+			Implementation: slices.Clone(stage.Implementation),
+			Validation:     slices.Clone(stage.Validation),
+			DependsOn:      slices.Clone(stage.DependsOn),
+			Timeouts:       maps.Clone(stage.Timeouts),
+			Model:          stage.Model,
+			Context:        stage.Context,
+		}
+		for _, risk := range stage.Risks {
+			planStage.Risks = append(planStage.Risks, Risk(risk))
+		}
+		out.Stages = append(out.Stages, planStage)
+	}
+
+	return out, nil
+}
+
+// ValidatePlan checks a plan against the rules for its version, as
+// ParsePlan and `m stack new --plan-file` do. Use it to check a plan built
+// or edited in code before writing it out.
+func ValidatePlan(p *Plan) error {
+	if p == nil {
+		return plan.Validate(nil)
+	}
+
+	file := &plan.File{
+		Version: p.Version,
+		Title:   p.Title,
+		Stages:  make([]plan.FileStage, 0, len(p.Stages)),
+	}
+	for _, stage := range p.Stages {
+		fileStage := plan.FileStage{
+			ID:             stage.ID,
+			Title:          stage.Title,
+			Outcome:        stage.Outcome,
+			Implementation: stage.Implementation,
+			Validation:     stage.Validation,
+			DependsOn:      stage.DependsOn,
+			Timeouts:       stage.Timeouts,
+			Model:          stage.Model,
+			Context:        stage.Context,
+		}
+		for _, risk := range stage.Risks {
+			fileStage.Risks = append(fileStage.Risks, plan.FileRisk(risk))
+		}
+		file.Stages = append(file.Stages, fileStage)
+	}
+
+	return plan.Validate(file)
+}
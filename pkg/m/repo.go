@@ -0,0 +1,155 @@
+package m
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/localignore"
+	"github.com/mlawd/m-cli/internal/state"
+	"github.com/mlawd/m-cli/internal/workflow"
+)
+
+// DefaultActor is recorded on journal events written through a Repo whose
+// Actor is empty.
+const DefaultActor = "sdk"
+
+// Repo is the m state of one git repository. It is safe for concurrent use;
+// every change takes the same lock as the CLI.
+type Repo struct {
+	// Actor is recorded on the journal events this Repo writes, so the CLI
+	// and other readers can tell which tool made a change.
+	Actor string
+
+	root      string
+	commonDir string
+}
+
+// Open finds the git repository containing dir. Linked worktrees resolve to
+// the main worktree, where m keeps its state. The repository does not need
+// to be initialized until a stack is created.
+func Open(dir string) (*Repo, error) {
+	info, err := gitx.DiscoverRepo(dir)
+	if err != nil {
+		return nil, fmt.Errorf("discover repo: %w", err)
+	}
+
+	return &Repo{
+		root:      gitx.SharedRoot(info.TopLevel, info.CommonDir),
+		commonDir: info.CommonDir,
+	}, nil
+}
+
+// Root returns the path of the main worktree.
+func (r *Repo) Root() string {
+	return r.root
+}
+
+// Init creates the repo-local state directory and keeps it out of git, like
+// `m init`. It is safe to call on an initialized repository.
+func (r *Repo) Init() error {
+	if err := state.EnsureInitialized(r.root); err != nil {
+		return err
+	}
+
+	return localignore.EnsurePattern(r.commonDir, ".m/")
+}
+
+// Stacks returns every stack in the repository, in creation order.
+func (r *Repo) Stacks() ([]Stack, error) {
+	stacks, err := state.LoadStacks(r.root)
+	if err != nil {
+		return nil, err
+	}
+
+	return stacksFromState(stacks), nil
+}
+
+// Stack returns the named stack.
+func (r *Repo) Stack(name string) (*Stack, error) {
+	stacks, err := state.LoadStacks(r.root)
+	if err != nil {
+		return nil, err
+	}
+
+	stack, err := findStack(stacks, strings.TrimSpace(name))
+	if err != nil {
+		return nil, err
+	}
+
+	out := stackFromState(stack)
+	return &out, nil
+}
+
+// CreateStackOptions describes a stack for CreateStack.
+type CreateStackOptions struct {
+	Name string
+	// Type is "feat", "fix", "chore" or empty.
+	Type string
+	// PlanFile, if set, is the markdown plan the stack's stages come from.
+	// A plan can also be attached later with AttachPlan.
+	PlanFile string
+}
+
+// CreateStack adds a stack, initializing the repository's state if needed,
+// and returns it.
+func (r *Repo) CreateStack(opts CreateStackOptions) (*Stack, error) {
+	stack, err := r.service().CreateStack(workflow.NewStackOptions{
+		Name:     opts.Name,
+		Type:     opts.Type,
+		PlanFile: opts.PlanFile,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := stackFromState(stack)
+	return &out, nil
+}
+
+// AttachPlan sets the stages of a stack that has no plan yet from the
+// markdown plan at planFile, and returns the updated stack.
+func (r *Repo) AttachPlan(stackName, planFile string) (*Stack, error) {
+	stack, err := r.service().AttachPlan(strings.TrimSpace(stackName), planFile)
+	if err != nil {
+		return nil, err
+	}
+
+	out := stackFromState(stack)
+	return &out, nil
+}
+
+// TransitionStage moves a stage to status and records the transition in the
+// stack's journal, with details such as a "summary" or "reason" attached.
+// Only the transitions the pipeline allows are accepted; see the Status
+// constants.
+func (r *Repo) TransitionStage(stackName, stageID, status string, details map[string]string) error {
+	return state.UpdateAs(r.root, r.actor(), func(stacks *state.Stacks) error {
+		return state.TransitionStageWith(stacks, strings.TrimSpace(stackName), strings.TrimSpace(stageID), status, details)
+	})
+}
+
+// Events returns the named stack's journal, oldest first.
+func (r *Repo) Events(stackName string) ([]Event, error) {
+	events, err := state.LoadEvents(r.root, strings.TrimSpace(stackName))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Event, 0, len(events))
+	for _, event := range events {
+		out = append(out, Event(event))
+	}
+	return out, nil
+}
+
+func (r *Repo) service() *workflow.Service {
+	return &workflow.Service{RepoRoot: r.root, Actor: r.actor()}
+}
+
+func (r *Repo) actor() string {
+	if actor := strings.TrimSpace(r.Actor); actor != "" {
+		return actor
+	}
+	return DefaultActor
+}
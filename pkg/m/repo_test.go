@@ -0,0 +1,178 @@
+package m
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/gitx"
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+const testPlan = `---
+version: 3
+title: Checkout
+stages:
+  - id: api
+    title: API
+  - id: ui
+    title: UI
+---
+
+## Stage: api
+Add the checkout endpoint.
+
+## Stage: ui
+Add the checkout page.
+`
+
+func TestRepoCreatesStacksAndTransitionsStages(t *testing.T) {
+	repoRoot := initTestRepo(t)
+	planFile := filepath.Join(repoRoot, "plan.md")
+	if err := os.WriteFile(planFile, []byte(testPlan), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := Open(repoRoot)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	repo.Actor = "dashboard"
+
+	created, err := repo.CreateStack(CreateStackOptions{Name: "checkout", Type: "feat", PlanFile: planFile})
+	if err != nil {
+		t.Fatalf("CreateStack() error = %v", err)
+	}
+	if len(created.Stages) != 2 || created.Stages[0].Status != StatusPending || created.Parallel != 1 {
+		t.Fatalf("created = %+v", created)
+	}
+
+	if err := repo.TransitionStage("checkout", "api", StatusDone, nil); err == nil {
+		t.Fatal("pending -> done succeeded, want invalid transition")
+	}
+	if err := repo.TransitionStage("checkout", "api", StatusImplementing, map[string]string{"summary": "started by hand"}); err != nil {
+		t.Fatalf("TransitionStage() error = %v", err)
+	}
+
+	stack, err := repo.Stack("checkout")
+	if err != nil {
+		t.Fatalf("Stack() error = %v", err)
+	}
+	if api := stack.Stage("api"); api == nil || api.Status != StatusImplementing || api.StartedAt == "" {
+		t.Fatalf("api = %+v", api)
+	}
+
+	events, err := repo.Events("checkout")
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Type != EventStageTransition || events[0].Actor != "dashboard" || events[0].Payload["summary"] != "started by hand" {
+		t.Fatalf("events = %+v", events)
+	}
+
+	if _, err := repo.Stack("missing"); err == nil {
+		t.Fatal("Stack(missing) succeeded, want error")
+	}
+}
+
+func TestRepoSubscribeDeliversChanges(t *testing.T) {
+	repoRoot := initTestRepo(t)
+	repo, err := Open(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	updates, err := repo.Subscribe(ctx, SubscribeOptions{Poll: true, PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if first := <-updates; len(first.Stacks) != 0 || len(first.Changes) != 0 {
+		t.Fatalf("first update = %+v", first)
+	}
+
+	if _, err := repo.CreateStack(CreateStackOptions{Name: "checkout"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for update := range updates {
+		if update.Err != nil {
+			t.Fatalf("update error = %v", update.Err)
+		}
+		if len(update.Changes) == 0 {
+			continue
+		}
+		if update.Changes[0].Type != ChangeStackAdded || update.Changes[0].String() != "stack checkout added" {
+			t.Fatalf("changes = %+v", update.Changes)
+		}
+		return
+	}
+	t.Fatal("subscription closed before the stack was added")
+}
+
+// TestConstantsMatchState guards the public constants, which are part of
+// the compatibility promise, against drift from the values m stores.
+func TestConstantsMatchState(t *testing.T) {
+	pairs := map[string]string{
+		StatusPending:        state.StatusPending,
+		StatusImplementing:   state.StatusImplementing,
+		StatusAIReview:       state.StatusAIReview,
+		StatusHumanReview:    state.StatusHumanReview,
+		StatusDone:           state.StatusDone,
+		StatusFailed:         state.StatusFailed,
+		StatusBlocked:        state.StatusBlocked,
+		EventStageTransition: state.EventStageTransition,
+		EventWorktreeCreated: state.EventWorktreeCreated,
+		EventPush:            state.EventPush,
+		EventSyncRebase:      state.EventSyncRebase,
+		EventSyncConflict:    state.EventSyncConflict,
+		EventSyncAbort:       state.EventSyncAbort,
+		EventAgentSpawn:      state.EventAgentSpawn,
+		EventAgentExit:       state.EventAgentExit,
+		EventPause:           state.EventPause,
+		EventResume:          state.EventResume,
+		EventLegacyImport:    state.EventLegacyImport,
+		ChangeStackAdded:     state.ChangeStackAdded,
+		ChangeStackRemoved:   state.ChangeStackRemoved,
+		ChangeStackPaused:    state.ChangeStackPaused,
+		ChangeStackResumed:   state.ChangeStackResumed,
+		ChangeStageAdded:     state.ChangeStageAdded,
+		ChangeStageRemoved:   state.ChangeStageRemoved,
+		ChangeStageStatus:    state.ChangeStageStatus,
+		ChangeAgentStarted:   state.ChangeAgentStarted,
+		ChangeAgentExited:    state.ChangeAgentExited,
+	}
+	for public, internal := range pairs {
+		if public != internal {
+			t.Errorf("public constant %q does not match %q", public, internal)
+		}
+	}
+	if PhaseImplementing != state.PhaseImplementing || PhaseAIReview != state.PhaseAIReview {
+		t.Error("phase constants do not match")
+	}
+}
+
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if _, err := gitx.Run(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,61 @@
+package m
+
+import (
+	"context"
+	"time"
+
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// SubscribeOptions tunes Subscribe. The zero value uses file notifications
+// with a one-second polling fallback.
+type SubscribeOptions struct {
+	// PollInterval is how often the index is checked when file
+	// notifications are unavailable or disabled.
+	PollInterval time.Duration
+	// Poll skips file notifications, e.g. on network filesystems where
+	// they are unreliable.
+	Poll bool
+}
+
+// Update is a snapshot of every stack, with the changes since the previous
+// update. The first update has no changes. An update with Err set reports a
+// snapshot that could not be read; the subscription keeps going.
+type Update struct {
+	Stacks  []Stack
+	Changes []Change
+	Err     error
+}
+
+// Subscribe delivers an Update each time any process writes the stack
+// index, starting with the current state, until ctx is done; the channel is
+// then closed. Each update's Changes are relative to the one before it, so a
+// slow receiver delays later updates but misses no changes.
+func (r *Repo) Subscribe(ctx context.Context, opts SubscribeOptions) (<-chan Update, error) {
+	source, err := state.Watch(ctx, r.root, state.WatchOptions{PollInterval: opts.PollInterval, Poll: opts.Poll})
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan Update, 1)
+	go func() {
+		defer close(updates)
+		for update := range source {
+			out := Update{Err: update.Err}
+			if update.Stacks != nil {
+				out.Stacks = stacksFromState(update.Stacks)
+			}
+			for _, change := range update.Changes {
+				out.Changes = append(out.Changes, Change(change))
+			}
+
+			select {
+			case updates <- out:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return updates, nil
+}
@@ -0,0 +1,244 @@
+package m
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/mlawd/m-cli/internal/state"
+)
+
+// Stage statuses. A stage moves pending → implementing → ai-review →
+// human-review → done; an ai-review that requests changes sends it back to
+// implementing, and an agent phase can stop in failed or blocked.
+const (
+	StatusPending      = "pending"
+	StatusImplementing = "implementing"
+	StatusAIReview     = "ai-review"
+	StatusHumanReview  = "human-review"
+	StatusDone         = "done"
+	StatusFailed       = "failed"
+	StatusBlocked      = "blocked"
+)
+
+// Agent phases, as recorded on Agent and Report.
+const (
+	PhaseImplementing = "implementing"
+	PhaseAIReview     = "ai_review"
+)
+
+// Event types recorded in a stack's journal.
+const (
+	EventStageTransition = "stage_transition"
+	EventWorktreeCreated = "worktree_created"
+	EventPush            = "push"
+	EventSyncRebase      = "sync_rebase"
+	EventSyncConflict    = "sync_conflict"
+	EventSyncAbort       = "sync_abort"
+	EventAgentSpawn      = "agent_spawn"
+	EventAgentExit       = "agent_exit"
+	EventPause           = "pause"
+	EventResume          = "resume"
+	EventLegacyImport    = "legacy_import"
+)
+
+// Change types delivered by Subscribe.
+const (
+	ChangeStackAdded   = "stack_added"
+	ChangeStackRemoved = "stack_removed"
+	ChangeStackPaused  = "stack_paused"
+	ChangeStackResumed = "stack_resumed"
+	ChangeStageAdded   = "stage_added"
+	ChangeStageRemoved = "stage_removed"
+	ChangeStageStatus  = "stage_status"
+	ChangeAgentStarted = "agent_started"
+	ChangeAgentExited  = "agent_exited"
+)
+
+// Stack is a named, ordered set of stages built from a plan. Timestamps are
+// RFC 3339 strings, as stored in the index.
+type Stack struct {
+	Name         string `json:"name"`
+	Type         string `json:"type,omitempty"`
+	PlanFile     string `json:"plan_file"`
+	CreatedAt    string `json:"created_at"`
+	CurrentStage string `json:"current_stage,omitempty"`
+	// DependencyGraph is set when stages declare DependsOn (plan version
+	// 4); otherwise each stage depends on the one before it.
+	DependencyGraph bool `json:"dependency_graph,omitempty"`
+	// Parallel is the number of stages the pipeline may run at once.
+	Parallel int `json:"parallel"`
+	// Paused stops the pipeline from starting more stages.
+	Paused bool    `json:"paused,omitempty"`
+	Stages []Stage `json:"stages"`
+}
+
+// Stage returns the stage with the given id, or nil.
+func (s *Stack) Stage(id string) *Stage {
+	for i := range s.Stages {
+		if s.Stages[i].ID == id {
+			return &s.Stages[i]
+		}
+	}
+	return nil
+}
+
+// Stage is one unit of work in a stack, with its plan details and its
+// progress through the pipeline.
+type Stage struct {
+	ID             string            `json:"id"`
+	Title          string            `json:"title"`
+	Outcome        string            `json:"outcome,omitempty"`
+	Implementation []string          `json:"implementation,omitempty"`
+	Validation     []string          `json:"validation,omitempty"`
+	Risks          []Risk            `json:"risks,omitempty"`
+	Context        string            `json:"context,omitempty"`
+	DependsOn      []string          `json:"depends_on,omitempty"`
+	Timeouts       map[string]string `json:"timeouts,omitempty"`
+	Model          string            `json:"model,omitempty"`
+	Branch         string            `json:"branch,omitempty"`
+	Worktree       string            `json:"worktree,omitempty"`
+	Parent         string            `json:"parent_branch,omitempty"`
+	// Status is one of the Status constants; it is never empty.
+	Status       string `json:"status"`
+	StartedAt    string `json:"started_at,omitempty"`
+	ReviewedAt   string `json:"reviewed_at,omitempty"`
+	ReviewRounds int    `json:"review_rounds,omitempty"`
+	// StatusReason and StoppedFrom explain a failed or blocked stage.
+	StatusReason string   `json:"status_reason,omitempty"`
+	StoppedFrom  string   `json:"stopped_from,omitempty"`
+	Agent        *Agent   `json:"agent,omitempty"`
+	Reports      []Report `json:"reports,omitempty"`
+}
+
+// Risk is a risk a plan stage calls out, with its mitigation.
+type Risk struct {
+	Risk       string `json:"risk"`
+	Mitigation string `json:"mitigation"`
+}
+
+// Agent is the agent process last started for a stage.
+type Agent struct {
+	Phase     string `json:"phase"`
+	PID       int    `json:"pid"`
+	Log       string `json:"log,omitempty"`
+	StartedAt string `json:"started_at"`
+	// ExitCode is nil until the agent has exited.
+	ExitCode *int   `json:"exit_code,omitempty"`
+	ExitedAt string `json:"exited_at,omitempty"`
+	// Running reports whether the process was alive when the stack was
+	// read.
+	Running bool `json:"running"`
+}
+
+// Report is an agent's phase completion report. Review reports carry the
+// reviewer's outcome and any findings sent back to the build agent.
+type Report struct {
+	Phase      string `json:"phase"`
+	Summary    string `json:"summary,omitempty"`
+	Outcome    string `json:"outcome,omitempty"`
+	Findings   string `json:"findings,omitempty"`
+	ReportedAt string `json:"reported_at"`
+	Commit     string `json:"commit,omitempty"`
+}
+
+// Event is an entry in a stack's append-only journal.
+type Event struct {
+	Time    string            `json:"time"`
+	Type    string            `json:"type"`
+	Actor   string            `json:"actor,omitempty"`
+	Stage   string            `json:"stage,omitempty"`
+	Payload map[string]string `json:"payload,omitempty"`
+}
+
+// Change is a difference between two snapshots of the stacks. For
+// ChangeStageStatus, From and To are the old and new status; for agent
+// changes, From is the agent phase and To its pid or exit code.
+type Change struct {
+	Type  string `json:"type"`
+	Stack string `json:"stack"`
+	Stage string `json:"stage,omitempty"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+func (c Change) String() string {
+	return state.Change(c).String()
+}
+
+func stackFromState(stack *state.Stack) Stack {
+	out := Stack{
+		Name:            stack.Name,
+		Type:            stack.Type,
+		PlanFile:        stack.PlanFile,
+		CreatedAt:       stack.CreatedAt,
+		CurrentStage:    stack.CurrentStage,
+		DependencyGraph: stack.DependencyGraph,
+		Parallel:        max(stack.Parallel, 1),
+		Paused:          stack.Paused,
+		Stages:          make([]Stage, 0, len(stack.Stages)),
+	}
+	for i := range stack.Stages {
+		out.Stages = append(out.Stages, stageFromState(&stack.Stages[i]))
+	}
+	return out
+}
+
+func stageFromState(stage *state.Stage) Stage {
+	out := Stage{
+		ID:             stage.ID,
+		Title:          stage.Title,
+		Outcome:        stage.Outcome,
+		Implementation: slices.Clone(stage.Implementation),
+		Validation:     slices.Clone(stage.Validation),
+		Context:        stage.Context,
+		DependsOn:      slices.Clone(stage.DependsOn),
+		Timeouts:       maps.Clone(stage.Timeouts),
+		Model:          stage.Model,
+		Branch:         stage.Branch,
+		Worktree:       stage.Worktree,
+		Parent:         stage.Parent,
+		Status:         state.EffectiveStatus(stage),
+		StartedAt:      stage.StartedAt,
+		ReviewedAt:     stage.ReviewedAt,
+		ReviewRounds:   stage.ReviewRounds,
+		StatusReason:   stage.StatusReason,
+		StoppedFrom:    stage.StoppedFrom,
+	}
+	for _, risk := range stage.Risks {
+		out.Risks = append(out.Risks, Risk(risk))
+	}
+	if agent := stage.Agent; agent != nil {
+		out.Agent = &Agent{
+			Phase:     agent.Phase,
+			PID:       agent.PID,
+			Log:       agent.Log,
+			StartedAt: agent.StartedAt,
+			ExitedAt:  agent.ExitedAt,
+			Running:   state.AgentRunning(agent),
+		}
+		if agent.ExitCode != nil {
+			code := *agent.ExitCode
+			out.Agent.ExitCode = &code
+		}
+	}
+	for _, report := range stage.Reports {
+		out.Reports = append(out.Reports, Report(report))
+	}
+	return out
+}
+
+func stacksFromState(stacks *state.Stacks) []Stack {
+	out := make([]Stack, 0, len(stacks.Stacks))
+	for i := range stacks.Stacks {
+		out = append(out, stackFromState(&stacks.Stacks[i]))
+	}
+	return out
+}
+
+func findStack(stacks *state.Stacks, name string) (*state.Stack, error) {
+	stack, _ := state.FindStack(stacks, name)
+	if stack == nil {
+		return nil, state.StackNotFoundError(name)
+	}
+	return stack, nil
+}